@@ -3,6 +3,7 @@ package calcium
 import (
 	"strings"
 
+	"github.com/patrickmn/go-cache"
 	"github.com/projecteru2/core/cluster"
 	"github.com/projecteru2/core/discovery"
 	"github.com/projecteru2/core/discovery/helium"
@@ -24,6 +25,8 @@ type Calcium struct {
 	scheduler scheduler.Scheduler
 	source    source.Source
 	watcher   discovery.Service
+
+	nodeResources *cache.Cache // nil if NodeResourceCacheTTL not set
 }
 
 // New returns a new cluster config
@@ -56,7 +59,11 @@ func New(config types.Config, embeddedStorage bool) (*Calcium, error) {
 	// set watcher
 	watcher := helium.New(config.GRPCConfig, store)
 
-	return &Calcium{store: store, config: config, scheduler: potassium, source: scm, watcher: watcher}, err
+	c := &Calcium{store: store, config: config, scheduler: potassium, source: scm, watcher: watcher}
+	if config.NodeResourceCacheTTL > 0 {
+		c.nodeResources = cache.New(config.NodeResourceCacheTTL, config.NodeResourceCacheTTL)
+	}
+	return c, err
 }
 
 // Finalizer use for defer
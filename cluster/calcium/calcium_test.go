@@ -60,16 +60,22 @@ func TestNewCluster(t *testing.T) {
 	_, err = privFile.WriteString("privkey")
 	assert.NoError(t, err)
 	defer privFile.Close()
+	// New replaces the global scheduler, don't leak into other tests
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
 	go func() {
+		defer wg.Done()
 		c, err := New(types.Config{Git: types.GitConfig{SCMType: "gitlab", PrivateKey: privFile.Name()}}, true)
 		assert.NoError(t, err)
 		c.Finalizer()
 	}()
 	go func() {
+		defer wg.Done()
 		c, err := New(types.Config{Git: types.GitConfig{SCMType: "github", PrivateKey: privFile.Name()}}, true)
 		assert.NoError(t, err)
 		c.Finalizer()
 	}()
+	wg.Wait()
 }
 
 func TestFinalizer(t *testing.T) {
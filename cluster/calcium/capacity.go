@@ -2,53 +2,150 @@ package calcium
 
 import (
 	"context"
+	"fmt"
+	"math"
 
+	"github.com/docker/go-units"
 	"github.com/pkg/errors"
 	"github.com/projecteru2/core/log"
 	"github.com/projecteru2/core/resources"
 	resourcetypes "github.com/projecteru2/core/resources/types"
 	"github.com/projecteru2/core/strategy"
 	"github.com/projecteru2/core/types"
+	"github.com/projecteru2/core/utils"
 )
 
 // CalculateCapacity calculates capacity
+// it's read only, nothing will be reserved, deploy status is loaded to plan like a real deploy
 func (c *Calcium) CalculateCapacity(ctx context.Context, opts *types.DeployOptions) (*types.CapacityMessage, error) {
-	var err error
+	if err := c.doFillDefaultStrategy(ctx, opts); err != nil {
+		return nil, err
+	}
 	msg := &types.CapacityMessage{
 		Total:          0,
 		NodeCapacities: map[string]int{},
+		Strategy:       opts.DeployStrategy,
 	}
-	return msg, c.withNodesLocked(ctx, opts.Podname, opts.Nodenames, nil, false, func(ctx context.Context, nodeMap map[string]*types.Node) error {
-		if opts.DeployStrategy != strategy.Dummy {
-			if _, msg.NodeCapacities, err = c.doAllocResource(ctx, nodeMap, opts); err != nil {
-				return errors.WithStack(err)
-			}
-
-			for _, capacity := range msg.NodeCapacities {
-				msg.Total += capacity
-			}
-		} else {
-			var infos []strategy.Info
-			msg.Total, _, infos, err = c.doCalculateCapacity(nodeMap, opts)
+	return msg, c.withNodesLocked(ctx, opts.Podname, opts.Nodenames, opts.NodeLabels, false, func(ctx context.Context, nodeMap map[string]*types.Node) error {
+		if opts.DeployStrategy == strategy.Dummy {
+			total, _, infos, err := c.doCalculateCapacity(nodeMap, opts)
 			if err != nil {
 				return errors.WithStack(err)
 			}
+			msg.Total = total
 			for _, info := range infos {
 				msg.NodeCapacities[info.Nodename] = info.Capacity
 			}
+			return nil
+		}
+
+		var err error
+		if _, msg.NodeCapacities, err = c.doAllocResource(ctx, nodeMap, opts); err != nil {
+			return err
+		}
+		for _, capacity := range msg.NodeCapacities {
+			msg.Total += capacity
 		}
 		return nil
 	})
 }
 
+// PreviewDeploy tells how many workloads each node would get by opts
+// nothing is locked or written, deploy status is loaded so fill and each count existing workloads
+func (c *Calcium) PreviewDeploy(ctx context.Context, opts *types.DeployOptions) (map[string]int, error) {
+	if err := c.doFillDefaultStrategy(ctx, opts); err != nil {
+		return nil, err
+	}
+	nodes, err := c.getNodes(ctx, opts.Podname, opts.Nodenames, opts.NodeLabels, false)
+	if err != nil {
+		return nil, err
+	}
+	nodeMap := map[string]*types.Node{}
+	for _, node := range nodes {
+		nodeMap[node.Name] = node
+	}
+	_, deployMap, err := c.doAllocResource(ctx, nodeMap, opts)
+	return deployMap, err
+}
+
+// CalculateBatchCapacity tells whether a batch of different specs fits as a whole
+// items are planned in order on the same nodes, each placement is deducted before the next one,
+// items can't be placed are not deducted and don't stop the rest
+// like PreviewDeploy nothing is locked or written, so the answer is advisory
+func (c *Calcium) CalculateBatchCapacity(ctx context.Context, batch []*types.DeployOptions) (*types.BatchCapacity, error) {
+	nodeCache := map[string]*types.Node{}
+	result := &types.BatchCapacity{Placements: make([]map[string]int, len(batch)), Shortfalls: map[int]string{}}
+	for i, opts := range batch {
+		if opts.Count <= 0 {
+			return nil, errors.WithStack(types.NewDetailedErr(types.ErrBadCount, fmt.Sprintf("item %d count %d", i, opts.Count)))
+		}
+		if err := c.doFillDefaultStrategy(ctx, opts); err != nil {
+			return nil, err
+		}
+		nodes, err := c.getNodes(ctx, opts.Podname, opts.Nodenames, opts.NodeLabels, false)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// items share nodes, so later ones see what's left
+		nodeMap := map[string]*types.Node{}
+		for _, node := range nodes {
+			if _, ok := nodeCache[node.Name]; !ok {
+				nodeCache[node.Name] = node
+			}
+			nodeMap[node.Name] = nodeCache[node.Name]
+		}
+
+		deployMap, err := c.doPlanBatchItem(ctx, nodeMap, opts)
+		if err != nil {
+			result.Shortfalls[i] = err.Error()
+			continue
+		}
+		result.Placements[i] = deployMap
+	}
+	if !result.Feasible() {
+		log.Warnf("[CalculateBatchCapacity] %d of %d items can't be placed", len(result.Shortfalls), len(batch))
+	}
+	return result, nil
+}
+
+// doPlanBatchItem places one item of batch and deducts it from nodes
+func (c *Calcium) doPlanBatchItem(ctx context.Context, nodeMap map[string]*types.Node, opts *types.DeployOptions) (map[string]int, error) {
+	plans, deployMap, err := c.doAllocResource(ctx, nodeMap, opts)
+	if err != nil {
+		return nil, err
+	}
+	for nodename, deploy := range deployMap {
+		for _, plan := range plans {
+			plan.ApplyChangesOnNode(nodeMap[nodename], utils.Range(deploy)...)
+		}
+	}
+	return deployMap, nil
+}
+
+// doDeployByStrategy makes deployMap of nodename to count, shortfall is explained
+func (c *Calcium) doDeployByStrategy(nodeMap map[string]*types.Node, opts *types.DeployOptions, plans []resourcetypes.ResourcePlans, infos []strategy.Info, total int) (map[string]int, error) {
+	deployMap, err := strategy.Deploy(opts, infos, total)
+	if errors.Is(err, types.ErrInsufficientRes) {
+		return nil, errors.WithStack(c.doExplainCapacity(nodeMap, opts, plans, infos, err))
+	}
+	return deployMap, errors.WithStack(err)
+}
+
 func (c *Calcium) doCalculateCapacity(nodeMap map[string]*types.Node, opts *types.DeployOptions) (
 	total int,
 	plans []resourcetypes.ResourcePlans,
 	infos []strategy.Info,
 	err error,
 ) {
-	if len(nodeMap) == 0 {
-		return 0, nil, nil, errors.WithStack(types.ErrInsufficientNodes)
+	// cordoned nodes are still accounted, just not for new placements
+	schedulable := map[string]*types.Node{}
+	for nodename, node := range nodeMap {
+		if !node.Cordoned {
+			schedulable[nodename] = node
+		}
+	}
+	if len(schedulable) == 0 {
+		return 0, nil, nil, errors.WithStack(c.doExplainCapacity(nodeMap, opts, nil, nil, types.ErrInsufficientNodes))
 	}
 
 	resourceRequests, err := resources.MakeRequests(opts.ResourceOpts)
@@ -57,15 +154,121 @@ func (c *Calcium) doCalculateCapacity(nodeMap map[string]*types.Node, opts *type
 	}
 
 	// select available nodes
-	if plans, err = resources.SelectNodesByResourceRequests(resourceRequests, nodeMap); err != nil {
-		return 0, nil, nil, errors.WithStack(err)
+	if plans, err = resources.SelectNodesByResourceRequests(resourceRequests, schedulable); err != nil {
+		return 0, nil, nil, errors.WithStack(c.doExplainCapacity(nodeMap, opts, plans, nil, err))
 	}
 	log.Debugf("[Calcium.doCalculateCapacity] plans: %+v, total: %v", plans, total)
 
 	// deploy strategy
-	infos = strategy.NewInfos(resourceRequests, nodeMap, plans)
+	infos = c.doLimitByFreeFloor(schedulable, opts.ResourceOpts, strategy.NewInfos(resourceRequests, schedulable, plans))
 	for _, info := range infos {
 		total += info.Capacity
 	}
 	return
 }
+
+// doLimitByFreeFloor caps capacity so that no placement drives a node below the free floor
+// nodes with no capacity left are dropped, like NewInfos does
+func (c *Calcium) doLimitByFreeFloor(nodeMap map[string]*types.Node, resourceOpts types.ResourceOptions, infos []strategy.Info) []strategy.Info {
+	limited := []strategy.Info{}
+	for _, info := range infos {
+		if info.Capacity, _ = c.doCapAboveFreeFloor(nodeMap[info.Nodename], resourceOpts, info.Capacity); info.Capacity <= 0 {
+			log.Infof("[doLimitByFreeFloor] node %s has no capacity above free floor %+v", info.Nodename, c.config.Scheduler.MinFree)
+			continue
+		}
+		limited = append(limited, info)
+	}
+	return limited
+}
+
+// doCapAboveFreeFloor caps capacity by every free floor set, the floor capping it is described, empty if none
+func (c *Calcium) doCapAboveFreeFloor(node *types.Node, resourceOpts types.ResourceOptions, capacity int) (int, string) {
+	floor := c.config.Scheduler.MinFree
+	limit := ""
+	capBy := func(fits int, desc string) {
+		if fits < capacity {
+			capacity, limit = fits, desc
+		}
+	}
+	if floor.CPU > 0 && resourceOpts.CPUQuotaRequest > 0 {
+		capBy(fitsAbove(float64(len(node.InitCPU))-node.CPUUsed, floor.CPU, resourceOpts.CPUQuotaRequest), fmt.Sprintf("cpu %v", floor.CPU))
+	}
+	if floor.Memory > 0 && resourceOpts.MemoryRequest > 0 {
+		capBy(fitsAbove(float64(node.MemCap), float64(floor.Memory), float64(resourceOpts.MemoryRequest)), "memory "+units.BytesSize(float64(floor.Memory)))
+	}
+	if floor.Storage > 0 && resourceOpts.StorageRequest > 0 && node.InitStorageCap > 0 {
+		capBy(fitsAbove(float64(node.StorageCap), float64(floor.Storage), float64(resourceOpts.StorageRequest)), "storage "+units.BytesSize(float64(floor.Storage)))
+	}
+	return capacity, limit
+}
+
+// fitsAbove tells how many requests fit in free resource above floor
+// a tiny epsilon keeps float noise like 0.9/0.3 from losing one
+func fitsAbove(free, floor, request float64) int {
+	return int(math.Max(0, free-floor)/request + 1e-9)
+}
+
+// doExplainCapacity attaches the reason of every node failed to deploy on to err
+// plans come from SelectNodesByResourceRequests, if it failed, the request after the last plan is the failed one
+// nodes passing every plan but not in infos are dropped by free floor
+func (c *Calcium) doExplainCapacity(nodeMap map[string]*types.Node, opts *types.DeployOptions, plans []resourcetypes.ResourcePlans, infos []strategy.Info, err error) error {
+	resourceRequests, e := resources.MakeRequests(opts.ResourceOpts)
+	if e != nil {
+		return err
+	}
+
+	capacities := map[string]int{}
+	for _, info := range infos {
+		capacities[info.Nodename] = info.Capacity
+	}
+
+	shortfall := &types.CapacityShortfall{Cause: err, Rejections: map[string]string{}}
+	for nodename, node := range nodeMap {
+		if node.Cordoned {
+			shortfall.Rejections[nodename] = "cordoned"
+			continue
+		}
+		if capacity, ok := capacities[nodename]; ok {
+			shortfall.Rejections[nodename] = fmt.Sprintf("capacity %d only", capacity)
+			continue
+		}
+		resourceType := types.ResourceAll
+		planned := math.MaxInt32
+		for _, plan := range plans {
+			capacity := plan.Capacity()[nodename]
+			if capacity <= 0 {
+				resourceType = plan.Type()
+				break
+			}
+			planned = utils.Min(planned, capacity)
+		}
+		if resourceType == types.ResourceAll && len(plans) < len(resourceRequests) {
+			resourceType = resourceRequests[len(plans)].Type()
+		}
+		if resourceType == types.ResourceAll {
+			if capacity, floor := c.doCapAboveFreeFloor(node, opts.ResourceOpts, planned); capacity <= 0 && floor != "" {
+				shortfall.Rejections[nodename] = fmt.Sprintf("below free floor (%s)", floor)
+				continue
+			}
+		}
+		shortfall.Rejections[nodename] = describeRejection(resourceType, node, opts.ResourceOpts)
+	}
+	return shortfall
+}
+
+func describeRejection(resourceType types.ResourceType, node *types.Node, opts types.ResourceOptions) string {
+	switch {
+	case resourceType == types.ResourceAll:
+		return "insufficient resource"
+	case resourceType&types.ResourceMemory != 0 && node.MemCap < opts.MemoryRequest:
+		return fmt.Sprintf("insufficient memory (need %s, have %s)", units.BytesSize(float64(opts.MemoryRequest)), units.BytesSize(float64(node.MemCap)))
+	case resourceType&types.ResourceCPU != 0:
+		return fmt.Sprintf("insufficient cpu (need %v, have %v)", opts.CPUQuotaRequest, float64(len(node.InitCPU))-node.CPUUsed)
+	case resourceType&types.ResourceStorage != 0:
+		return fmt.Sprintf("insufficient storage (need %s, have %s)", units.BytesSize(float64(opts.StorageRequest)), units.BytesSize(float64(node.AvailableStorage())))
+	case resourceType&types.ResourceVolume != 0:
+		return fmt.Sprintf("insufficient volume (need %s, have %s)", units.BytesSize(float64(opts.VolumeRequest.TotalSize())), units.BytesSize(float64(node.Volume.Total())))
+	default:
+		return "insufficient resource"
+	}
+}
@@ -2,12 +2,16 @@ package calcium
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	enginemocks "github.com/projecteru2/core/engine/mocks"
 	lockmocks "github.com/projecteru2/core/lock/mocks"
+	"github.com/projecteru2/core/resources"
 	resourcetypes "github.com/projecteru2/core/resources/types"
+	resourcetypesmocks "github.com/projecteru2/core/resources/types/mocks"
 	"github.com/projecteru2/core/scheduler"
+	complexscheduler "github.com/projecteru2/core/scheduler/complex"
 	schedulermocks "github.com/projecteru2/core/scheduler/mocks"
 	storemocks "github.com/projecteru2/core/store/mocks"
 	"github.com/projecteru2/core/strategy"
@@ -68,6 +72,7 @@ func TestCalculateCapacity(t *testing.T) {
 	r, err := c.CalculateCapacity(ctx, opts)
 	assert.NoError(t, err)
 	assert.Equal(t, r.Total, 5)
+	assert.Equal(t, strategy.Auto, r.Strategy)
 	opts.DeployStrategy = strategy.Dummy
 	r, err = c.CalculateCapacity(ctx, opts)
 	assert.NoError(t, err)
@@ -113,4 +118,268 @@ func TestCalculateCapacity(t *testing.T) {
 	assert.EqualValues(t, 0, r.Total)
 	sched.AssertExpectations(t)
 	store.AssertExpectations(t)
+
+	// filter by labels
+	opts.Podname = "p1"
+	opts.Nodenames = nil
+	opts.NodeLabels = map[string]string{"zone": "a"}
+	store.On("GetNodesByPod", mock.Anything, "p1", opts.NodeLabels, false).Return(nil, types.ErrBadMeta).Once()
+	_, err = c.CalculateCapacity(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrBadMeta))
+	store.AssertExpectations(t)
+
+	// pod default strategy is taken before deciding how to calculate
+	opts.DeployStrategy = ""
+	store.On("GetPod", mock.Anything, "p1").Return(nil, types.ErrNoETCD).Once()
+	_, err = c.CalculateCapacity(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	store.On("GetPod", mock.Anything, "p1").Return(&types.Pod{Name: "p1", DefaultStrategy: strategy.Dummy}, nil).Once()
+	store.On("GetNodesByPod", mock.Anything, "p1", opts.NodeLabels, false).Return(nil, types.ErrBadMeta).Once()
+	_, err = c.CalculateCapacity(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrBadMeta))
+	assert.Equal(t, strategy.Dummy, opts.DeployStrategy)
+	store.AssertExpectations(t)
+}
+
+func TestPreviewDeploy(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	potassium, _ := complexscheduler.New(c.config)
+	c.scheduler = potassium
+	scheduler.InitSchedulerV1(potassium)
+	opts := &types.DeployOptions{
+		Podname:        "p1",
+		Count:          3,
+		DeployStrategy: strategy.Auto,
+		ResourceOpts:   types.ResourceOptions{MemoryRequest: 10, MemoryLimit: 10},
+		Entrypoint:     &types.Entrypoint{Name: "entry"},
+	}
+
+	// failed by GetNodesByPod
+	store.On("GetNodesByPod", mock.Anything, "p1", mock.Anything, false).Return(nil, types.ErrNoETCD).Once()
+	_, err := c.PreviewDeploy(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+
+	nodes := []*types.Node{
+		{NodeMeta: types.NodeMeta{Name: "n1", MemCap: 100}},
+		{NodeMeta: types.NodeMeta{Name: "n2", MemCap: 25}},
+	}
+	store.On("GetNodesByPod", mock.Anything, "p1", mock.Anything, false).Return(nodes, nil)
+	// failed by MakeDeployStatus
+	store.On("MakeDeployStatus", mock.Anything, mock.Anything, mock.Anything).Return(types.ErrNoETCD).Once()
+	_, err = c.PreviewDeploy(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	store.On("MakeDeployStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	deployMap, err := c.PreviewDeploy(ctx, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n1": 2, "n2": 1}, deployMap)
+
+	// existing workloads are counted like a real deploy
+	store.On("MakeDeployStatus", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		for i, info := range args.Get(2).([]strategy.Info) {
+			if info.Nodename == "n2" {
+				args.Get(2).([]strategy.Info)[i].Count = 2
+			}
+		}
+	}).Return(nil).Once()
+	opts.DeployStrategy, opts.NodesLimit = strategy.Fill, 1
+	deployMap, err = c.PreviewDeploy(ctx, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n2": 1}, deployMap)
+	opts.DeployStrategy, opts.NodesLimit = strategy.Auto, 0
+	store.On("MakeDeployStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// cordoned one is skipped
+	nodes[1].Cordoned = true
+	deployMap, err = c.PreviewDeploy(ctx, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n1": 3}, deployMap)
+	nodes[0].Cordoned = true
+	_, err = c.PreviewDeploy(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrInsufficientNodes))
+	var shortfall *types.CapacityShortfall
+	assert.True(t, errors.As(err, &shortfall))
+	assert.Equal(t, map[string]string{nodes[0].Name: "cordoned", nodes[1].Name: "cordoned"}, shortfall.Rejections)
+	nodes[0].Cordoned = false
+
+	// cordoned one is explained as well
+	opts.Count = 13
+	_, err = c.PreviewDeploy(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrInsufficientRes))
+	assert.True(t, errors.As(err, &shortfall))
+	assert.Equal(t, "cordoned", shortfall.Rejections[nodes[1].Name])
+	nodes[1].Cordoned = false
+
+	// shortfall is explained
+	_, err = c.PreviewDeploy(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrInsufficientRes))
+	assert.True(t, errors.As(err, &shortfall))
+
+	// pod default strategy applied
+	opts.Count, opts.DeployStrategy, opts.NodesLimit = 3, "", 1
+	store.On("GetPod", mock.Anything, "p1").Return(&types.Pod{Name: "p1", DefaultStrategy: strategy.Fill}, nil).Once()
+	deployMap, err = c.PreviewDeploy(ctx, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, strategy.Fill, opts.DeployStrategy)
+	assert.Equal(t, map[string]int{"n1": 3}, deployMap)
+
+	// nothing locked
+	store.AssertNotCalled(t, "CreateLock", mock.Anything, mock.Anything)
+}
+
+func TestCalculateBatchCapacity(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	potassium, _ := complexscheduler.New(c.config)
+	c.scheduler = potassium
+	scheduler.InitSchedulerV1(potassium)
+	item := func(count int, memory int64) *types.DeployOptions {
+		return &types.DeployOptions{
+			Podname:        "p1",
+			Count:          count,
+			DeployStrategy: strategy.Auto,
+			ResourceOpts:   types.ResourceOptions{MemoryRequest: memory, MemoryLimit: memory},
+			Entrypoint:     &types.Entrypoint{Name: "entry"},
+		}
+	}
+
+	_, err := c.CalculateBatchCapacity(ctx, []*types.DeployOptions{item(0, 10)})
+	assert.True(t, errors.Is(err, types.ErrBadCount))
+	store.On("GetNodesByPod", mock.Anything, "p1", mock.Anything, false).Return(nil, types.ErrNoETCD).Once()
+	_, err = c.CalculateBatchCapacity(ctx, []*types.DeployOptions{item(1, 10)})
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+
+	nodes := []*types.Node{
+		{NodeMeta: types.NodeMeta{Name: "n1", MemCap: 100}},
+		{NodeMeta: types.NodeMeta{Name: "n2", MemCap: 25}},
+	}
+	store.On("GetNodesByPod", mock.Anything, "p1", mock.Anything, false).Return(nodes, nil)
+	store.On("MakeDeployStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	// 90 taken by the first, 50 doesn't fit in the rest, 20 does
+	r, err := c.CalculateBatchCapacity(ctx, []*types.DeployOptions{item(3, 30), item(1, 50), item(1, 20)})
+	assert.NoError(t, err)
+	assert.False(t, r.Feasible())
+	assert.Equal(t, map[string]int{"n1": 3}, r.Placements[0])
+	assert.Nil(t, r.Placements[1])
+	assert.Contains(t, r.Shortfalls, 1)
+	assert.Equal(t, map[string]int{"n2": 1}, r.Placements[2])
+	assert.Len(t, r.Shortfalls, 1)
+
+	// pod default strategy applied to items without one
+	noStrategy := item(1, 10)
+	noStrategy.DeployStrategy = ""
+	store.On("GetPod", mock.Anything, "p1").Return(nil, types.ErrNoETCD).Once()
+	_, err = c.CalculateBatchCapacity(ctx, []*types.DeployOptions{noStrategy})
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	store.On("GetPod", mock.Anything, "p1").Return(&types.Pod{Name: "p1", DefaultStrategy: strategy.Fill}, nil).Once()
+	r, err = c.CalculateBatchCapacity(ctx, []*types.DeployOptions{noStrategy})
+	assert.NoError(t, err)
+	assert.True(t, r.Feasible())
+	assert.Equal(t, strategy.Fill, noStrategy.DeployStrategy)
+
+	// nothing locked, deploy status loaded
+	store.AssertNotCalled(t, "CreateLock", mock.Anything, mock.Anything)
+	store.AssertCalled(t, "MakeDeployStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExplainCapacity(t *testing.T) {
+	c := NewTestCluster()
+	nodeMap := map[string]*types.Node{
+		"n1": {NodeMeta: types.NodeMeta{Name: "n1", MemCap: 4 << 30}},
+		"n2": {NodeMeta: types.NodeMeta{Name: "n2", MemCap: 500 << 20}},
+		"n3": {NodeMeta: types.NodeMeta{Name: "n3", MemCap: 4 << 30, StorageCap: 1 << 30, InitStorageCap: 4 << 30}},
+	}
+	opts := &types.DeployOptions{
+		ResourceOpts: types.ResourceOptions{MemoryRequest: 2 << 30, StorageRequest: 2 << 30},
+	}
+	memPlan := &resourcetypesmocks.ResourcePlans{}
+	memPlan.On("Type").Return(types.ResourceCPU | types.ResourceMemory)
+	memPlan.On("Capacity").Return(map[string]int{"n1": 2, "n3": 2})
+	storagePlan := &resourcetypesmocks.ResourcePlans{}
+	storagePlan.On("Type").Return(types.ResourceStorage)
+	storagePlan.On("Capacity").Return(map[string]int{"n1": 1})
+	infos := []strategy.Info{{Nodename: "n1", Capacity: 1}}
+
+	err := c.doExplainCapacity(nodeMap, opts, []resourcetypes.ResourcePlans{memPlan, storagePlan}, infos, types.ErrInsufficientRes)
+	assert.True(t, errors.Is(err, types.ErrInsufficientRes))
+	var shortfall *types.CapacityShortfall
+	assert.True(t, errors.As(err, &shortfall))
+	assert.Equal(t, "capacity 1 only", shortfall.Rejections["n1"])
+	assert.Equal(t, "insufficient memory (need 2GiB, have 500MiB)", shortfall.Rejections["n2"])
+	assert.Equal(t, "insufficient storage (need 2GiB, have 1GiB)", shortfall.Rejections["n3"])
+
+	// failed by the request after the last plan
+	err = c.doExplainCapacity(nodeMap, opts, []resourcetypes.ResourcePlans{memPlan}, nil, types.ErrInsufficientRes)
+	assert.True(t, errors.As(err, &shortfall))
+	assert.Equal(t, "insufficient storage (need 2GiB, have 1GiB)", shortfall.Rejections["n3"])
+	assert.Equal(t, "insufficient memory (need 2GiB, have 500MiB)", shortfall.Rejections["n2"])
+
+	// dropped by free floor, or cordoned
+	c.config.Scheduler.MinFree = types.FreeFloor{Memory: 3 << 30}
+	nodeMap["n2"].Cordoned = true
+	opts = &types.DeployOptions{ResourceOpts: types.ResourceOptions{MemoryRequest: 2 << 30}}
+	requests, err := resources.MakeRequests(opts.ResourceOpts)
+	assert.NoError(t, err)
+	// every plan passed
+	passPlan := &resourcetypesmocks.ResourcePlans{}
+	passPlan.On("Type").Return(types.ResourceCPU | types.ResourceMemory)
+	passPlan.On("Capacity").Return(map[string]int{"n1": 2, "n3": 2})
+	passPlans := []resourcetypes.ResourcePlans{}
+	for range requests {
+		passPlans = append(passPlans, passPlan)
+	}
+	err = c.doExplainCapacity(nodeMap, opts, passPlans, infos, types.ErrInsufficientRes)
+	assert.True(t, errors.As(err, &shortfall))
+	assert.Equal(t, "capacity 1 only", shortfall.Rejections["n1"])
+	assert.Equal(t, "cordoned", shortfall.Rejections["n2"])
+	assert.Equal(t, "below free floor (memory 3GiB)", shortfall.Rejections["n3"])
+}
+
+func TestCalculateCapacityFreeFloor(t *testing.T) {
+	c := NewTestCluster()
+	scheduler.InitSchedulerV1(c.scheduler)
+	sched := c.scheduler.(*schedulermocks.Scheduler)
+	scheduleInfos := []resourcetypes.ScheduleInfo{
+		{NodeMeta: types.NodeMeta{Name: "n1"}, Capacity: 10},
+		{NodeMeta: types.NodeMeta{Name: "n2"}, Capacity: 10},
+	}
+	sched.On("SelectMemoryNodes", mock.Anything, mock.Anything, mock.Anything).Return(scheduleInfos, 20, nil)
+	sched.On("SelectStorageNodes", mock.Anything, mock.Anything).Return(scheduleInfos, 20, nil)
+	sched.On("SelectVolumeNodes", mock.Anything, mock.Anything).Return(scheduleInfos, nil, 20, nil)
+	nodeMap := map[string]*types.Node{
+		// nearly full
+		"n1": {NodeMeta: types.NodeMeta{Name: "n1", InitCPU: types.CPUMap{"0": 100, "1": 100}, MemCap: 110, InitMemCap: 1000, StorageCap: 1000, InitStorageCap: 1000}, CPUUsed: 0.6},
+		"n2": {NodeMeta: types.NodeMeta{Name: "n2", InitCPU: types.CPUMap{"0": 100, "1": 100}, MemCap: 500, InitMemCap: 1000, StorageCap: 1000, InitStorageCap: 1000}},
+	}
+	opts := &types.DeployOptions{ResourceOpts: types.ResourceOptions{CPUQuotaRequest: 0.3, MemoryRequest: 20, StorageRequest: 100}}
+
+	// no floor
+	total, _, infos, err := c.doCalculateCapacity(nodeMap, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, total)
+	assert.Len(t, infos, 2)
+
+	// n1 has no memory above floor
+	c.config.Scheduler.MinFree = types.FreeFloor{Memory: 100}
+	total, _, infos, err = c.doCalculateCapacity(nodeMap, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, total)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, "n2", infos[0].Nodename)
+
+	// limited by every floor
+	c.config.Scheduler.MinFree = types.FreeFloor{CPU: 0.5, Memory: 60, Storage: 500}
+	total, _, infos, err = c.doCalculateCapacity(nodeMap, opts)
+	assert.NoError(t, err)
+	capacities := map[string]int{}
+	for _, info := range infos {
+		capacities[info.Nodename] = info.Capacity
+	}
+	// n1: cpu (2-0.6-0.5)/0.3 = 3, memory (110-60)/20 = 2; n2: cpu (2-0.5)/0.3 = 5, storage (1000-500)/100 = 5
+	assert.Equal(t, map[string]int{"n1": 2, "n2": 5}, capacities)
+	assert.Equal(t, 7, total)
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"sync"
 
+	"github.com/pkg/errors"
 	"github.com/projecteru2/core/cluster"
 	"github.com/projecteru2/core/log"
 	"github.com/projecteru2/core/types"
@@ -100,3 +101,18 @@ func (c *Calcium) doStopWorkload(ctx context.Context, workload *types.Workload,
 	}
 	return message, err
 }
+
+// UpdateWorkloadRestartPolicy updates restart policy of a workload in place
+// e.g. set a crash looping one to no without recreating it
+func (c *Calcium) UpdateWorkloadRestartPolicy(ctx context.Context, id, restartPolicy string) error {
+	if err := types.ValidateRestartPolicy(restartPolicy); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.withWorkloadLocked(ctx, id, func(ctx context.Context, workload *types.Workload) error {
+		if err := workload.Engine.VirtualizationUpdateRestartPolicy(ctx, workload.ID, restartPolicy); err != nil {
+			return errors.WithStack(err)
+		}
+		log.Infof("[UpdateWorkloadRestartPolicy] Workload %s restart policy updated to %s", id, restartPolicy)
+		return nil
+	})
+}
@@ -3,6 +3,7 @@ package calcium
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io/ioutil"
 	"testing"
 
@@ -180,3 +181,31 @@ func TestControlRestart(t *testing.T) {
 		assert.NoError(t, r.Error)
 	}
 }
+
+func TestUpdateWorkloadRestartPolicy(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	lock := &lockmocks.DistributedLock{}
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	c.store = store
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	// failed by GetWorkloads
+	store.On("GetWorkloads", mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD).Once()
+	assert.Error(t, c.UpdateWorkloadRestartPolicy(ctx, "id1", "no"))
+
+	engine := &enginemocks.API{}
+	workload := &types.Workload{ID: "id1", Engine: engine}
+	store.On("GetWorkloads", mock.Anything, mock.Anything).Return([]*types.Workload{workload}, nil)
+	// failed by bad policy
+	assert.True(t, errors.Is(c.UpdateWorkloadRestartPolicy(ctx, "id1", "bad"), types.ErrBadRestartPolicy))
+	engine.AssertNotCalled(t, "VirtualizationUpdateRestartPolicy", mock.Anything, mock.Anything, mock.Anything)
+	// failed by engine
+	engine.On("VirtualizationUpdateRestartPolicy", mock.Anything, "id1", "always").Return(types.ErrNilEngine)
+	assert.Error(t, c.UpdateWorkloadRestartPolicy(ctx, "id1", "always"))
+
+	engine.On("VirtualizationUpdateRestartPolicy", mock.Anything, "id1", "no").Return(nil)
+	assert.NoError(t, c.UpdateWorkloadRestartPolicy(ctx, "id1", "no"))
+	engine.AssertNotCalled(t, "VirtualizationStop", mock.Anything, mock.Anything)
+}
@@ -3,6 +3,7 @@ package calcium
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,6 +32,9 @@ func (c *Calcium) CreateWorkload(ctx context.Context, opts *types.DeployOptions)
 	if opts.Count <= 0 {
 		return nil, errors.WithStack(types.NewDetailedErr(types.ErrBadCount, opts.Count))
 	}
+	if err := c.doFillDefaultStrategy(ctx, opts); err != nil {
+		return nil, err
+	}
 
 	ch, err := c.doCreateWorkloads(ctx, opts)
 	return ch, errors.WithStack(err)
@@ -73,6 +77,14 @@ func (c *Calcium) doCreateWorkloads(ctx context.Context, opts *types.DeployOptio
 						}
 					}()
 
+					// reserved resources are given back to be allocated again
+					var reservation *types.Reservation
+					if opts.Reservation != "" {
+						if reservation, err = c.doConsumeReservation(ctx, opts.Reservation, nodeMap); err != nil {
+							return errors.WithStack(err)
+						}
+					}
+
 					// calculate plans
 					if plans, deployMap, err = c.doAllocResource(ctx, nodeMap, opts); err != nil {
 						return errors.WithStack(err)
@@ -81,6 +93,7 @@ func (c *Calcium) doCreateWorkloads(ctx context.Context, opts *types.DeployOptio
 					// commit changes
 					nodes := []*types.Node{}
 					for nodename, deploy := range deployMap {
+						c.doInvalidateNodeResource(nodename)
 						for _, plan := range plans {
 							plan.ApplyChangesOnNode(nodeMap[nodename], utils.Range(deploy)...)
 						}
@@ -89,7 +102,17 @@ func (c *Calcium) doCreateWorkloads(ctx context.Context, opts *types.DeployOptio
 							return errors.WithStack(err)
 						}
 					}
-					return errors.WithStack(c.store.UpdateNodes(ctx, nodes...))
+					if reservation == nil {
+						return errors.WithStack(c.store.UpdateNodes(ctx, nodes...))
+					}
+					for nodename := range reservation.Resources {
+						if _, ok := deployMap[nodename]; !ok {
+							c.doInvalidateNodeResource(nodename)
+							nodes = append(nodes, nodeMap[nodename])
+						}
+					}
+					// removed with nodes at once, so the reservation can't be released again after its resources allocated
+					return c.store.RemoveReservation(ctx, reservation.Token, nodes)
 				})
 			},
 
@@ -118,6 +141,10 @@ func (c *Calcium) doCreateWorkloads(ctx context.Context, opts *types.DeployOptio
 		); err != nil {
 			log.Errorf("[Calcium.doCreateWorkloads] %+v", err)
 		}
+		// workloads are saved after resources allocated, so invalidate again
+		for nodename := range deployMap {
+			c.doInvalidateNodeResource(nodename)
+		}
 	}()
 
 	return ch, errors.WithStack(err)
@@ -240,7 +267,10 @@ func (c *Calcium) doDeployOneWorkload(
 	return utils.Txn(
 		ctx,
 		// create workload
-		func(ctx context.Context) error {
+		func(ctx context.Context) (err error) {
+			if config.Secrets, err = c.doResolveSecrets(ctx, opts.SecretEnv); err != nil {
+				return err
+			}
 			created, err := node.Engine.VirtualizationCreate(ctx, config)
 			if err != nil {
 				return errors.WithStack(err)
@@ -320,6 +350,33 @@ func (c *Calcium) doDeployOneWorkload(
 	)
 }
 
+// doResolveSecrets turns secret references to env, values only go to engine
+func (c *Calcium) doResolveSecrets(ctx context.Context, secretEnv map[string]string) ([]string, error) {
+	if len(secretEnv) == 0 {
+		return nil, nil
+	}
+	names := []string{}
+	keys := []string{}
+	for name, key := range secretEnv {
+		names = append(names, name)
+		keys = append(keys, key)
+	}
+	secrets, err := c.store.GetSecrets(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	env := []string{}
+	for _, name := range names {
+		value, ok := secrets[secretEnv[name]]
+		if !ok {
+			return nil, types.NewDetailedErr(types.ErrSecretNotExists, secretEnv[name])
+		}
+		env = append(env, fmt.Sprintf("%s=%s", name, value))
+	}
+	return env, nil
+}
+
 func (c *Calcium) doMakeWorkloadOptions(no int, msg *types.CreateWorkloadMessage, opts *types.DeployOptions, node *types.Node) *enginetypes.VirtualizationCreateOptions {
 	config := &enginetypes.VirtualizationCreateOptions{}
 	// general
@@ -351,6 +408,12 @@ func (c *Calcium) doMakeWorkloadOptions(no int, msg *types.CreateWorkloadMessage
 		config.LogType = entry.Log.Type
 		config.LogConfig = entry.Log.Config
 	}
+	if entry.HealthCheck != nil && entry.HealthCheck.WatchdogInterval > 0 {
+		config.HealthCheck = &enginetypes.HealthCheck{
+			Interval: entry.HealthCheck.WatchdogInterval,
+			Notify:   entry.HealthCheck.Notify,
+		}
+	}
 	// name
 	suffix := utils.RandomString(6)
 	config.Name = utils.MakeWorkloadName(opts.Name, opts.Entrypoint.Name, suffix)
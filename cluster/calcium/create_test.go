@@ -62,6 +62,12 @@ func TestCreateWorkload(t *testing.T) {
 	assert.Error(t, err)
 	opts.Entrypoint.Name = "some-nice-entrypoint"
 
+	// failed by getting pod default strategy
+	store.On("GetPod", mock.Anything, "somepod").Return(nil, types.ErrNoETCD).Once()
+	_, err = c.CreateWorkload(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	store.On("GetPod", mock.Anything, "somepod").Return(&types.Pod{Name: "somepod"}, nil)
+
 	// failed by memory check
 	opts.ResourceOpts = types.ResourceOptions{MemoryLimit: -1}
 	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
@@ -299,3 +305,23 @@ func TestCreateWorkloadTxn(t *testing.T) {
 	assert.EqualValues(t, 1, node1.CPUUsed+node2.CPUUsed)
 	return
 }
+
+func TestResolveSecrets(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := c.store.(*storemocks.Store)
+
+	env, err := c.doResolveSecrets(ctx, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, env)
+
+	// failed by missing secret
+	store.On("GetSecrets", mock.Anything, []string{"missing"}).Return(nil, types.NewDetailedErr(types.ErrSecretNotExists, "missing"))
+	_, err = c.doResolveSecrets(ctx, map[string]string{"DB_PASS": "missing"})
+	assert.True(t, errors.Is(err, types.ErrSecretNotExists))
+
+	store.On("GetSecrets", mock.Anything, mock.Anything).Return(map[string]string{"db": "pass", "token": "t"}, nil)
+	env, err = c.doResolveSecrets(ctx, map[string]string{"TOKEN": "token", "DB_PASS": "db"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DB_PASS=pass", "TOKEN=t"}, env)
+}
@@ -17,6 +17,7 @@ func (c *Calcium) DissociateWorkload(ctx context.Context, ids []string) (chan *t
 		for _, id := range ids {
 			err := c.withWorkloadLocked(ctx, id, func(ctx context.Context, workload *types.Workload) error {
 				return c.withNodeLocked(ctx, workload.Nodename, func(ctx context.Context, node *types.Node) (err error) {
+					defer c.doInvalidateNodeResource(node.Name)
 					return utils.Txn(
 						ctx,
 						// if
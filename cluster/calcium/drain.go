@@ -0,0 +1,87 @@
+package calcium
+
+import (
+	"context"
+	"sort"
+
+	"github.com/projecteru2/core/log"
+	"github.com/projecteru2/core/types"
+)
+
+// PlanDrain tells whether workloads on the node fit in the rest of its pod
+// it's read only, placements are simulated on nodes fetched here one workload by another, largest first
+// nothing is locked or written back, so the plan is advisory
+func (c *Calcium) PlanDrain(ctx context.Context, nodename string) (*types.DrainPlan, error) {
+	node, err := c.GetNode(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	workloads, err := c.ListNodeWorkloads(ctx, nodename, nil)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := c.ListPodNodes(ctx, node.Podname, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	// each workload is planned like deploying one more into the pod
+	deployOpts := &types.DeployOptions{Podname: node.Podname}
+	if err := c.doFillDefaultStrategy(ctx, deployOpts); err != nil {
+		return nil, err
+	}
+	nodeMap := map[string]*types.Node{}
+	for _, n := range nodes {
+		if n.Name != nodename {
+			nodeMap[n.Name] = n
+		}
+	}
+
+	usage := newResourceUsage()
+	for _, workload := range workloads {
+		usage.add(&workload.ResourceMeta)
+	}
+	plan := &types.DrainPlan{
+		Nodename:   nodename,
+		CPU:        usage.cpus,
+		Memory:     usage.memory,
+		Storage:    usage.storage,
+		Volume:     usage.volume,
+		Targets:    map[string]string{},
+		Shortfalls: map[string]string{},
+	}
+
+	sort.SliceStable(workloads, func(i, j int) bool {
+		if workloads[i].MemoryRequest != workloads[j].MemoryRequest {
+			return workloads[i].MemoryRequest > workloads[j].MemoryRequest
+		}
+		return workloads[i].CPUQuotaRequest > workloads[j].CPUQuotaRequest
+	})
+	for _, workload := range workloads {
+		opts := &types.DeployOptions{Podname: node.Podname, DeployStrategy: deployOpts.DeployStrategy, Count: 1, ResourceOpts: workloadResourceOptions(workload)}
+		total, plans, infos, err := c.doCalculateCapacity(nodeMap, opts)
+		if err != nil {
+			plan.Shortfalls[workload.ID] = err.Error()
+			continue
+		}
+		if total == 0 {
+			plan.Shortfalls[workload.ID] = types.ErrInsufficientRes.Error()
+			continue
+		}
+		// the most capable node keeps room for the following ones
+		target := ""
+		capacity := 0
+		for _, info := range infos {
+			if info.Capacity > capacity || info.Capacity == capacity && info.Nodename < target {
+				target, capacity = info.Nodename, info.Capacity
+			}
+		}
+		for _, p := range plans {
+			p.ApplyChangesOnNode(nodeMap[target], 0)
+		}
+		plan.Targets[workload.ID] = target
+	}
+	if !plan.Feasible() {
+		log.Warnf("[PlanDrain] %d of %d workloads on node %s can't be rescheduled", len(plan.Shortfalls), len(workloads), nodename)
+	}
+	return plan, nil
+}
@@ -0,0 +1,53 @@
+package calcium
+
+import (
+	"context"
+	"testing"
+
+	"github.com/projecteru2/core/scheduler"
+	complexscheduler "github.com/projecteru2/core/scheduler/complex"
+	storemocks "github.com/projecteru2/core/store/mocks"
+	"github.com/projecteru2/core/strategy"
+	"github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPlanDrain(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	potassium, _ := complexscheduler.New(c.config)
+	c.scheduler = potassium
+	scheduler.InitSchedulerV1(potassium)
+
+	// failed by GetNode
+	store.On("GetNode", mock.Anything, "n0").Return(nil, types.ErrNoETCD).Once()
+	_, err := c.PlanDrain(ctx, "n0")
+	assert.Error(t, err)
+
+	n0 := &types.Node{NodeMeta: types.NodeMeta{Name: "n0", Podname: "p1"}}
+	n1 := &types.Node{NodeMeta: types.NodeMeta{Name: "n1", Podname: "p1", MemCap: 70, InitMemCap: 100}}
+	n2 := &types.Node{NodeMeta: types.NodeMeta{Name: "n2", Podname: "p1", MemCap: 60, InitMemCap: 100}}
+	store.On("GetNode", mock.Anything, "n0").Return(n0, nil)
+	store.On("GetNodesByPod", mock.Anything, "p1", mock.Anything, false).Return([]*types.Node{n0, n1, n2}, nil)
+	workload := func(ID string, memory int64) *types.Workload {
+		return &types.Workload{ID: ID, Nodename: "n0", ResourceMeta: types.ResourceMeta{MemoryRequest: memory, MemoryLimit: memory}}
+	}
+	store.On("ListNodeWorkloads", mock.Anything, "n0", mock.Anything).Return([]*types.Workload{workload("w3", 30), workload("w1", 60), workload("w2", 50)}, nil)
+
+	// failed by getting pod default strategy
+	store.On("GetPod", mock.Anything, "p1").Return(nil, types.ErrNoETCD).Once()
+	_, err = c.PlanDrain(ctx, "n0")
+	assert.Error(t, err)
+	store.On("GetPod", mock.Anything, "p1").Return(&types.Pod{Name: "p1", DefaultStrategy: strategy.Fill}, nil)
+
+	plan, err := c.PlanDrain(ctx, "n0")
+	assert.NoError(t, err)
+	assert.Equal(t, "n0", plan.Nodename)
+	assert.EqualValues(t, 140, plan.Memory)
+	assert.Equal(t, map[string]string{"w1": "n1", "w2": "n2"}, plan.Targets)
+	assert.Contains(t, plan.Shortfalls, "w3")
+	assert.False(t, plan.Feasible())
+}
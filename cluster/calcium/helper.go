@@ -105,6 +105,22 @@ func pullImage(ctx context.Context, node *types.Node, image string) error {
 	return nil
 }
 
+// workloadResourceOptions requests the same resources as workload to schedule it again
+func workloadResourceOptions(workload *types.Workload) types.ResourceOptions {
+	return types.ResourceOptions{
+		CPUQuotaRequest: workload.CPUQuotaRequest,
+		CPUQuotaLimit:   workload.CPUQuotaLimit,
+		CPUBind:         len(workload.CPU) > 0,
+		MemoryRequest:   workload.MemoryRequest,
+		MemoryLimit:     workload.MemoryLimit,
+		StorageRequest:  workload.StorageRequest,
+		StorageLimit:    workload.StorageLimit,
+		StoragePool:     workload.StoragePool,
+		VolumeRequest:   workload.VolumeRequest,
+		VolumeLimit:     workload.VolumeLimit,
+	}
+}
+
 func makeCopyMessage(id, name, path string, err error, data io.ReadCloser) *types.CopyMessage {
 	return &types.CopyMessage{
 		ID:    id,
@@ -14,10 +14,12 @@ import (
 func (c *Calcium) doLock(ctx context.Context, name string, timeout time.Duration) (lock.DistributedLock, context.Context, error) {
 	lock, err := c.store.CreateLock(name, timeout)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, &types.LockError{Name: name, Cause: err}
 	}
-	ctx, err = lock.Lock(ctx)
-	return lock, ctx, err
+	if ctx, err = lock.Lock(ctx); err != nil {
+		return lock, ctx, &types.LockError{Name: name, Cause: err}
+	}
+	return lock, ctx, nil
 }
 
 func (c *Calcium) doUnlock(ctx context.Context, lock lock.DistributedLock, msg string) error {
@@ -53,6 +55,16 @@ func (c *Calcium) withNodeLocked(ctx context.Context, nodename string, f func(co
 	})
 }
 
+// withNodeUnlocked is break-glass for reading a node whose lock is stuck, never write with it
+func (c *Calcium) withNodeUnlocked(ctx context.Context, nodename string, f func(context.Context, *types.Node) error) error {
+	node, err := c.GetNode(ctx, nodename)
+	if err != nil {
+		return err
+	}
+	log.Warnf("[withNodeUnlocked] node %s read without lock", nodename)
+	return f(ctx, node)
+}
+
 func (c *Calcium) withWorkloadsLocked(ctx context.Context, ids []string, f func(context.Context, map[string]*types.Workload) error) error {
 	workloads := map[string]*types.Workload{}
 	locks := map[string]lock.DistributedLock{}
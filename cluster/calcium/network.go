@@ -2,16 +2,46 @@ package calcium
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/engine"
 	enginetypes "github.com/projecteru2/core/engine/types"
+	"github.com/projecteru2/core/log"
 	"github.com/projecteru2/core/types"
+	"github.com/projecteru2/core/utils"
 )
 
 // ListNetworks by podname
-// get one node from a pod
-// and list networks
-// only get those driven by network driver
-func (c *Calcium) ListNetworks(ctx context.Context, podname string, driver string) ([]*enginetypes.Network, error) {
+// list networks on every node of the pod
+// and merge them by name, only get those driven by network driver
+// nodes timed out are skipped, Nodes of each network tells which nodes served it
+// labels are filtered by engine if supported and checked again here
+// more tells whether there are networks after the page
+func (c *Calcium) ListNetworks(ctx context.Context, opts *types.ListNetworksOptions) (networks []*enginetypes.Network, more bool, err error) {
+	if err = opts.Validate(); err != nil {
+		return nil, false, err
+	}
+	if networks, err = c.doListPodNetworks(ctx, opts.Podname, opts.Driver, opts.Labels); err != nil {
+		return networks, false, err
+	}
+	if opts.Offset >= len(networks) {
+		return []*enginetypes.Network{}, false, nil
+	}
+	networks = networks[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(networks) {
+		return networks[:opts.Limit], true, nil
+	}
+	return networks, false, nil
+}
+
+func (c *Calcium) doListPodNetworks(ctx context.Context, podname, driver string, labels map[string]string) ([]*enginetypes.Network, error) {
 	networks := []*enginetypes.Network{}
 	nodes, err := c.ListPodNodes(ctx, podname, nil, false)
 	if err != nil {
@@ -22,31 +52,795 @@ func (c *Calcium) ListNetworks(ctx context.Context, podname string, driver strin
 		return networks, types.NewDetailedErr(types.ErrPodNoNodes, podname)
 	}
 
+	// nodes whose engine can't list networks are ignored
+	if nodes, err = filterNetworkCapableNodes(podname, nodes, enginetypes.CapNetworkList); err != nil {
+		return networks, err
+	}
+
 	drivers := []string{}
 	if driver != "" {
 		drivers = append(drivers, driver)
 	}
 
+	if len(nodes) == 1 {
+		ns, err := c.doListNetworksWithRetry(ctx, nodes[0], drivers, labels)
+		if err != nil {
+			return networks, errors.Wrapf(err, "list networks on node %s failed", nodes[0].Name)
+		}
+		for _, n := range ns {
+			n.Nodes = []string{nodes[0].Name}
+			networks = append(networks, n)
+		}
+		sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+		return networks, nil
+	}
+
+	merged := map[string]*enginetypes.Network{}
+	subnets := map[string]map[string]struct{}{}
+	served := 0
+	var lastErr error
+	for _, node := range nodes {
+		ns, err := c.doListNetworksWithRetry(ctx, node, drivers, labels)
+		if err != nil && isRetryableEngineError(ctx, err) {
+			log.Warnf("[ListNetworks] List networks on node %s failed %v, try next one", node.Name, err)
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return networks, errors.Wrapf(err, "list networks on node %s failed", node.Name)
+		}
+		served++
+		for _, n := range ns {
+			m, ok := merged[n.Name]
+			if !ok {
+				m = &enginetypes.Network{Name: n.Name, Subnets: []string{}, Labels: n.Labels}
+				merged[n.Name] = m
+				subnets[n.Name] = map[string]struct{}{}
+				networks = append(networks, m)
+			}
+			for _, subnet := range n.Subnets {
+				if _, ok := subnets[n.Name][subnet]; !ok {
+					subnets[n.Name][subnet] = struct{}{}
+					m.Subnets = append(m.Subnets, subnet)
+				}
+			}
+			m.Nodes = append(m.Nodes, node.Name)
+		}
+	}
+	if served == 0 {
+		return networks, errors.Wrapf(lastErr, "list networks on all nodes of pod %s failed", podname)
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	return networks, nil
+}
+
+// filterNetworkCapableNodes keeps nodes in order whose engine has all the capabilities
+// e.g. systemd engine has no network driver at all
+func filterNetworkCapableNodes(podname string, nodes []*types.Node, capabilities ...string) ([]*types.Node, error) {
+	capable := []*types.Node{}
+	for _, node := range nodes {
+		ok := true
+		for _, capability := range capabilities {
+			ok = ok && node.Engine.Capabilities().Has(capability)
+		}
+		if ok {
+			capable = append(capable, node)
+		}
+	}
+	if len(capable) == 0 {
+		return nil, types.NewDetailedErr(types.ErrPodNoNetworkCapableNodes, fmt.Sprintf("pod %s, %s", podname, strings.Join(capabilities, ",")))
+	}
+	return capable, nil
+}
+
+func doCheckCapability(engine engine.API, capability string) error {
+	if !engine.Capabilities().Has(capability) {
+		return types.NewDetailedErr(types.ErrEngineUnsupported, capability)
+	}
+	return nil
+}
+
+// retries of listing networks on one node for retryable errors, backoff doubles each time
+const listNetworksRetries = 2
+
+var listNetworksBackoff = 100 * time.Millisecond
+
+// doListNetworksWithRetry retries on errors of engine restarting or hanging, others fail at once
+func (c *Calcium) doListNetworksWithRetry(ctx context.Context, node *types.Node, drivers []string, labels map[string]string) ([]*enginetypes.Network, error) {
+	backoff := listNetworksBackoff
+	for i := 0; ; i++ {
+		ns, err := c.doListNetworks(ctx, node, drivers, labels)
+		if err == nil || i == listNetworksRetries || !isRetryableEngineError(ctx, err) {
+			return ns, err
+		}
+		log.Warnf("[ListNetworks] List networks on node %s failed %v, retry %d after %v", node.Name, err, i+1, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, errors.WithStack(ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryableEngineError tells errors that may go away, e.g. daemon restarting
+// errors of caller's ctx are not, nothing can be done within it
+func isRetryableEngineError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// engine clients may flatten the cause into message
+	msg := err.Error()
+	return strings.Contains(msg, "connection refused") || strings.Contains(msg, "connection reset") || strings.Contains(msg, "Cannot connect to the Docker daemon")
+}
+
+func (c *Calcium) doListNetworks(ctx context.Context, node *types.Node, drivers []string, labels map[string]string) ([]*enginetypes.Network, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.GlobalTimeout)
+	defer cancel()
+	ns, err := node.Engine.NetworkList(ctx, drivers, labels)
+	if err != nil || len(labels) == 0 {
+		return ns, err
+	}
+	filtered := []*enginetypes.Network{}
+	for _, n := range ns {
+		if utils.FilterWorkload(n.Labels, labels) {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered, nil
+}
+
+// InspectNetwork by podname
+// get the first node able to inspect network from a pod
+// and inspect the network on it
+func (c *Calcium) InspectNetwork(ctx context.Context, podname string, network string) (*enginetypes.Network, error) {
+	nodes, err := c.ListPodNodes(ctx, podname, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) == 0 {
+		return nil, types.NewDetailedErr(types.ErrPodNoNodes, podname)
+	}
+	if nodes, err = filterNetworkCapableNodes(podname, nodes, enginetypes.CapNetworkInspect); err != nil {
+		return nil, err
+	}
+
 	node := nodes[0]
-	return node.Engine.NetworkList(ctx, drivers)
+	return node.Engine.NetworkInspect(ctx, network)
+}
+
+// NetworkUsage by podname
+// inspect networks on every node of the pod
+// and count allocated addresses of each subnet
+func (c *Calcium) NetworkUsage(ctx context.Context, podname string, driver string) ([]*types.NetworkUsage, error) {
+	usages := []*types.NetworkUsage{}
+	nodes, err := c.ListPodNodes(ctx, podname, nil, false)
+	if err != nil {
+		return usages, err
+	}
+
+	if len(nodes) == 0 {
+		return usages, types.NewDetailedErr(types.ErrPodNoNodes, podname)
+	}
+	if nodes, err = filterNetworkCapableNodes(podname, nodes, enginetypes.CapNetworkList, enginetypes.CapNetworkInspect); err != nil {
+		return usages, err
+	}
+
+	drivers := []string{}
+	if driver != "" {
+		drivers = append(drivers, driver)
+	}
+
+	ipams := map[string]map[string]*enginetypes.IPAMConfig{}
+	addresses := map[string]map[string]struct{}{}
+	for _, node := range nodes {
+		ns, err := node.Engine.NetworkList(ctx, drivers, nil)
+		if err != nil {
+			return usages, errors.Wrapf(err, "list networks on node %s failed", node.Name)
+		}
+		for _, n := range ns {
+			network, err := node.Engine.NetworkInspect(ctx, n.Name)
+			if err != nil {
+				return usages, errors.Wrapf(err, "inspect network %s on node %s failed", n.Name, node.Name)
+			}
+			if network == nil {
+				continue
+			}
+			if _, ok := ipams[n.Name]; !ok {
+				ipams[n.Name] = map[string]*enginetypes.IPAMConfig{}
+				addresses[n.Name] = map[string]struct{}{}
+			}
+			for _, ipam := range network.IPAM {
+				ipams[n.Name][ipam.Subnet] = ipam
+			}
+			for _, address := range network.Addresses {
+				addresses[n.Name][address] = struct{}{}
+			}
+		}
+	}
+
+	for name, configs := range ipams {
+		for subnet, ipam := range configs {
+			usage, err := doCountNetworkUsage(ipam, addresses[name])
+			if err != nil {
+				return usages, errors.Wrapf(err, "count usage of network %s failed", name)
+			}
+			usage.Network = name
+			usage.Subnet = subnet
+			usages = append(usages, usage)
+		}
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Network == usages[j].Network {
+			return usages[i].Subnet < usages[j].Subnet
+		}
+		return usages[i].Network < usages[j].Network
+	})
+	return usages, nil
+}
+
+// NetworkWorkloads by podname
+// inspect the network on every node having it
+// and map connected endpoints to workloads of the node
+func (c *Calcium) NetworkWorkloads(ctx context.Context, podname string, network string) (*types.NetworkWorkloads, error) {
+	nodes, err := c.ListPodNodes(ctx, podname, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) == 0 {
+		return nil, types.NewDetailedErr(types.ErrPodNoNodes, podname)
+	}
+	if nodes, err = filterNetworkCapableNodes(podname, nodes, enginetypes.CapNetworkList, enginetypes.CapNetworkInspect); err != nil {
+		return nil, err
+	}
+
+	result := &types.NetworkWorkloads{Network: network, Workloads: []*types.NetworkEndpoint{}, Orphans: []*types.NetworkEndpoint{}}
+	for _, node := range nodes {
+		ns, err := node.Engine.NetworkList(ctx, nil, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "list networks on node %s failed", node.Name)
+		}
+		found := false
+		for _, n := range ns {
+			found = found || n.Name == network
+		}
+		if !found {
+			continue
+		}
+
+		n, err := node.Engine.NetworkInspect(ctx, network)
+		if err != nil {
+			return nil, errors.Wrapf(err, "inspect network %s on node %s failed", network, node.Name)
+		}
+		if n == nil || len(n.Endpoints) == 0 {
+			continue
+		}
+		workloads, err := c.store.ListNodeWorkloads(ctx, node.Name, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "list workloads on node %s failed", node.Name)
+		}
+		workloadMap := map[string]*types.Workload{}
+		for _, workload := range workloads {
+			workloadMap[workload.ID] = workload
+		}
+
+		for ID, addresses := range n.Endpoints {
+			endpoint := &types.NetworkEndpoint{ID: ID, Nodename: node.Name, Addresses: addresses, Workload: workloadMap[ID]}
+			if endpoint.Workload == nil {
+				result.Orphans = append(result.Orphans, endpoint)
+				continue
+			}
+			result.Workloads = append(result.Workloads, endpoint)
+		}
+	}
+
+	for _, endpoints := range [][]*types.NetworkEndpoint{result.Workloads, result.Orphans} {
+		sort.Slice(endpoints, func(i, j int) bool {
+			if endpoints[i].Nodename == endpoints[j].Nodename {
+				return endpoints[i].ID < endpoints[j].ID
+			}
+			return endpoints[i].Nodename < endpoints[j].Nodename
+		})
+	}
+	if len(result.Orphans) > 0 {
+		log.Warnf("[NetworkWorkloads] %d endpoints of network %s have no workload", len(result.Orphans), network)
+	}
+	return result, nil
+}
+
+// doCountNetworkUsage counts addresses of the subnet, or of the ip range if given
+// network and broadcast addresses of IPv4 are not allocatable, gateway is always taken
+func doCountNetworkUsage(ipam *enginetypes.IPAMConfig, addresses map[string]struct{}) (*types.NetworkUsage, error) {
+	pool := ipam.Subnet
+	if ipam.IPRange != "" {
+		pool = ipam.IPRange
+	}
+	_, ipnet, err := net.ParseCIDR(pool)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	usage := &types.NetworkUsage{Total: math.MaxUint64}
+	if bits-ones < 64 {
+		usage.Total = uint64(1) << uint(bits-ones)
+	}
+	if ipnet.IP.To4() != nil && bits-ones > 1 {
+		usage.Total -= 2
+	}
+
+	if gateway := net.ParseIP(ipam.Gateway); gateway != nil && ipnet.Contains(gateway) {
+		if _, ok := addresses[gateway.String()]; !ok {
+			usage.Used++
+		}
+	}
+	for address := range addresses {
+		if ip := net.ParseIP(address); ip != nil && ipnet.Contains(ip) {
+			usage.Used++
+		}
+	}
+	if usage.Used < usage.Total {
+		usage.Free = usage.Total - usage.Used
+	}
+	return usage, nil
+}
+
+// drivers connecting hosts themselves, network is visible to every node once created
+var globalNetworkDrivers = map[string]bool{"overlay": true, "calico": true}
+
+// CreateNetwork on nodes of pod
+// network of global driver only needs to be created on one node
+// otherwise created on every node, and removed from nodes already done if any of them failed
+func (c *Calcium) CreateNetwork(ctx context.Context, podname string, opts *enginetypes.NetworkCreateOptions) (*enginetypes.Network, error) {
+	if opts.Name == "" {
+		return nil, types.ErrEmptyNetworkName
+	}
+	if opts.Subnet != "" {
+		_, ipnet, err := net.ParseCIDR(opts.Subnet)
+		if err != nil {
+			return nil, types.NewDetailedErr(types.ErrInvalidIP, opts.Subnet)
+		}
+		if gateway := net.ParseIP(opts.Gateway); opts.Gateway != "" && (gateway == nil || !ipnet.Contains(gateway)) {
+			return nil, types.NewDetailedErr(types.ErrInvalidIP, fmt.Sprintf("gateway %s not in subnet %s", opts.Gateway, opts.Subnet))
+		}
+	}
+
+	nodes, err := c.doGetNetworkNodes(ctx, podname, opts.Driver, enginetypes.CapNetworkCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	created := []*types.Node{}
+	err = utils.Txn(
+		ctx,
+		// if
+		func(ctx context.Context) error {
+			for _, node := range nodes {
+				if _, err := node.Engine.NetworkCreate(ctx, opts); err != nil {
+					return errors.Wrapf(err, "create network %s on node %s failed", opts.Name, node.Name)
+				}
+				created = append(created, node)
+			}
+			return nil
+		},
+		// then
+		nil,
+		// rollback
+		func(ctx context.Context, _ bool) (err error) {
+			for _, node := range created {
+				if e := node.Engine.NetworkRemove(ctx, opts.Name); e != nil {
+					log.Errorf("[CreateNetwork] remove network %s from node %s failed %v", opts.Name, node.Name, e)
+					err = e
+				}
+			}
+			return err
+		},
+		c.config.GlobalTimeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	network := &enginetypes.Network{Name: opts.Name, Driver: opts.Driver, Subnets: []string{}}
+	if opts.Subnet != "" {
+		network.Subnets = append(network.Subnets, opts.Subnet)
+	}
+	for _, node := range created {
+		network.Nodes = append(network.Nodes, node.Name)
+	}
+	return network, nil
+}
+
+// RemoveNetwork from nodes of pod
+// network of global driver only needs to be removed on one node
+// nodes without the network are taken as removed, so a partly failed removal can be retried
+func (c *Calcium) RemoveNetwork(ctx context.Context, podname string, network string) error {
+	if network == "" {
+		return types.ErrEmptyNetworkName
+	}
+
+	nodes, err := c.doGetNetworkNodes(ctx, podname, "", enginetypes.CapNetworkRemove)
+	if err != nil {
+		return err
+	}
+
+	// find out driver from any node has it
+	for _, node := range nodes {
+		if !node.Engine.Capabilities().Has(enginetypes.CapNetworkInspect) {
+			continue
+		}
+		if n, err := node.Engine.NetworkInspect(ctx, network); err == nil && n != nil {
+			if globalNetworkDrivers[n.Driver] {
+				nodes = []*types.Node{node}
+			}
+			break
+		}
+	}
+
+	failed := []string{}
+	for _, node := range nodes {
+		err := node.Engine.NetworkRemove(ctx, network)
+		if errors.Is(err, enginetypes.ErrNetworkNotExists) {
+			log.Debugf("[RemoveNetwork] network %s not on node %s", network, node.Name)
+			continue
+		}
+		if err != nil {
+			log.Errorf("[RemoveNetwork] remove network %s on node %s failed %v", network, node.Name, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", node.Name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("remove network %s on %d nodes failed: %s", network, len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// doGetNetworkNodes gets nodes of pod able to manage network, incapable ones are skipped
+// only the first one is returned for global driver
+func (c *Calcium) doGetNetworkNodes(ctx context.Context, podname, driver, capability string) ([]*types.Node, error) {
+	nodes, err := c.ListPodNodes(ctx, podname, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, types.NewDetailedErr(types.ErrPodNoNodes, podname)
+	}
+
+	if nodes, err = filterNetworkCapableNodes(podname, nodes, capability); err != nil {
+		return nil, err
+	}
+	if globalNetworkDrivers[driver] {
+		return nodes[:1], nil
+	}
+	return nodes, nil
 }
 
 // ConnectNetwork connect to a network
+// already attached with the same addresses is taken as success
 func (c *Calcium) ConnectNetwork(ctx context.Context, network, target, ipv4, ipv6 string) ([]string, error) {
+	if err := validateAttachment(ipv4, ipv6); err != nil {
+		return nil, err
+	}
+
 	workload, err := c.GetWorkload(ctx, target)
 	if err != nil {
 		return nil, err
 	}
+	if err := doCheckCapability(workload.Engine, enginetypes.CapNetworkConnect); err != nil {
+		return nil, err
+	}
 
-	return workload.Engine.NetworkConnect(ctx, network, target, ipv4, ipv6)
+	addresses, _, err := c.doConnectNetwork(ctx, workload, &types.NetworkAttachment{Network: network, IPv4: ipv4, IPv6: ipv6})
+	return addresses, err
 }
 
-// DisconnectNetwork connect to a network
-func (c *Calcium) DisconnectNetwork(ctx context.Context, network, target string, force bool) error {
+// ConnectNetworks connect to networks in sequence
+// already attached networks will be disconnected if any of them failed
+func (c *Calcium) ConnectNetworks(ctx context.Context, target string, attachments []*types.NetworkAttachment) ([]string, error) {
+	for _, attachment := range attachments {
+		if err := validateAttachment(attachment.IPv4, attachment.IPv6); err != nil {
+			return nil, err
+		}
+	}
+
 	workload, err := c.GetWorkload(ctx, target)
 	if err != nil {
+		return nil, err
+	}
+	if err := doCheckCapability(workload.Engine, enginetypes.CapNetworkConnect); err != nil {
+		return nil, err
+	}
+
+	addresses := []string{}
+	attached := []string{}
+	err = utils.Txn(
+		ctx,
+		// if
+		func(ctx context.Context) error {
+			for _, attachment := range attachments {
+				subnets, connected, err := c.doConnectNetwork(ctx, workload, attachment)
+				if err != nil {
+					return err
+				}
+				// leave those attached before untouched on rollback
+				if connected {
+					attached = append(attached, attachment.Network)
+				}
+				addresses = append(addresses, subnets...)
+			}
+			return nil
+		},
+		// then
+		nil,
+		// rollback
+		func(ctx context.Context, _ bool) (err error) {
+			for _, network := range attached {
+				if e := workload.Engine.NetworkDisconnect(ctx, network, workload.ID, true); e != nil {
+					log.Errorf("[ConnectNetworks] disconnect %s from network %s failed %v", workload.ID, network, e)
+					err = e
+				}
+			}
+			return err
+		},
+		c.config.GlobalTimeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// doConnectNetwork returns addresses on the network
+// and whether it's connected now or was already attached before
+func (c *Calcium) doConnectNetwork(ctx context.Context, workload *types.Workload, attachment *types.NetworkAttachment) ([]string, bool, error) {
+	ip4, _ := parseIP(attachment.IPv4, false)
+	ip6, _ := parseIP(attachment.IPv6, true)
+
+	info, err := workload.Engine.VirtualizationInspect(ctx, workload.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	// ipv6 is not reported by inspect, so attachments with ipv6 can't be confirmed
+	if address, ok := info.Networks[attachment.Network]; ok && ip6 == nil && (ip4 == nil || ip4.Equal(net.ParseIP(address))) {
+		log.Infof("[ConnectNetwork] Workload %s already attached to network %s", workload.ID, attachment.Network)
+		addresses := []string{}
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+		return addresses, false, nil
+	}
+
+	// subnets can't be checked if engine can't inspect network
+	v6only := false
+	if (ip4 != nil || ip6 != nil) && workload.Engine.Capabilities().Has(enginetypes.CapNetworkInspect) {
+		n, err := workload.Engine.NetworkInspect(ctx, attachment.Network)
+		if err != nil {
+			return nil, false, err
+		}
+		// the other family can be omitted, but never be given to a network without it
+		supports4, supports6 := subnetFamilies(n)
+		v6only = supports6 && !supports4
+		if ip4 != nil && !supports4 {
+			return nil, false, types.NewDetailedErr(types.ErrInvalidIP, fmt.Sprintf("network %s has no ipv4 subnet for %s", attachment.Network, ip4))
+		}
+		if ip6 != nil && !supports6 {
+			return nil, false, types.NewDetailedErr(types.ErrInvalidIP, fmt.Sprintf("network %s has no ipv6 subnet for %s", attachment.Network, ip6))
+		}
+		for _, ip := range []net.IP{ip4, ip6} {
+			if ip != nil && !inSubnets(n, ip) {
+				return nil, false, types.NewDetailedErr(types.ErrInvalidIP, fmt.Sprintf("%s not in subnets of network %s", ip, attachment.Network))
+			}
+		}
+	}
+
+	addresses, err := workload.Engine.NetworkConnect(ctx, attachment.Network, workload.ID, attachment.IPv4, attachment.IPv6)
+	if err != nil {
+		return nil, false, err
+	}
+	return connectedAddresses(addresses, ip4 == nil && v6only), true, nil
+}
+
+// connectedAddresses drops empty addresses reported by engines for families not assigned
+// and ipv4 ones when connected to a v6 only network
+func connectedAddresses(addresses []string, v6only bool) []string {
+	result := []string{}
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+		// engines may report address with prefix length
+		ip := net.ParseIP(strings.SplitN(address, "/", 2)[0])
+		if v6only && ip != nil && ip.To4() != nil {
+			continue
+		}
+		result = append(result, address)
+	}
+	return result
+}
+
+// retries of disconnecting if endpoint still exists, backoff doubles each time
+const disconnectRetries = 3
+
+var disconnectBackoff = 100 * time.Millisecond
+
+// DisconnectNetwork disconnects workload from a network
+// without force, it waits graceSeconds first, then the endpoint is confirmed gone by inspecting the workload
+// the wait only gives in-flight traffic time to finish, peers are not notified
+func (c *Calcium) DisconnectNetwork(ctx context.Context, network, target string, force bool, graceSeconds int) error {
+	workload, err := c.GetWorkload(ctx, target)
+	if err != nil {
+		return err
+	}
+	if err := doCheckCapability(workload.Engine, enginetypes.CapNetworkDisconnect); err != nil {
+		return err
+	}
+
+	if !force && graceSeconds > 0 {
+		log.Infof("[DisconnectNetwork] Wait %ds before disconnecting workload %s from network %s", graceSeconds, workload.ID, network)
+		if err := doWaitGrace(ctx, time.Duration(graceSeconds)*time.Second); err != nil {
+			return err
+		}
+	}
+
+	if err := workload.Engine.NetworkDisconnect(ctx, network, target, force); err != nil || force {
 		return err
 	}
+	return c.doVerifyDisconnected(ctx, workload, network)
+}
+
+// DisconnectAllNetworks disconnects workload from every network it's attached to
+// failure on one network doesn't stop the others, networks detached are returned anyway
+func (c *Calcium) DisconnectAllNetworks(ctx context.Context, target string, force bool) ([]string, error) {
+	workload, err := c.GetWorkload(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := doCheckCapability(workload.Engine, enginetypes.CapNetworkDisconnect); err != nil {
+		return nil, err
+	}
+	info, err := workload.Engine.VirtualizationInspect(ctx, workload.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	networks := []string{}
+	for network := range info.Networks {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
 
-	return workload.Engine.NetworkDisconnect(ctx, network, target, force)
+	detached := []string{}
+	failed := []string{}
+	for _, network := range networks {
+		err := workload.Engine.NetworkDisconnect(ctx, network, workload.ID, force)
+		if err == nil && !force {
+			err = c.doVerifyDisconnected(ctx, workload, network)
+		}
+		if err != nil {
+			log.Errorf("[DisconnectAllNetworks] disconnect %s from network %s failed %v", workload.ID, network, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", network, err))
+			continue
+		}
+		detached = append(detached, network)
+	}
+	if len(failed) > 0 {
+		return detached, errors.Errorf("disconnect from %d networks failed: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return detached, nil
+}
+
+// doVerifyDisconnected disconnects again if engine reports success but leaves the endpoint
+func (c *Calcium) doVerifyDisconnected(ctx context.Context, workload *types.Workload, network string) error {
+	backoff := disconnectBackoff
+	for i := 0; ; i++ {
+		info, err := workload.Engine.VirtualizationInspect(ctx, workload.ID)
+		if err != nil {
+			return err
+		}
+		if _, ok := info.Networks[network]; !ok {
+			return nil
+		}
+		if i == disconnectRetries {
+			return types.NewDetailedErr(types.ErrEndpointRemains, fmt.Sprintf("workload %s, network %s", workload.ID, network))
+		}
+		log.Warnf("[DisconnectNetwork] Workload %s still on network %s, retry %d after %v", workload.ID, network, i+1, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		}
+		backoff *= 2
+		if err := workload.Engine.NetworkDisconnect(ctx, network, workload.ID, false); err != nil {
+			return err
+		}
+	}
+}
+
+// doWaitGrace waits the grace period unless caller gives up
+func doWaitGrace(ctx context.Context, grace time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(grace):
+		return nil
+	}
+}
+
+func validateAttachment(ipv4, ipv6 string) error {
+	if _, err := parseIP(ipv4, false); err != nil {
+		return err
+	}
+	_, err := parseIP(ipv6, true)
+	return err
+}
+
+// parseIP parses ip, empty string is allowed
+func parseIP(s string, v6 bool) (net.IP, error) {
+	if s == "" {
+		return nil, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, types.NewDetailedErr(types.ErrInvalidIP, s)
+	}
+	if (ip.To4() == nil) != v6 {
+		return nil, types.NewDetailedErr(types.ErrInvalidIP, fmt.Sprintf("%s address family mismatch", s))
+	}
+	return ip, nil
+}
+
+// subnetFamilies tells address families of network by its ipam config, falling back to subnets
+// engines without subnets info can't be checked, both families are assumed
+func subnetFamilies(n *enginetypes.Network) (v4, v6 bool) {
+	subnets := []string{}
+	if n != nil {
+		for _, config := range n.IPAM {
+			subnets = append(subnets, config.Subnet)
+		}
+		if len(subnets) == 0 {
+			subnets = n.Subnets
+		}
+	}
+	if len(subnets) == 0 {
+		return true, true
+	}
+	for _, subnet := range subnets {
+		ip, _, err := net.ParseCIDR(subnet)
+		switch {
+		case err != nil:
+			continue
+		case ip.To4() != nil:
+			v4 = true
+		default:
+			v6 = true
+		}
+	}
+	return v4, v6
+}
+
+// inSubnets checks whether ip falls in one of network's subnets
+// engines without subnets info can't be checked, just let it pass
+func inSubnets(n *enginetypes.Network, ip net.IP) bool {
+	if n == nil || len(n.Subnets) == 0 {
+		return true
+	}
+	for _, subnet := range n.Subnets {
+		_, ipnet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			continue
+		}
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
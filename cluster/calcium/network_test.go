@@ -2,7 +2,12 @@ package calcium
 
 import (
 	"context"
+	"errors"
+	"math"
+	"net"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -14,6 +19,15 @@ import (
 	"github.com/projecteru2/core/types"
 )
 
+var networkCapabilities = enginetypes.Capabilities{
+	enginetypes.CapNetworkConnect:    true,
+	enginetypes.CapNetworkDisconnect: true,
+	enginetypes.CapNetworkList:       true,
+	enginetypes.CapNetworkInspect:    true,
+	enginetypes.CapNetworkCreate:     true,
+	enginetypes.CapNetworkRemove:     true,
+}
+
 func TestNetwork(t *testing.T) {
 	c := NewTestCluster()
 	ctx := context.Background()
@@ -21,14 +35,15 @@ func TestNetwork(t *testing.T) {
 	c.store = store
 
 	store.On("GetNodesByPod", mock.AnythingOfType("*context.emptyCtx"), mock.Anything, mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD).Once()
-	_, err := c.ListNetworks(ctx, "", "")
+	_, _, err := c.ListNetworks(ctx, &types.ListNetworksOptions{})
 	assert.Error(t, err)
 	// No nodes
 	store.On("GetNodesByPod", mock.AnythingOfType("*context.emptyCtx"), mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{}, nil).Once()
-	_, err = c.ListNetworks(ctx, "", "")
+	_, _, err = c.ListNetworks(ctx, &types.ListNetworksOptions{})
 	assert.Error(t, err)
 	// vaild
 	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
 	node := &types.Node{
 		NodeMeta: types.NodeMeta{
 			Name: "test",
@@ -37,29 +52,512 @@ func TestNetwork(t *testing.T) {
 		Engine:    engine,
 	}
 	name := "test"
-	engine.On("NetworkList", mock.Anything, mock.Anything).Return([]*enginetypes.Network{{Name: name}}, nil)
+	engine.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return([]*enginetypes.Network{{Name: name}}, nil)
 	store.On("GetNodesByPod", mock.AnythingOfType("*context.emptyCtx"), mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node}, nil)
-	ns, err := c.ListNetworks(ctx, "", "xx")
+	ns, _, err := c.ListNetworks(ctx, &types.ListNetworksOptions{Driver: "xx"})
 	assert.NoError(t, err)
 	assert.Equal(t, len(ns), 1)
 	assert.Equal(t, ns[0].Name, name)
 }
 
+func TestListNetworksAcrossNodes(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	engine1 := &enginemocks.API{}
+	engine1.On("Capabilities").Return(networkCapabilities)
+	engine2 := &enginemocks.API{}
+	engine2.On("Capabilities").Return(networkCapabilities)
+	node1 := &types.Node{NodeMeta: types.NodeMeta{Name: "node1"}, Available: true, Engine: engine1}
+	node2 := &types.Node{NodeMeta: types.NodeMeta{Name: "node2"}, Available: true, Engine: engine2}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node1, node2}, nil)
+
+	engine1.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD).Once()
+	_, _, err := c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.Error(t, err)
+
+	engine1.On("NetworkList", mock.Anything, []string{"bridge"}, mock.Anything).Return([]*enginetypes.Network{
+		{Name: "host", Subnets: []string{}},
+		{Name: "bridge", Subnets: []string{"172.17.0.0/16"}},
+	}, nil)
+	engine2.On("NetworkList", mock.Anything, []string{"bridge"}, mock.Anything).Return([]*enginetypes.Network{
+		{Name: "bridge", Subnets: []string{"172.17.0.0/16", "172.18.0.0/16"}},
+		{Name: "local", Subnets: []string{"10.0.0.0/8"}},
+	}, nil)
+	ns, _, err := c.ListNetworks(ctx, &types.ListNetworksOptions{Driver: "bridge"})
+	assert.NoError(t, err)
+	assert.Len(t, ns, 3)
+	assert.Equal(t, "bridge", ns[0].Name)
+	assert.Equal(t, []string{"172.17.0.0/16", "172.18.0.0/16"}, ns[0].Subnets)
+	assert.Equal(t, []string{"node1", "node2"}, ns[0].Nodes)
+	assert.Equal(t, "host", ns[1].Name)
+	assert.Equal(t, []string{"node1"}, ns[1].Nodes)
+	assert.Equal(t, "local", ns[2].Name)
+	assert.Equal(t, []string{"node2"}, ns[2].Nodes)
+}
+
+func TestListNetworksFilterAndPage(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	labels := map[string]string{"team": "a"}
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	// engine ignoring the label filter
+	engine.On("NetworkList", mock.Anything, mock.Anything, labels).Return([]*enginetypes.Network{
+		{Name: "n3", Labels: labels}, {Name: "other"}, {Name: "n1", Labels: labels}, {Name: "n2", Labels: map[string]string{"team": "a", "x": "y"}},
+	}, nil)
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{{NodeMeta: types.NodeMeta{Name: "node"}, Engine: engine}}, nil)
+
+	ns, more, err := c.ListNetworks(ctx, &types.ListNetworksOptions{Labels: labels})
+	assert.NoError(t, err)
+	assert.False(t, more)
+	assert.Len(t, ns, 3)
+
+	ns, more, err = c.ListNetworks(ctx, &types.ListNetworksOptions{Labels: labels, Limit: 2})
+	assert.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, "n1", ns[0].Name)
+	assert.Equal(t, "n2", ns[1].Name)
+
+	ns, more, err = c.ListNetworks(ctx, &types.ListNetworksOptions{Labels: labels, Limit: 2, Offset: 2})
+	assert.NoError(t, err)
+	assert.False(t, more)
+	assert.Len(t, ns, 1)
+	assert.Equal(t, "n3", ns[0].Name)
+
+	ns, more, err = c.ListNetworks(ctx, &types.ListNetworksOptions{Labels: labels, Offset: 3})
+	assert.NoError(t, err)
+	assert.False(t, more)
+	assert.Empty(t, ns)
+
+	// negative ones are rejected before listing
+	_, _, err = c.ListNetworks(ctx, &types.ListNetworksOptions{Offset: -1})
+	assert.True(t, errors.Is(err, types.ErrBadOffset))
+	_, _, err = c.ListNetworks(ctx, &types.ListNetworksOptions{Limit: -1})
+	assert.True(t, errors.Is(err, types.ErrBadLimit))
+}
+
+func TestListNetworksTimeout(t *testing.T) {
+	c := NewTestCluster()
+	c.config.GlobalTimeout = 50 * time.Millisecond
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	backoff := listNetworksBackoff
+	listNetworksBackoff = time.Millisecond
+	defer func() { listNetworksBackoff = backoff }()
+
+	hung := &enginemocks.API{}
+	hung.On("Capabilities").Return(networkCapabilities)
+	hung.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, _ []string, _ map[string]string) []*enginetypes.Network {
+			<-ctx.Done()
+			return nil
+		},
+		func(ctx context.Context, _ []string, _ map[string]string) error { return ctx.Err() },
+	)
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	engine.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return([]*enginetypes.Network{{Name: "bridge"}}, nil)
+	node1 := &types.Node{NodeMeta: types.NodeMeta{Name: "node1"}, Engine: hung}
+	node2 := &types.Node{NodeMeta: types.NodeMeta{Name: "node2"}, Engine: engine}
+
+	// single hung node
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node1}, nil).Once()
+	_, _, err := c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// fall back to next node
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node1, node2}, nil).Once()
+	ns, _, err := c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, ns, 1)
+	assert.Equal(t, []string{"node2"}, ns[0].Nodes)
+
+	// nodes can't list networks are ignored
+	unsupported := &enginemocks.API{}
+	unsupported.On("Capabilities").Return(enginetypes.Capabilities{})
+	node3 := &types.Node{NodeMeta: types.NodeMeta{Name: "node3"}, Engine: unsupported}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node3}, nil).Once()
+	_, _, err = c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.True(t, errors.Is(err, types.ErrPodNoNetworkCapableNodes))
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node3, node2}, nil).Once()
+	ns, _, err = c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node2"}, ns[0].Nodes)
+	unsupported.AssertNotCalled(t, "NetworkList", mock.Anything, mock.Anything, mock.Anything)
+
+	// all hung
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node1, node1}, nil).Once()
+	_, _, err = c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestListNetworksRetry(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	backoff := listNetworksBackoff
+	listNetworksBackoff = time.Millisecond
+	defer func() { listNetworksBackoff = backoff }()
+	refused := &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}
+
+	// restarting daemon comes back
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	engine.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return(nil, refused).Once()
+	engine.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return([]*enginetypes.Network{{Name: "bridge"}}, nil)
+	node1 := &types.Node{NodeMeta: types.NodeMeta{Name: "node1"}, Engine: engine}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node1}, nil).Once()
+	ns, _, err := c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, ns, 1)
+	engine.AssertNumberOfCalls(t, "NetworkList", 2)
+
+	// keeps refusing, bounded and falls back to next node
+	down := &enginemocks.API{}
+	down.On("Capabilities").Return(networkCapabilities)
+	down.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("Cannot connect to the Docker daemon at unix:///var/run/docker.sock"))
+	node2 := &types.Node{NodeMeta: types.NodeMeta{Name: "node2"}, Engine: down}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node2, node1}, nil).Once()
+	ns, _, err = c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node1"}, ns[0].Nodes)
+	down.AssertNumberOfCalls(t, "NetworkList", listNetworksRetries+1)
+
+	// fatal one is not retried nor skipped
+	broken := &enginemocks.API{}
+	broken.On("Capabilities").Return(networkCapabilities)
+	broken.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return(nil, types.ErrBadMeta)
+	node3 := &types.Node{NodeMeta: types.NodeMeta{Name: "node3"}, Engine: broken}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node3, node1}, nil).Once()
+	_, _, err = c.ListNetworks(ctx, &types.ListNetworksOptions{})
+	assert.True(t, errors.Is(err, types.ErrBadMeta))
+	broken.AssertNumberOfCalls(t, "NetworkList", 1)
+
+	// caller gives up
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, isRetryableEngineError(cancelled, context.DeadlineExceeded))
+	assert.True(t, isRetryableEngineError(ctx, refused))
+}
+
+func TestInspectNetwork(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD).Once()
+	_, err := c.InspectNetwork(ctx, "", "")
+	assert.Error(t, err)
+	// No nodes
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{}, nil).Once()
+	_, err = c.InspectNetwork(ctx, "", "")
+	assert.Error(t, err)
+	// vaild
+	engine := &enginemocks.API{}
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{
+			Name: "test",
+		},
+		Available: true,
+		Engine:    engine,
+	}
+	name := "test"
+	engine.On("NetworkInspect", mock.Anything, name).Return(&enginetypes.Network{
+		Name:       name,
+		Subnets:    []string{"10.0.0.0/24"},
+		IPAM:       []*enginetypes.IPAMConfig{{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1"}},
+		Containers: []string{"id1", "id2"},
+	}, nil)
+	engine.On("Capabilities").Return(networkCapabilities)
+	// first node can't inspect network
+	unsupported := &enginemocks.API{}
+	unsupported.On("Capabilities").Return(enginetypes.Capabilities{})
+	systemd := &types.Node{NodeMeta: types.NodeMeta{Name: "systemd"}, Engine: unsupported}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{systemd}, nil).Once()
+	_, err = c.InspectNetwork(ctx, "", name)
+	assert.True(t, errors.Is(err, types.ErrPodNoNetworkCapableNodes))
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{systemd, node}, nil)
+	n, err := c.InspectNetwork(ctx, "", name)
+	assert.NoError(t, err)
+	assert.Equal(t, n.Name, name)
+	assert.Equal(t, n.IPAM[0].Gateway, "10.0.0.1")
+	assert.Len(t, n.Containers, 2)
+	unsupported.AssertNotCalled(t, "NetworkInspect", mock.Anything, mock.Anything)
+}
+
 func TestConnectNetwork(t *testing.T) {
 	c := NewTestCluster()
 	ctx := context.Background()
 	store := &storemocks.Store{}
 	c.store = store
 	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	engine.On("VirtualizationInspect", mock.Anything, mock.Anything).Return(&enginetypes.VirtualizationInfo{}, nil)
 	workload := &types.Workload{Engine: engine}
 
 	store.On("GetWorkload", mock.Anything, mock.Anything).Return(nil, types.ErrBadMeta).Once()
 	_, err := c.ConnectNetwork(ctx, "network", "123", "", "")
 	assert.Error(t, err)
+	// unsupported by engine
+	unsupported := &enginemocks.API{}
+	unsupported.On("Capabilities").Return(enginetypes.Capabilities{})
+	store.On("GetWorkload", mock.Anything, mock.Anything).Return(&types.Workload{Engine: unsupported}, nil).Once()
+	_, err = c.ConnectNetwork(ctx, "network", "123", "", "")
+	assert.True(t, errors.Is(err, types.ErrEngineUnsupported))
 	store.On("GetWorkload", mock.Anything, mock.Anything).Return(workload, nil)
 	engine.On("NetworkConnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]string{}, nil)
 	_, err = c.ConnectNetwork(ctx, "network", "123", "", "")
 	assert.NoError(t, err)
+
+	// invalid ip
+	_, err = c.ConnectNetwork(ctx, "network", "123", "10.0.0.256", "")
+	assert.True(t, errors.Is(err, types.ErrInvalidIP))
+	_, err = c.ConnectNetwork(ctx, "network", "123", "fe80::1", "")
+	assert.True(t, errors.Is(err, types.ErrInvalidIP))
+	_, err = c.ConnectNetwork(ctx, "network", "123", "", "10.0.0.2")
+	assert.True(t, errors.Is(err, types.ErrInvalidIP))
+	engine.AssertNotCalled(t, "NetworkInspect", mock.Anything, mock.Anything)
+
+	// check subnets
+	engine.On("NetworkInspect", mock.Anything, "network").Return(&enginetypes.Network{Name: "network", Subnets: []string{"10.0.0.0/24", "fd00::/64"}}, nil)
+	_, err = c.ConnectNetwork(ctx, "network", "123", "10.0.1.2", "")
+	assert.True(t, errors.Is(err, types.ErrInvalidIP))
+	_, err = c.ConnectNetwork(ctx, "network", "123", "10.0.0.2", "fd01::2")
+	assert.True(t, errors.Is(err, types.ErrInvalidIP))
+	_, err = c.ConnectNetwork(ctx, "network", "123", "10.0.0.2", "fd00::2")
+	assert.NoError(t, err)
+
+	// check families by ipam
+	engine.On("NetworkInspect", mock.Anything, "v4only").Return(&enginetypes.Network{Name: "v4only", Subnets: []string{"10.0.0.0/24"}, IPAM: []*enginetypes.IPAMConfig{{Subnet: "10.0.0.0/24"}}}, nil)
+	_, err = c.ConnectNetwork(ctx, "v4only", "123", "10.0.0.2", "fd00::2")
+	assert.True(t, errors.Is(err, types.ErrInvalidIP))
+	assert.Contains(t, err.Error(), "no ipv6 subnet")
+	_, err = c.ConnectNetwork(ctx, "v4only", "123", "10.0.0.2", "")
+	assert.NoError(t, err)
+	engine.On("NetworkInspect", mock.Anything, "v6only").Return(&enginetypes.Network{Name: "v6only", IPAM: []*enginetypes.IPAMConfig{{Subnet: "fd00::/64"}}}, nil)
+	_, err = c.ConnectNetwork(ctx, "v6only", "123", "10.0.0.2", "")
+	assert.Contains(t, err.Error(), "no ipv4 subnet")
+	_, err = c.ConnectNetwork(ctx, "v6only", "123", "", "fd00::2")
+	assert.NoError(t, err)
+}
+
+func TestConnectNetworkIPv6Only(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(&enginetypes.VirtualizationInfo{}, nil)
+	store.On("GetWorkload", mock.Anything, mock.Anything).Return(&types.Workload{ID: "123", Engine: engine}, nil)
+	engine.On("NetworkInspect", mock.Anything, "v6only").Return(&enginetypes.Network{Name: "v6only", IPAM: []*enginetypes.IPAMConfig{{Subnet: "fd00::/64"}}}, nil)
+	engine.On("NetworkInspect", mock.Anything, "dual").Return(&enginetypes.Network{Name: "dual", Subnets: []string{"10.0.0.0/24", "fd00::/64"}}, nil)
+	engine.On("NetworkConnect", mock.Anything, "v6only", "123", "", "fd00::2").Return([]string{"", "10.0.0.9", "fd00::2"}, nil)
+	engine.On("NetworkConnect", mock.Anything, "dual", "123", "", "fd00::2").Return([]string{"", "10.0.0.9/24", "fd00::2"}, nil)
+
+	// only v6 address of v6 only network
+	addresses, err := c.ConnectNetwork(ctx, "v6only", "123", "", "fd00::2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fd00::2"}, addresses)
+	engine.AssertCalled(t, "NetworkConnect", mock.Anything, "v6only", "123", "", "fd00::2")
+
+	// auto allocated v4 of dual stack is kept
+	addresses, err = c.ConnectNetwork(ctx, "dual", "123", "", "fd00::2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.9/24", "fd00::2"}, addresses)
+}
+
+func TestConnectNetworkAttached(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(&enginetypes.VirtualizationInfo{Networks: map[string]string{"n1": "10.0.0.2"}}, nil)
+	store.On("GetWorkload", mock.Anything, mock.Anything).Return(&types.Workload{ID: "123", Engine: engine}, nil)
+
+	// same address or any address
+	addresses, err := c.ConnectNetwork(ctx, "n1", "123", "10.0.0.2", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, addresses)
+	addresses, err = c.ConnectNetwork(ctx, "n1", "123", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, addresses)
+	engine.AssertNotCalled(t, "NetworkConnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// different address goes to engine
+	engine.On("NetworkInspect", mock.Anything, "n1").Return(&enginetypes.Network{Name: "n1"}, nil)
+	engine.On("NetworkConnect", mock.Anything, "n1", "123", "10.0.0.3", "").Return(nil, types.ErrNoETCD)
+	_, err = c.ConnectNetwork(ctx, "n1", "123", "10.0.0.3", "")
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+
+	// attached one is not disconnected on rollback
+	engine.On("NetworkConnect", mock.Anything, "n2", "123", "", "").Return(nil, types.ErrNoETCD)
+	_, err = c.ConnectNetworks(ctx, "123", []*types.NetworkAttachment{{Network: "n1"}, {Network: "n2"}})
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	engine.AssertNotCalled(t, "NetworkDisconnect", mock.Anything, "n1", "123", true)
+}
+
+func TestConnectNetworks(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(&enginetypes.VirtualizationInfo{}, nil)
+	workload := &types.Workload{ID: "123", Engine: engine}
+	attachments := []*types.NetworkAttachment{{Network: "n1"}, {Network: "n2", IPv4: "10.0.0.2"}, {Network: "n3"}}
+
+	// invalid ip
+	_, err := c.ConnectNetworks(ctx, "123", []*types.NetworkAttachment{{Network: "n1", IPv4: "fe80::1"}})
+	assert.True(t, errors.Is(err, types.ErrInvalidIP))
+	store.On("GetWorkload", mock.Anything, mock.Anything).Return(nil, types.ErrBadMeta).Once()
+	_, err = c.ConnectNetworks(ctx, "123", attachments)
+	assert.Error(t, err)
+	store.On("GetWorkload", mock.Anything, mock.Anything).Return(workload, nil)
+
+	// rollback
+	engine.On("NetworkInspect", mock.Anything, "n2").Return(&enginetypes.Network{Name: "n2", Subnets: []string{"10.0.0.0/24"}}, nil)
+	engine.On("NetworkConnect", mock.Anything, "n1", "123", "", "").Return([]string{"10.0.1.2"}, nil)
+	engine.On("NetworkConnect", mock.Anything, "n2", "123", "10.0.0.2", "").Return(nil, types.ErrNoETCD).Once()
+	engine.On("NetworkDisconnect", mock.Anything, "n1", "123", true).Return(nil)
+	_, err = c.ConnectNetworks(ctx, "123", attachments)
+	assert.Error(t, err)
+	engine.AssertCalled(t, "NetworkDisconnect", mock.Anything, "n1", "123", true)
+	engine.AssertNotCalled(t, "NetworkDisconnect", mock.Anything, "n2", "123", true)
+	engine.AssertNotCalled(t, "NetworkConnect", mock.Anything, "n3", "123", "", "")
+
+	// success
+	engine.On("NetworkConnect", mock.Anything, "n2", "123", "10.0.0.2", "").Return([]string{"10.0.0.2"}, nil)
+	engine.On("NetworkConnect", mock.Anything, "n3", "123", "", "").Return([]string{"10.0.2.2"}, nil)
+	addresses, err := c.ConnectNetworks(ctx, "123", attachments)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.1.2", "10.0.0.2", "10.0.2.2"}, addresses)
+}
+
+func TestNetworkUsage(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{}, nil).Once()
+	_, err := c.NetworkUsage(ctx, "", "")
+	assert.Error(t, err)
+
+	engine1 := &enginemocks.API{}
+	engine2 := &enginemocks.API{}
+	nodes := []*types.Node{
+		{NodeMeta: types.NodeMeta{Name: "n1"}, Engine: engine1},
+		{NodeMeta: types.NodeMeta{Name: "n2"}, Engine: engine2},
+	}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nodes, nil)
+	engine1.On("Capabilities").Return(networkCapabilities)
+	engine2.On("Capabilities").Return(networkCapabilities)
+	engine1.On("NetworkList", mock.Anything, []string{"calico"}, mock.Anything).Return([]*enginetypes.Network{{Name: "net"}}, nil)
+	engine2.On("NetworkList", mock.Anything, []string{"calico"}, mock.Anything).Return([]*enginetypes.Network{{Name: "net"}}, nil)
+	ipam := []*enginetypes.IPAMConfig{
+		{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1"},
+		{Subnet: "fd00::/120"},
+	}
+	engine1.On("NetworkInspect", mock.Anything, "net").Return(&enginetypes.Network{
+		Name:      "net",
+		IPAM:      ipam,
+		Addresses: []string{"10.0.0.2", "fd00::2"},
+	}, nil)
+	engine2.On("NetworkInspect", mock.Anything, "net").Return(&enginetypes.Network{
+		Name:      "net",
+		IPAM:      ipam,
+		Addresses: []string{"10.0.0.2", "10.0.0.3", "10.0.1.3"},
+	}, nil).Once()
+	usages, err := c.NetworkUsage(ctx, "", "calico")
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.NetworkUsage{
+		{Network: "net", Subnet: "10.0.0.0/24", Total: 254, Used: 3, Free: 251},
+		{Network: "net", Subnet: "fd00::/120", Total: 256, Used: 1, Free: 255},
+	}, usages)
+
+	// inspect failed
+	engine2.On("NetworkInspect", mock.Anything, "net").Return(nil, types.ErrNilEngine)
+	_, err = c.NetworkUsage(ctx, "", "calico")
+	assert.True(t, errors.Is(err, types.ErrNilEngine))
+	assert.Contains(t, err.Error(), "n2")
+}
+
+func TestNetworkWorkloads(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{}, nil).Once()
+	_, err := c.NetworkWorkloads(ctx, "", "net")
+	assert.Error(t, err)
+
+	engine1 := &enginemocks.API{}
+	engine2 := &enginemocks.API{}
+	nodes := []*types.Node{
+		{NodeMeta: types.NodeMeta{Name: "n1"}, Engine: engine1},
+		{NodeMeta: types.NodeMeta{Name: "n2"}, Engine: engine2},
+	}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nodes, nil)
+	engine1.On("Capabilities").Return(networkCapabilities)
+	engine2.On("Capabilities").Return(networkCapabilities)
+	engine1.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return([]*enginetypes.Network{{Name: "net"}}, nil)
+	// n2 doesn't have the network
+	engine2.On("NetworkList", mock.Anything, mock.Anything, mock.Anything).Return([]*enginetypes.Network{{Name: "other"}}, nil)
+
+	// inspect failed
+	engine1.On("NetworkInspect", mock.Anything, "net").Return(nil, types.ErrNilEngine).Once()
+	_, err = c.NetworkWorkloads(ctx, "", "net")
+	assert.True(t, errors.Is(err, types.ErrNilEngine))
+	assert.Contains(t, err.Error(), "n1")
+
+	engine1.On("NetworkInspect", mock.Anything, "net").Return(&enginetypes.Network{
+		Name: "net",
+		Endpoints: map[string][]string{
+			"w2":     {"10.0.0.3"},
+			"w1":     {"10.0.0.2", "fd00::2"},
+			"orphan": {"10.0.0.4"},
+		},
+	}, nil)
+	w1 := &types.Workload{ID: "w1", Nodename: "n1"}
+	w2 := &types.Workload{ID: "w2", Nodename: "n1"}
+	store.On("ListNodeWorkloads", mock.Anything, "n1", mock.Anything).Return([]*types.Workload{w1, w2}, nil)
+	result, err := c.NetworkWorkloads(ctx, "", "net")
+	assert.NoError(t, err)
+	assert.Equal(t, "net", result.Network)
+	assert.Equal(t, []*types.NetworkEndpoint{
+		{ID: "w1", Nodename: "n1", Addresses: []string{"10.0.0.2", "fd00::2"}, Workload: w1},
+		{ID: "w2", Nodename: "n1", Addresses: []string{"10.0.0.3"}, Workload: w2},
+	}, result.Workloads)
+	assert.Equal(t, []*types.NetworkEndpoint{{ID: "orphan", Nodename: "n1", Addresses: []string{"10.0.0.4"}}}, result.Orphans)
+	engine2.AssertNotCalled(t, "NetworkInspect", mock.Anything, mock.Anything)
+}
+
+func TestCountNetworkUsage(t *testing.T) {
+	usage, err := doCountNetworkUsage(&enginetypes.IPAMConfig{Subnet: "10.0.0.0/16", IPRange: "10.0.1.0/28", Gateway: "10.0.0.1"}, map[string]struct{}{"10.0.1.2": {}})
+	assert.NoError(t, err)
+	assert.Equal(t, &types.NetworkUsage{Total: 14, Used: 1, Free: 13}, usage)
+
+	usage, err = doCountNetworkUsage(&enginetypes.IPAMConfig{Subnet: "fd00::/48"}, nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, uint64(math.MaxUint64), usage.Total)
+	assert.EqualValues(t, uint64(math.MaxUint64), usage.Free)
+
+	_, err = doCountNetworkUsage(&enginetypes.IPAMConfig{Subnet: "invalid"}, nil)
+	assert.Error(t, err)
 }
 
 func TestDisConnectNetwork(t *testing.T) {
@@ -68,13 +566,214 @@ func TestDisConnectNetwork(t *testing.T) {
 	store := &storemocks.Store{}
 	c.store = store
 	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
 	workload := &types.Workload{Engine: engine}
 
 	store.On("GetWorkload", mock.Anything, mock.Anything).Return(nil, types.ErrBadMeta).Once()
-	err := c.DisconnectNetwork(ctx, "network", "123", true)
+	err := c.DisconnectNetwork(ctx, "network", "123", true, 0)
 	assert.Error(t, err)
+	// unsupported by engine
+	unsupported := &enginemocks.API{}
+	unsupported.On("Capabilities").Return(enginetypes.Capabilities{})
+	store.On("GetWorkload", mock.Anything, mock.Anything).Return(&types.Workload{Engine: unsupported}, nil).Once()
+	err = c.DisconnectNetwork(ctx, "network", "123", false, 10)
+	assert.True(t, errors.Is(err, types.ErrEngineUnsupported))
 	store.On("GetWorkload", mock.Anything, mock.Anything).Return(workload, nil)
 	engine.On("NetworkDisconnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
-	err = c.DisconnectNetwork(ctx, "network", "123", true)
+	err = c.DisconnectNetwork(ctx, "network", "123", true, 0)
+	assert.NoError(t, err)
+	// force skips grace wait
+	start := time.Now()
+	err = c.DisconnectNetwork(ctx, "network", "123", true, 10)
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// grace wait is interrupted by caller, status is never touched
+	workload.StatusMeta = &types.StatusMeta{ID: "123", Running: true, Networks: map[string]string{"network": "10.0.0.1", "other": "10.0.1.1"}}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err = c.DisconnectNetwork(cctx, "network", "123", false, 10)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, &types.StatusMeta{ID: "123", Running: true, Networks: map[string]string{"network": "10.0.0.1", "other": "10.0.1.1"}}, workload.StatusMeta)
+	store.AssertNotCalled(t, "SetWorkloadStatus", mock.Anything, mock.Anything, mock.Anything)
+	engine.AssertNumberOfCalls(t, "NetworkDisconnect", 2)
+	engine.AssertNotCalled(t, "VirtualizationInspect", mock.Anything, mock.Anything)
+}
+
+func TestDisconnectNetworkVerify(t *testing.T) {
+	backoff := disconnectBackoff
+	disconnectBackoff = time.Millisecond
+	defer func() { disconnectBackoff = backoff }()
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	engine.On("NetworkDisconnect", mock.Anything, "network", "123", false).Return(nil)
+	store.On("GetWorkload", mock.Anything, mock.Anything).Return(&types.Workload{ID: "123", Engine: engine}, nil)
+
+	// gone after retry
+	attached := &enginetypes.VirtualizationInfo{Networks: map[string]string{"network": "10.0.0.1"}}
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(attached, nil).Once()
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(&enginetypes.VirtualizationInfo{}, nil).Once()
+	assert.NoError(t, c.DisconnectNetwork(ctx, "network", "123", false, 0))
+	engine.AssertNumberOfCalls(t, "NetworkDisconnect", 2)
+
+	// never gone
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(attached, nil)
+	err := c.DisconnectNetwork(ctx, "network", "123", false, 0)
+	assert.True(t, errors.Is(err, types.ErrEndpointRemains))
+	engine.AssertNumberOfCalls(t, "NetworkDisconnect", 2+1+disconnectRetries)
+
+	// backoff is interrupted by caller, no more retries
+	disconnectBackoff = time.Hour
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err = c.DisconnectNetwork(cctx, "network", "123", false, 0)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	engine.AssertNumberOfCalls(t, "NetworkDisconnect", 2+1+disconnectRetries+1)
+}
+
+func TestCreateNetwork(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	_, err := c.CreateNetwork(ctx, "p1", &enginetypes.NetworkCreateOptions{})
+	assert.True(t, errors.Is(err, types.ErrEmptyNetworkName))
+	_, err = c.CreateNetwork(ctx, "p1", &enginetypes.NetworkCreateOptions{Name: "net", Subnet: "10.0.0.0/24", Gateway: "10.0.1.1"})
+	assert.True(t, errors.Is(err, types.ErrInvalidIP))
+
+	engine1 := &enginemocks.API{}
+	engine2 := &enginemocks.API{}
+	engine1.On("Capabilities").Return(networkCapabilities)
+	engine2.On("Capabilities").Return(networkCapabilities)
+	nodes := []*types.Node{
+		{NodeMeta: types.NodeMeta{Name: "n1"}, Engine: engine1},
+		{NodeMeta: types.NodeMeta{Name: "n2"}, Engine: engine2},
+	}
+	store.On("GetNodesByPod", mock.Anything, "p1", mock.Anything, mock.Anything).Return(nodes, nil)
+
+	// local driver, created on every node
+	opts := &enginetypes.NetworkCreateOptions{Name: "net", Driver: "bridge", Subnet: "10.0.0.0/24", Gateway: "10.0.0.1"}
+	engine1.On("NetworkCreate", mock.Anything, opts).Return("id1", nil)
+	engine2.On("NetworkCreate", mock.Anything, opts).Return("id2", nil).Once()
+	n, err := c.CreateNetwork(ctx, "p1", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"n1", "n2"}, n.Nodes)
+	assert.Equal(t, []string{"10.0.0.0/24"}, n.Subnets)
+
+	// rollback
+	engine2.On("NetworkCreate", mock.Anything, opts).Return("", types.ErrNilEngine).Once()
+	engine1.On("NetworkRemove", mock.Anything, "net").Return(nil).Once()
+	_, err = c.CreateNetwork(ctx, "p1", opts)
+	assert.True(t, errors.Is(err, types.ErrNilEngine))
+	engine1.AssertCalled(t, "NetworkRemove", mock.Anything, "net")
+
+	// global driver, created on one node
+	opts = &enginetypes.NetworkCreateOptions{Name: "overlay-net", Driver: "overlay"}
+	engine1.On("NetworkCreate", mock.Anything, opts).Return("id3", nil)
+	n, err = c.CreateNetwork(ctx, "p1", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"n1"}, n.Nodes)
+	engine2.AssertNotCalled(t, "NetworkCreate", mock.Anything, opts)
+
+	// incapable nodes are skipped, even the first one
+	unsupported := &enginemocks.API{}
+	unsupported.On("Capabilities").Return(enginetypes.Capabilities{})
+	store.On("GetNodesByPod", mock.Anything, "p2", mock.Anything, mock.Anything).Return([]*types.Node{{NodeMeta: types.NodeMeta{Name: "n3"}, Engine: unsupported}, nodes[1]}, nil)
+	engine2.On("NetworkCreate", mock.Anything, opts).Return("id4", nil)
+	n, err = c.CreateNetwork(ctx, "p2", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"n2"}, n.Nodes)
+
+	// none capable
+	store.On("GetNodesByPod", mock.Anything, "p3", mock.Anything, mock.Anything).Return([]*types.Node{{NodeMeta: types.NodeMeta{Name: "n3"}, Engine: unsupported}}, nil)
+	_, err = c.CreateNetwork(ctx, "p3", opts)
+	assert.True(t, errors.Is(err, types.ErrPodNoNetworkCapableNodes))
+}
+
+func TestRemoveNetwork(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	assert.True(t, errors.Is(c.RemoveNetwork(ctx, "p1", ""), types.ErrEmptyNetworkName))
+
+	engine1 := &enginemocks.API{}
+	engine2 := &enginemocks.API{}
+	engine1.On("Capabilities").Return(networkCapabilities)
+	engine2.On("Capabilities").Return(networkCapabilities)
+	nodes := []*types.Node{
+		{NodeMeta: types.NodeMeta{Name: "n1"}, Engine: engine1},
+		{NodeMeta: types.NodeMeta{Name: "n2"}, Engine: engine2},
+	}
+	store.On("GetNodesByPod", mock.Anything, "p1", mock.Anything, mock.Anything).Return(nodes, nil)
+
+	engine1.On("NetworkInspect", mock.Anything, "net").Return(&enginetypes.Network{Name: "net", Driver: "bridge"}, nil)
+	engine1.On("NetworkRemove", mock.Anything, "net").Return(types.ErrNilEngine).Once()
+	engine2.On("NetworkRemove", mock.Anything, "net").Return(nil).Once()
+	// failure of one node doesn't stop the others
+	err := c.RemoveNetwork(ctx, "p1", "net")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "n1")
+	assert.NotContains(t, err.Error(), "n2")
+	engine2.AssertNumberOfCalls(t, "NetworkRemove", 1)
+	// retried, node already without it is taken as removed
+	engine1.On("NetworkRemove", mock.Anything, "net").Return(nil)
+	engine2.On("NetworkRemove", mock.Anything, "net").Return(types.NewDetailedErr(enginetypes.ErrNetworkNotExists, "net")).Once()
+	assert.NoError(t, c.RemoveNetwork(ctx, "p1", "net"))
+
+	engine1.On("NetworkInspect", mock.Anything, "overlay-net").Return(&enginetypes.Network{Name: "overlay-net", Driver: "overlay"}, nil)
+	engine1.On("NetworkRemove", mock.Anything, "overlay-net").Return(nil)
+	assert.NoError(t, c.RemoveNetwork(ctx, "p1", "overlay-net"))
+	engine2.AssertNotCalled(t, "NetworkRemove", mock.Anything, "overlay-net")
+
+	// driver resolved from the node has it, incapable nodes are skipped
+	unsupported := &enginemocks.API{}
+	unsupported.On("Capabilities").Return(enginetypes.Capabilities{})
+	store.On("GetNodesByPod", mock.Anything, "p2", mock.Anything, mock.Anything).Return([]*types.Node{{NodeMeta: types.NodeMeta{Name: "n3"}, Engine: unsupported}, nodes[0], nodes[1]}, nil)
+	engine1.On("NetworkInspect", mock.Anything, "calico-net").Return(nil, types.ErrNilEngine)
+	engine2.On("NetworkInspect", mock.Anything, "calico-net").Return(&enginetypes.Network{Name: "calico-net", Driver: "calico"}, nil)
+	engine2.On("NetworkRemove", mock.Anything, "calico-net").Return(nil)
+	assert.NoError(t, c.RemoveNetwork(ctx, "p2", "calico-net"))
+	engine1.AssertNotCalled(t, "NetworkRemove", mock.Anything, "calico-net")
+	unsupported.AssertNotCalled(t, "NetworkRemove", mock.Anything, mock.Anything)
+}
+
+func TestDisconnectAllNetworks(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(networkCapabilities)
+	store.On("GetWorkload", mock.Anything, mock.Anything).Return(&types.Workload{ID: "123", Engine: engine}, nil)
+
+	// failed by inspect
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(nil, types.ErrNoETCD).Once()
+	_, err := c.DisconnectAllNetworks(ctx, "123", true)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+
+	// one failure doesn't stop the others
+	attached := &enginetypes.VirtualizationInfo{Networks: map[string]string{"n1": "10.0.0.1", "n2": "10.0.1.1", "n3": "10.0.2.1"}}
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(attached, nil).Once()
+	engine.On("NetworkDisconnect", mock.Anything, "n1", "123", true).Return(nil)
+	engine.On("NetworkDisconnect", mock.Anything, "n2", "123", true).Return(types.ErrNoETCD)
+	engine.On("NetworkDisconnect", mock.Anything, "n3", "123", true).Return(nil)
+	detached, err := c.DisconnectAllNetworks(ctx, "123", true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "n2")
+	assert.Equal(t, []string{"n1", "n3"}, detached)
+
+	// verified without force
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(&enginetypes.VirtualizationInfo{Networks: map[string]string{"n1": "10.0.0.1"}}, nil).Once()
+	engine.On("VirtualizationInspect", mock.Anything, "123").Return(&enginetypes.VirtualizationInfo{}, nil)
+	engine.On("NetworkDisconnect", mock.Anything, "n1", "123", false).Return(nil)
+	detached, err = c.DisconnectAllNetworks(ctx, "123", false)
 	assert.NoError(t, err)
+	assert.Equal(t, []string{"n1"}, detached)
 }
@@ -2,7 +2,9 @@ package calcium
 
 import (
 	"context"
+	"fmt"
 
+	enginetypes "github.com/projecteru2/core/engine/types"
 	"github.com/projecteru2/core/log"
 	"github.com/projecteru2/core/types"
 	"github.com/sanity-io/litter"
@@ -34,6 +36,29 @@ func (c *Calcium) RemoveNode(ctx context.Context, nodename string) error {
 	})
 }
 
+// CordonNode stops placing new workloads on node, workloads running are left as is
+func (c *Calcium) CordonNode(ctx context.Context, nodename string) error {
+	return c.doSetNodeCordoned(ctx, nodename, true)
+}
+
+// UncordonNode makes node schedulable again
+func (c *Calcium) UncordonNode(ctx context.Context, nodename string) error {
+	return c.doSetNodeCordoned(ctx, nodename, false)
+}
+
+func (c *Calcium) doSetNodeCordoned(ctx context.Context, nodename string, cordoned bool) error {
+	if nodename == "" {
+		return types.ErrEmptyNodeName
+	}
+	return c.withNodeLocked(ctx, nodename, func(ctx context.Context, node *types.Node) error {
+		if node.Cordoned == cordoned {
+			return nil
+		}
+		node.Cordoned = cordoned
+		return c.store.UpdateNodes(ctx, node)
+	})
+}
+
 // ListPodNodes list nodes belong to pod
 func (c *Calcium) ListPodNodes(ctx context.Context, podname string, labels map[string]string, all bool) ([]*types.Node, error) {
 	return c.store.GetNodesByPod(ctx, podname, labels, all)
@@ -47,6 +72,15 @@ func (c *Calcium) GetNode(ctx context.Context, nodename string) (*types.Node, er
 	return c.store.GetNode(ctx, nodename)
 }
 
+// NodeCapabilities get operations supported by engine of node
+func (c *Calcium) NodeCapabilities(ctx context.Context, nodename string) (enginetypes.Capabilities, error) {
+	node, err := c.GetNode(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	return node.Engine.Capabilities(), nil
+}
+
 // SetNode set node available or not
 func (c *Calcium) SetNode(ctx context.Context, opts *types.SetNodeOptions) (*types.Node, error) { // nolint
 	if err := opts.Validate(); err != nil {
@@ -128,6 +162,11 @@ func (c *Calcium) SetNode(ctx context.Context, opts *types.SetNodeOptions) (*typ
 				}
 			}
 		}
+		if opts.ShareBase > 0 {
+			if err := doSetShareBase(n, opts.ShareBase); err != nil {
+				return err
+			}
+		}
 		// update volume
 		for volumeDir, changeCap := range opts.DeltaVolume {
 			_, ok := n.Volume[volumeDir]
@@ -146,10 +185,34 @@ func (c *Calcium) SetNode(ctx context.Context, opts *types.SetNodeOptions) (*typ
 				}
 			}
 		}
-		return c.store.UpdateNodes(ctx, n)
+		if err := c.store.UpdateNodes(ctx, n); err != nil {
+			return err
+		}
+		// cached resource is stale with init capacities changed
+		if opts.ChangesCapacity() {
+			c.doInvalidateNodeResource(n.Name)
+		}
+		return nil
 	})
 }
 
+// doSetShareBase rescales every core to the new share base
+// shares bound are pieces of the old base, e.g. a full core would look half free on a doubled one
+// so a core with shares bound can't be rescaled
+func doSetShareBase(node *types.Node, shareBase int) error {
+	for cpuID, initShare := range node.InitCPU {
+		if used := initShare - node.CPU[cpuID]; used > 0 && initShare != int64(shareBase) {
+			return types.NewDetailedErr(types.ErrBadCPU, fmt.Sprintf("cpu %s has %d shares bound, can't rescale to share base %d", cpuID, used, shareBase))
+		}
+	}
+	for cpuID, initShare := range node.InitCPU {
+		node.CPU[cpuID] += int64(shareBase) - initShare
+		node.InitCPU[cpuID] = int64(shareBase)
+	}
+	node.ShareBase = shareBase
+	return nil
+}
+
 // GetNodes get nodes
 func (c *Calcium) getNodes(ctx context.Context, podname string, nodenames []string, labels map[string]string, all bool) ([]*types.Node, error) {
 	var err error
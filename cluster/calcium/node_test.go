@@ -2,8 +2,11 @@ package calcium
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	enginemocks "github.com/projecteru2/core/engine/mocks"
+	enginetypes "github.com/projecteru2/core/engine/types"
 	lockmocks "github.com/projecteru2/core/lock/mocks"
 	storemocks "github.com/projecteru2/core/store/mocks"
 	"github.com/projecteru2/core/types"
@@ -125,6 +128,25 @@ func TestGetNode(t *testing.T) {
 	assert.Equal(t, n.Name, name)
 }
 
+func TestNodeCapabilities(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+
+	_, err := c.NodeCapabilities(ctx, "")
+	assert.Error(t, err)
+
+	engine := &enginemocks.API{}
+	engine.On("Capabilities").Return(enginetypes.Capabilities{enginetypes.CapNetworkConnect: true})
+	store := &storemocks.Store{}
+	store.On("GetNode", mock.Anything, "test").Return(&types.Node{NodeMeta: types.NodeMeta{Name: "test"}, Engine: engine}, nil)
+	c.store = store
+
+	capabilities, err := c.NodeCapabilities(ctx, "test")
+	assert.NoError(t, err)
+	assert.True(t, capabilities.Has(enginetypes.CapNetworkConnect))
+	assert.False(t, capabilities.Has(enginetypes.CapNetworkList))
+}
+
 func TestSetNode(t *testing.T) {
 	c := NewTestCluster()
 	ctx := context.Background()
@@ -249,6 +271,33 @@ func TestSetNode(t *testing.T) {
 	assert.Equal(t, n.InitCPU["3"], int64(10))
 	assert.Equal(t, len(n.CPU), 2)
 	assert.Equal(t, len(n.InitCPU), 2)
+	// cores with shares bound can't be rescaled
+	setOpts.DeltaCPU = nil
+	setOpts.ShareBase = 100
+	_, err = c.SetNode(ctx, setOpts)
+	assert.True(t, errors.Is(err, types.ErrBadCPU))
+	// a fully bound core doesn't turn partly free
+	n.CPU = types.CPUMap{"2": 0, "3": 100}
+	n.InitCPU = types.CPUMap{"2": 100, "3": 100}
+	setOpts.ShareBase = 200
+	_, err = c.SetNode(ctx, setOpts)
+	assert.True(t, errors.Is(err, types.ErrBadCPU))
+	assert.Equal(t, types.CPUMap{"2": 0, "3": 100}, n.CPU)
+	assert.Equal(t, types.CPUMap{"2": 100, "3": 100}, n.InitCPU)
+	// same share base keeps bound cores
+	setOpts.ShareBase = 100
+	n, err = c.SetNode(ctx, setOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, types.CPUMap{"2": 0, "3": 100}, n.CPU)
+	// rescale free cores
+	n.CPU = types.CPUMap{"2": 9, "3": 10}
+	n.InitCPU = types.CPUMap{"2": 9, "3": 10}
+	n, err = c.SetNode(ctx, setOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, types.CPUMap{"2": 100, "3": 100}, n.CPU)
+	assert.Equal(t, types.CPUMap{"2": 100, "3": 100}, n.InitCPU)
+	assert.Equal(t, 100, n.GetShareBase(10))
+	setOpts.ShareBase = 0
 	// succ set volume
 	n.Volume = types.VolumeMap{"/sda1": 10, "/sda2": 20}
 	setOpts.DeltaCPU = nil
@@ -260,3 +309,31 @@ func TestSetNode(t *testing.T) {
 	assert.Equal(t, n.Volume["/sda0"], int64(5))
 	assert.Equal(t, n.Volume["/sda2"], int64(19))
 }
+
+func TestCordonNode(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	node := &types.Node{NodeMeta: types.NodeMeta{Name: "n1"}, Available: true}
+	store.On("GetNode", mock.Anything, "n1").Return(node, nil)
+
+	assert.True(t, errors.Is(c.CordonNode(ctx, ""), types.ErrEmptyNodeName))
+	store.On("UpdateNodes", mock.Anything, mock.Anything).Return(types.ErrNoETCD).Once()
+	assert.True(t, errors.Is(c.CordonNode(ctx, "n1"), types.ErrNoETCD))
+	store.On("UpdateNodes", mock.Anything, mock.Anything).Return(nil)
+	node.Cordoned = false
+	assert.NoError(t, c.CordonNode(ctx, "n1"))
+	assert.True(t, node.Cordoned)
+	assert.True(t, node.Available)
+	// already cordoned, nothing written
+	assert.NoError(t, c.CordonNode(ctx, "n1"))
+	store.AssertNumberOfCalls(t, "UpdateNodes", 2)
+	assert.NoError(t, c.UncordonNode(ctx, "n1"))
+	assert.False(t, node.Cordoned)
+	store.AssertNumberOfCalls(t, "UpdateNodes", 3)
+}
@@ -3,6 +3,7 @@ package calcium
 import (
 	"context"
 
+	"github.com/projecteru2/core/strategy"
 	"github.com/projecteru2/core/types"
 )
 
@@ -34,6 +35,36 @@ func (c *Calcium) GetPod(ctx context.Context, podname string) (*types.Pod, error
 	return c.store.GetPod(ctx, podname)
 }
 
+// SetPodDefaultStrategy sets strategy for deploys in pod without one, empty clears it
+func (c *Calcium) SetPodDefaultStrategy(ctx context.Context, podname, deployStrategy string) (*types.Pod, error) {
+	if podname == "" {
+		return nil, types.ErrEmptyPodName
+	}
+	if deployStrategy != "" && !strategy.Known(deployStrategy) {
+		return nil, types.NewDetailedErr(types.ErrBadDeployStrategy, deployStrategy)
+	}
+	pod, err := c.store.GetPod(ctx, podname)
+	if err != nil {
+		return nil, err
+	}
+	pod.DefaultStrategy = deployStrategy
+	return pod, c.store.UpdatePod(ctx, pod)
+}
+
+// doFillDefaultStrategy takes strategy of pod if opts has none
+// every entry point planning by strategy calls it, so they all plan like a real deploy
+func (c *Calcium) doFillDefaultStrategy(ctx context.Context, opts *types.DeployOptions) error {
+	if opts.DeployStrategy != "" || opts.Podname == "" {
+		return nil
+	}
+	pod, err := c.store.GetPod(ctx, opts.Podname)
+	if err != nil {
+		return err
+	}
+	opts.DeployStrategy = pod.DefaultStrategy
+	return nil
+}
+
 // ListPods show pods
 func (c *Calcium) ListPods(ctx context.Context) ([]*types.Pod, error) {
 	return c.store.GetAllPods(ctx)
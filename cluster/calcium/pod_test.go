@@ -4,8 +4,11 @@ import (
 	"context"
 	"testing"
 
+	"github.com/pkg/errors"
+
 	lockmocks "github.com/projecteru2/core/lock/mocks"
 	storemocks "github.com/projecteru2/core/store/mocks"
+	"github.com/projecteru2/core/strategy"
 	"github.com/projecteru2/core/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -87,3 +90,53 @@ func TestGetPod(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, p.Name, name)
 }
+
+func TestSetPodDefaultStrategy(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+
+	_, err := c.SetPodDefaultStrategy(ctx, "", strategy.Auto)
+	assert.Error(t, err)
+	_, err = c.SetPodDefaultStrategy(ctx, "p1", "invalid")
+	assert.True(t, errors.Is(err, types.ErrBadDeployStrategy))
+
+	pod := &types.Pod{Name: "p1"}
+	store := &storemocks.Store{}
+	defer store.AssertExpectations(t)
+	c.store = store
+	store.On("GetPod", mock.Anything, "p1").Return(pod, nil)
+	store.On("UpdatePod", mock.Anything, pod).Return(nil)
+
+	p, err := c.SetPodDefaultStrategy(ctx, "p1", strategy.Fill)
+	assert.NoError(t, err)
+	assert.Equal(t, strategy.Fill, p.DefaultStrategy)
+
+	// empty clears
+	p, err = c.SetPodDefaultStrategy(ctx, "p1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", p.DefaultStrategy)
+}
+
+func TestFillDefaultStrategy(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	// given strategy is kept, no pod no default
+	opts := &types.DeployOptions{Podname: "p1", DeployStrategy: strategy.Auto}
+	assert.NoError(t, c.doFillDefaultStrategy(ctx, opts))
+	assert.Equal(t, strategy.Auto, opts.DeployStrategy)
+	opts = &types.DeployOptions{}
+	assert.NoError(t, c.doFillDefaultStrategy(ctx, opts))
+	assert.Equal(t, "", opts.DeployStrategy)
+	store.AssertNotCalled(t, "GetPod", mock.Anything, mock.Anything)
+
+	store.On("GetPod", mock.Anything, "nopod").Return(nil, types.ErrNoETCD)
+	assert.True(t, errors.Is(c.doFillDefaultStrategy(ctx, &types.DeployOptions{Podname: "nopod"}), types.ErrNoETCD))
+
+	store.On("GetPod", mock.Anything, "p1").Return(&types.Pod{Name: "p1", DefaultStrategy: strategy.Fill}, nil)
+	opts = &types.DeployOptions{Podname: "p1"}
+	assert.NoError(t, c.doFillDefaultStrategy(ctx, opts))
+	assert.Equal(t, strategy.Fill, opts.DeployStrategy)
+}
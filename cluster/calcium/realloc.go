@@ -39,6 +39,7 @@ func (c *Calcium) ReallocResource(ctx context.Context, opts *types.ReallocOption
 // transaction: node resource
 func (c *Calcium) doReallocOnNode(ctx context.Context, nodename string, workload *types.Workload, rrs resourcetypes.ResourceRequests) error {
 	return c.withNodeLocked(ctx, nodename, func(ctx context.Context, node *types.Node) error {
+		defer c.doInvalidateNodeResource(node.Name)
 		node.RecycleResources(&workload.ResourceMeta)
 		plans, err := resources.SelectNodesByResourceRequests(rrs, map[string]*types.Node{node.Name: node})
 		if err != nil {
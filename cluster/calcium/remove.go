@@ -31,6 +31,7 @@ func (c *Calcium) RemoveWorkload(ctx context.Context, ids []string, force bool,
 				ret := &types.RemoveWorkloadMessage{WorkloadID: id, Success: false, Hook: []*bytes.Buffer{}}
 				if err := c.withWorkloadLocked(ctx, id, func(ctx context.Context, workload *types.Workload) error {
 					return c.withNodeLocked(ctx, workload.Nodename, func(ctx context.Context, node *types.Node) (err error) {
+						defer c.doInvalidateNodeResource(node.Name)
 						return utils.Txn(
 							ctx,
 							// if
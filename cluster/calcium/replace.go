@@ -57,17 +57,7 @@ func (c *Calcium) ReplaceWorkload(ctx context.Context, opts *types.ReplaceOption
 					}
 					// 使用复制之后的配置
 					// 停老的，起新的
-					replaceOpts.ResourceOpts = types.ResourceOptions{
-						CPUQuotaRequest: workload.CPUQuotaRequest,
-						CPUQuotaLimit:   workload.CPUQuotaLimit,
-						CPUBind:         len(workload.CPU) > 0,
-						MemoryRequest:   workload.MemoryRequest,
-						MemoryLimit:     workload.MemoryLimit,
-						StorageRequest:  workload.StorageRequest,
-						StorageLimit:    workload.StorageLimit,
-						VolumeRequest:   workload.VolumeRequest,
-						VolumeLimit:     workload.VolumeLimit,
-					}
+					replaceOpts.ResourceOpts = workloadResourceOptions(workload)
 					// 覆盖 podname 如果做全量更新的话
 					replaceOpts.Podname = workload.Podname
 					// 覆盖 Volumes
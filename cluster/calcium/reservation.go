@@ -0,0 +1,151 @@
+package calcium
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/log"
+	resourcetypes "github.com/projecteru2/core/resources/types"
+	"github.com/projecteru2/core/types"
+	"github.com/projecteru2/core/utils"
+)
+
+// ReserveCapacity holds resources of workloads scheduled like deploying
+// resources are given back by ReleaseReservation, deploying with the token, or expiring
+func (c *Calcium) ReserveCapacity(ctx context.Context, opts *types.ReserveOptions) (*types.Reservation, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if err := c.doFillDefaultStrategy(ctx, &opts.DeployOptions); err != nil {
+		return nil, err
+	}
+	// expired ones may hold the capacity needed
+	if err := c.ReclaimReservations(ctx); err != nil {
+		log.Warnf("[ReserveCapacity] reclaim expired reservations failed %v", err)
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = c.config.ReservationTTL
+	}
+	reservation := &types.Reservation{
+		Token:     utils.RandomString(32),
+		Podname:   opts.Podname,
+		Resources: map[string][]*types.ResourceMeta{},
+		ExpireAt:  time.Now().Add(ttl),
+	}
+	if err := c.withNodesLocked(ctx, opts.Podname, opts.Nodenames, opts.NodeLabels, false, func(ctx context.Context, nodeMap map[string]*types.Node) error {
+		plans, deployMap, err := c.doAllocResource(ctx, nodeMap, &opts.DeployOptions)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		nodes := []*types.Node{}
+		for nodename, deploy := range deployMap {
+			node := nodeMap[nodename]
+			for idx := 0; idx < deploy; idx++ {
+				r := &types.ResourceMeta{}
+				for _, plan := range plans {
+					if r, err = plan.Dispense(resourcetypes.DispenseOptions{Node: node, Index: idx}, r); err != nil {
+						return errors.WithStack(err)
+					}
+				}
+				reservation.Resources[nodename] = append(reservation.Resources[nodename], r)
+			}
+			for _, plan := range plans {
+				plan.ApplyChangesOnNode(node, utils.Range(deploy)...)
+			}
+			nodes = append(nodes, node)
+		}
+
+		// saved with nodes at once, a reservation never exists without its resources taken
+		defer c.doInvalidateNodesResource(nodes)
+		return errors.WithStack(c.store.AddReservation(ctx, reservation, nodes))
+	}); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// ReleaseReservation gives back reserved resources
+func (c *Calcium) ReleaseReservation(ctx context.Context, token string) error {
+	reservation, err := c.store.GetReservation(ctx, token)
+	if err != nil {
+		return err
+	}
+	return c.doReleaseReservation(ctx, reservation)
+}
+
+// ReclaimReservations releases all expired reservations
+func (c *Calcium) ReclaimReservations(ctx context.Context) (err error) {
+	reservations, err := c.store.ListReservations(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, reservation := range reservations {
+		if !reservation.Expired(now) {
+			continue
+		}
+		log.Infof("[ReclaimReservations] reservation %s expired at %s", reservation.Token, reservation.ExpireAt)
+		if e := c.doReleaseReservation(ctx, reservation); e != nil {
+			log.Errorf("[ReclaimReservations] release reservation %s failed %v", reservation.Token, e)
+			err = e
+		}
+	}
+	return err
+}
+
+// reservation is removed with nodes at once, resources are neither leaked nor given back twice
+func (c *Calcium) doReleaseReservation(ctx context.Context, reservation *types.Reservation) error {
+	return c.withNodesLocked(ctx, reservation.Podname, reservation.Nodenames(), nil, false, func(ctx context.Context, nodeMap map[string]*types.Node) error {
+		nodes := doRecycleReservation(reservation, nodeMap)
+		defer c.doInvalidateNodesResource(nodes)
+		return c.store.RemoveReservation(ctx, reservation.Token, nodes)
+	})
+}
+
+// doConsumeReservation gives back reserved resources on locked nodes for deploying
+// caller removes the reservation with nodes after resources allocated
+func (c *Calcium) doConsumeReservation(ctx context.Context, token string, nodeMap map[string]*types.Node) (*types.Reservation, error) {
+	reservation, err := c.store.GetReservation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	for nodename := range reservation.Resources {
+		if _, ok := nodeMap[nodename]; !ok {
+			return nil, types.NewDetailedErr(types.ErrInvalidReservation, fmt.Sprintf("token %s, node %s not in deploy", token, nodename))
+		}
+	}
+	doRecycleReservation(reservation, nodeMap)
+	return reservation, nil
+}
+
+func doRecycleReservation(reservation *types.Reservation, nodeMap map[string]*types.Node) []*types.Node {
+	nodes := []*types.Node{}
+	for nodename, resources := range reservation.Resources {
+		node := nodeMap[nodename]
+		for _, resource := range resources {
+			node.RecycleResources(resource)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// reservedResources picks resources reserved on the node
+func reservedResources(reservations []*types.Reservation, nodename string) []*types.ResourceMeta {
+	resources := []*types.ResourceMeta{}
+	for _, reservation := range reservations {
+		resources = append(resources, reservation.Resources[nodename]...)
+	}
+	return resources
+}
+
+func (c *Calcium) doInvalidateNodesResource(nodes []*types.Node) {
+	for _, node := range nodes {
+		c.doInvalidateNodeResource(node.Name)
+	}
+}
@@ -0,0 +1,182 @@
+package calcium
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	lockmocks "github.com/projecteru2/core/lock/mocks"
+	resourcetypes "github.com/projecteru2/core/resources/types"
+	"github.com/projecteru2/core/scheduler"
+	schedulermocks "github.com/projecteru2/core/scheduler/mocks"
+	storemocks "github.com/projecteru2/core/store/mocks"
+	"github.com/projecteru2/core/strategy"
+	"github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newReservationCluster(t *testing.T, node *types.Node) (*Calcium, *storemocks.Store) {
+	c := NewTestCluster()
+	c.config.ReservationTTL = time.Minute
+	store := &storemocks.Store{}
+	sche := &schedulermocks.Scheduler{}
+	scheduler.InitSchedulerV1(sche)
+	c.store = store
+	c.scheduler = sche
+
+	lock := &lockmocks.DistributedLock{}
+	lock.On("Lock", mock.Anything).Return(context.Background(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node}, nil)
+	store.On("GetNode", mock.Anything, mock.Anything).Return(node, nil)
+	store.On("MakeDeployStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	sche.On("SelectStorageNodes", mock.Anything, mock.Anything).Return(func(scheduleInfos []resourcetypes.ScheduleInfo, _ int64) []resourcetypes.ScheduleInfo {
+		return scheduleInfos
+	}, 1, nil)
+	sche.On("SelectVolumeNodes", mock.Anything, mock.Anything).Return(func(scheduleInfos []resourcetypes.ScheduleInfo, _ types.VolumeBindings) []resourcetypes.ScheduleInfo {
+		return scheduleInfos
+	}, nil, 1, nil)
+	sche.On("SelectMemoryNodes", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(scheduleInfos []resourcetypes.ScheduleInfo, _ float64, _ int64) []resourcetypes.ScheduleInfo {
+			for i := range scheduleInfos {
+				scheduleInfos[i].Capacity = 2
+			}
+			return scheduleInfos
+		}, 2, nil)
+	old := strategy.Plans[strategy.Auto]
+	strategy.Plans[strategy.Auto] = func(sis []strategy.Info, need, total, _ int) (map[string]int, error) {
+		return map[string]int{sis[0].Nodename: need}, nil
+	}
+	t.Cleanup(func() { strategy.Plans[strategy.Auto] = old })
+	return c, store
+}
+
+func TestReserveCapacity(t *testing.T) {
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{
+			Name:       "n1",
+			MemCap:     100,
+			InitMemCap: 100,
+			CPU:        types.CPUMap{"0": 100},
+			InitCPU:    types.CPUMap{"0": 100},
+		},
+	}
+	c, store := newReservationCluster(t, node)
+	ctx := context.Background()
+	opts := &types.ReserveOptions{
+		DeployOptions: types.DeployOptions{
+			Name:           "app",
+			Podname:        "p1",
+			Count:          2,
+			DeployStrategy: strategy.Auto,
+			ResourceOpts:   types.ResourceOptions{MemoryLimit: 10, MemoryRequest: 10},
+			Entrypoint:     &types.Entrypoint{Name: "entry"},
+		},
+	}
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+
+	// failed by validating
+	opts.Count = 0
+	_, err := c.ReserveCapacity(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrBadCount))
+	opts.Count = 2
+
+	// failed by getting pod default strategy
+	opts.DeployStrategy = ""
+	store.On("GetPod", mock.Anything, "p1").Return(nil, types.ErrNoETCD).Once()
+	_, err = c.ReserveCapacity(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	store.AssertNotCalled(t, "AddReservation", mock.Anything, mock.Anything, mock.Anything)
+	opts.DeployStrategy = strategy.Auto
+
+	// failed by AddReservation, no token returned
+	store.On("AddReservation", mock.Anything, mock.Anything, mock.Anything).Return(types.ErrNoETCD).Once()
+	reservation, err := c.ReserveCapacity(ctx, opts)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	assert.Nil(t, reservation)
+
+	node.MemCap = 100
+	store.On("AddReservation", mock.Anything, mock.Anything, []*types.Node{node}).Return(nil)
+	reservation, err = c.ReserveCapacity(ctx, opts)
+	assert.NoError(t, err)
+	assert.Len(t, reservation.Token, 32)
+	assert.Equal(t, []string{"n1"}, reservation.Nodenames())
+	assert.Len(t, reservation.Resources["n1"], 2)
+	assert.EqualValues(t, 80, node.MemCap)
+	assert.False(t, reservation.Expired(time.Now()))
+	assert.True(t, reservation.Expired(time.Now().Add(2*time.Minute)))
+}
+
+func TestReleaseReservation(t *testing.T) {
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{
+			Name:       "n1",
+			MemCap:     80,
+			InitMemCap: 100,
+		},
+	}
+	c, store := newReservationCluster(t, node)
+	ctx := context.Background()
+	reservation := &types.Reservation{
+		Token:   "token",
+		Podname: "p1",
+		Resources: map[string][]*types.ResourceMeta{
+			"n1": {{MemoryRequest: 10}, {MemoryRequest: 10}},
+		},
+		ExpireAt: time.Now().Add(time.Minute),
+	}
+
+	// failed by GetReservation
+	store.On("GetReservation", mock.Anything, "bad").Return(nil, types.ErrInvalidReservation)
+	err := c.ReleaseReservation(ctx, "bad")
+	assert.True(t, errors.Is(err, types.ErrInvalidReservation))
+
+	// failed by RemoveReservation
+	store.On("GetReservation", mock.Anything, "token").Return(reservation, nil)
+	store.On("RemoveReservation", mock.Anything, "token", mock.Anything).Return(types.ErrInvalidReservation).Once()
+	err = c.ReleaseReservation(ctx, "token")
+	assert.True(t, errors.Is(err, types.ErrInvalidReservation))
+
+	// nodes are saved with the reservation removed
+	node.MemCap = 80
+	store.On("RemoveReservation", mock.Anything, "token", []*types.Node{node}).Return(nil)
+	assert.NoError(t, c.ReleaseReservation(ctx, "token"))
+	assert.EqualValues(t, 100, node.MemCap)
+	store.AssertNotCalled(t, "UpdateNodes", mock.Anything, mock.Anything)
+}
+
+func TestReclaimReservations(t *testing.T) {
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{
+			Name:       "n1",
+			MemCap:     80,
+			InitMemCap: 100,
+		},
+	}
+	c, store := newReservationCluster(t, node)
+	ctx := context.Background()
+	expired := &types.Reservation{
+		Token:     "expired",
+		Podname:   "p1",
+		Resources: map[string][]*types.ResourceMeta{"n1": {{MemoryRequest: 10}}},
+		ExpireAt:  time.Now().Add(-time.Second),
+	}
+	alive := &types.Reservation{
+		Token:     "alive",
+		Podname:   "p1",
+		Resources: map[string][]*types.ResourceMeta{"n1": {{MemoryRequest: 10}}},
+		ExpireAt:  time.Now().Add(time.Minute),
+	}
+
+	store.On("ListReservations", mock.Anything).Return(nil, types.ErrNoETCD).Once()
+	assert.Error(t, c.ReclaimReservations(ctx))
+
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{expired, alive}, nil)
+	store.On("RemoveReservation", mock.Anything, "expired", []*types.Node{node}).Return(nil)
+	assert.NoError(t, c.ReclaimReservations(ctx))
+	assert.EqualValues(t, 90, node.MemCap)
+	store.AssertNotCalled(t, "RemoveReservation", mock.Anything, "alive", mock.Anything)
+}
@@ -3,144 +3,648 @@ package calcium
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
+	enginetypes "github.com/projecteru2/core/engine/types"
 	"github.com/projecteru2/core/log"
 
 	resourcetypes "github.com/projecteru2/core/resources/types"
-	"github.com/projecteru2/core/strategy"
 	"github.com/projecteru2/core/types"
 	"github.com/projecteru2/core/utils"
 )
 
 // PodResource show pod resource usage
-func (c *Calcium) PodResource(ctx context.Context, podname string) (*types.PodResource, error) {
+// withWorkloads indicates whether to carry per-workload resource usage
+func (c *Calcium) PodResource(ctx context.Context, podname string, withWorkloads bool) (*types.PodResource, error) {
 	nodes, err := c.ListPodNodes(ctx, podname, nil, true)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "list nodes of pod %s", podname)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	// listed once for all nodes, it's read only so a reservation changed meanwhile only shows as a transient diff
+	reservations, err := c.store.ListReservations(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "list reservations")
 	}
+	nodesResource := make([]*types.NodeResource, len(nodes))
+	errs := make([]error, len(nodes))
+
+	// bounded by max concurrency, results are placed by index to keep order
+	utils.Parallel(len(nodes), c.config.MaxConcurrency, func(i int) {
+		nodesResource[i], errs[i] = c.doGetNodeResource(ctx, nodes[i].Name, &types.NodeResourceOptions{WithWorkloads: withWorkloads}, reservations)
+	})
+
 	r := &types.PodResource{
-		Name:          podname,
-		NodesResource: []*types.NodeResource{},
+		Name:           podname,
+		NodesResource:  []*types.NodeResource{},
+		EngineVersions: map[string][]string{},
+		Skipped:        []string{},
 	}
-	for _, node := range nodes {
-		nodeResource, err := c.doGetNodeResource(ctx, node.Name, false)
-		if err != nil {
-			return nil, err
+	failed := []string{}
+	for i, nodeResource := range nodesResource {
+		// busy nodes don't fail the others
+		var lockErr *types.LockError
+		if errors.As(errs[i], &lockErr) {
+			log.Warnf("[PodResource] skip node %s %v", nodes[i].Name, errs[i])
+			r.Skipped = append(r.Skipped, nodes[i].Name)
+			continue
+		}
+		if errs[i] != nil {
+			log.Errorf("[PodResource] get node %s resource failed %v", nodes[i].Name, errs[i])
+			failed = append(failed, fmt.Sprintf("%s: %v", nodes[i].Name, errs[i]))
+			continue
 		}
 		r.NodesResource = append(r.NodesResource, nodeResource)
+		r.CPU.Add(float64(len(nodes[i].InitCPU)), nodeResource.CPUPercent)
+		r.Memory.Add(float64(nodes[i].InitMemCap), nodeResource.MemoryPercent)
+		r.Storage.Add(float64(nodes[i].InitStorageCap), nodeResource.StoragePercent)
+		r.Volume.Add(float64(nodes[i].InitVolume.Total()), nodeResource.VolumePercent)
+		version := strings.TrimSpace(nodeResource.EngineType + " " + nodeResource.EngineVersion)
+		r.EngineVersions[version] = append(r.EngineVersions[version], nodeResource.Name)
+	}
+	if len(r.EngineVersions) > 1 {
+		log.Warnf("[PodResource] pod %s runs %d engine versions %v", podname, len(r.EngineVersions), r.EngineVersions)
+	}
+	if len(failed) > 0 {
+		return r, errors.Errorf("get resource of %d nodes failed: %s", len(failed), strings.Join(failed, "; "))
 	}
 	return r, nil
 }
 
-// NodeResource check node's workload and resource
-func (c *Calcium) NodeResource(ctx context.Context, nodename string, fix bool) (*types.NodeResource, error) {
+// FixPodResource fixes resource accounting of every node in the pod
+// nodes failed are reported and skipped, dryRun only makes the plans
+func (c *Calcium) FixPodResource(ctx context.Context, podname string, dryRun bool) (*types.PodResourceFix, error) {
+	nodes, err := c.ListPodNodes(ctx, podname, nil, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list nodes of pod %s", podname)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	fixes := make([]*types.NodeResourceFix, len(nodes))
+
+	utils.Parallel(len(nodes), c.config.MaxConcurrency, func(i int) {
+		nodename := nodes[i].Name
+		fixes[i] = &types.NodeResourceFix{Nodename: nodename}
+		nr, err := c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{Fix: !dryRun, DryRun: dryRun}, nil)
+		if err != nil {
+			log.Errorf("[FixPodResource] fix node %s resource failed %v", nodename, err)
+			fixes[i].Error = err
+			return
+		}
+		fixes[i].Diffs, fixes[i].Plan, fixes[i].Residual = nr.Diffs, nr.FixPlan, nr.ResidualDiffs
+	})
+	return &types.PodResourceFix{Name: podname, Nodes: fixes}, nil
+}
+
+// NodeResourceMetrics renders node resource as prometheus text, it's read only and may be served from cache
+func (c *Calcium) NodeResourceMetrics(ctx context.Context, nodename string) (string, error) {
+	node, err := c.GetNode(ctx, nodename)
+	if err != nil {
+		return "", errors.Wrapf(err, "get node %s", nodename)
+	}
+	nr, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true})
+	if err != nil {
+		return "", err
+	}
+	return utils.FormatNodeResourceMetrics(node.Podname, nr), nil
+}
+
+// RefreshNodeResource recomputes init capacities of node from what's free plus what's used
+// unlike fixing, free side is trusted here, e.g. after capacity edited on node
+// the node resource is checked again on the refreshed node
+func (c *Calcium) RefreshNodeResource(ctx context.Context, nodename string) (*types.NodeResource, error) {
 	if nodename == "" {
-		return nil, types.ErrEmptyNodeName
+		return nil, errors.WithStack(types.ErrEmptyNodeName)
+	}
+	if err := c.withNodeLocked(ctx, nodename, func(ctx context.Context, node *types.Node) error {
+		workloads, err := c.ListNodeWorkloads(ctx, node.Name, nil)
+		if err != nil {
+			return errors.Wrap(err, "list workloads")
+		}
+		reservations, err := c.store.ListReservations(ctx)
+		if err != nil {
+			return errors.Wrap(err, "list reservations")
+		}
+		reserved := reservedResources(reservations, node.Name)
+		usage := newResourceUsage()
+		for _, resource := range reserved {
+			usage.add(resource)
+		}
+		for _, workload := range workloads {
+			usage.add(&workload.ResourceMeta)
+		}
+
+		initCPU := types.CPUMap{}
+		initCPU.Add(node.CPU)
+		initCPU.Add(usage.cpumap)
+		initVolume := types.VolumeMap{}
+		initVolume.Add(node.Volume)
+		initVolume.Add(usage.volumemap)
+		initNUMAMemory := types.NUMAMemory{}
+		for nodeID, memory := range node.NUMAMemory {
+			initNUMAMemory[nodeID] = memory + usage.numaMemory[nodeID]
+		}
+		var initStoragePools types.StoragePools
+		if node.StoragePools != nil {
+			initStoragePools = types.StoragePools{}
+			for pool, storage := range node.StoragePools {
+				initStoragePools[pool] = storage + usage.storagePools[pool]
+			}
+		}
+		log.Infof("[RefreshNodeResource] node %s init cpu %v -> %v, memory %d -> %d, storage %d -> %d", node.Name,
+			node.InitCPU, initCPU, node.InitMemCap, node.MemCap+usage.memory, node.InitStorageCap, node.StorageCap+usage.storage)
+
+		node.InitCPU = initCPU
+		node.InitMemCap = node.MemCap + usage.memory
+		node.InitStorageCap = node.StorageCap + usage.storage
+		node.InitNUMAMemory = initNUMAMemory
+		node.InitStoragePools = initStoragePools
+		node.InitVolume = initVolume
+		defer c.doInvalidateNodeResource(node.Name)
+		return errors.Wrap(c.store.UpdateNodes(ctx, node), "update node")
+	}); err != nil {
+		return nil, errors.Wrapf(err, "refresh resource of node %s", nodename)
 	}
+	return c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
+}
 
-	nr, err := c.doGetNodeResource(ctx, nodename, fix)
+// ListResourceFixes lists audit records of fixing node's resource
+func (c *Calcium) ListResourceFixes(ctx context.Context, nodename string) ([]*types.ResourceFixRecord, error) {
+	if nodename == "" {
+		return nil, errors.WithStack(types.ErrEmptyNodeName)
+	}
+	records, err := c.store.ListResourceFixRecords(ctx, nodename)
+	return records, errors.Wrapf(err, "list resource fixes of node %s", nodename)
+}
+
+// SnapshotNodeResource saves current resource of node to diff with later
+func (c *Calcium) SnapshotNodeResource(ctx context.Context, nodename string) (*types.NodeResourceSnapshot, error) {
+	if nodename == "" {
+		return nil, errors.WithStack(types.ErrEmptyNodeName)
+	}
+	nr, err := c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
 	if err != nil {
 		return nil, err
 	}
-	for _, workload := range nr.Workloads {
-		if _, err := workload.Inspect(ctx); err != nil { // 用于探测节点上容器是否存在
-			nr.Diffs = append(nr.Diffs, fmt.Sprintf("workload %s inspect failed %v \n", workload.ID, err))
-			continue
+	snapshot := types.NewNodeResourceSnapshot(nr, time.Now())
+	if err := c.store.AddNodeResourceSnapshot(ctx, snapshot); err != nil {
+		return nil, errors.Wrapf(err, "save snapshot of node %s", nodename)
+	}
+	return snapshot, nil
+}
+
+// DiffNodeResource computes changes of node resource since the snapshot
+func (c *Calcium) DiffNodeResource(ctx context.Context, nodename, snapshotID string) (*types.NodeResourceDelta, error) {
+	if nodename == "" {
+		return nil, errors.WithStack(types.ErrEmptyNodeName)
+	}
+	snapshot, err := c.store.GetNodeResourceSnapshot(ctx, nodename, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	nr, err := c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Diff(types.NewNodeResourceSnapshot(nr, time.Now())), nil
+}
+
+// NodeResource check node's workload and resource
+// cached one is returned if node resource cache enabled and opts neither fix nor bypass it
+// live cpu usage is compared with request when inspecting if CPUDriftRatio set
+func (c *Calcium) NodeResource(ctx context.Context, nodename string, opts *types.NodeResourceOptions) (*types.NodeResource, error) {
+	if nodename == "" {
+		return nil, errors.WithStack(types.ErrEmptyNodeName)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var nr *types.NodeResource
+	if !opts.WithWorkloads && !opts.Fix && !opts.DryRun && !opts.Refresh && !opts.Force {
+		nr = c.doGetCachedNodeResource(nodename)
+	}
+	if nr == nil {
+		var err error
+		if nr, err = c.doGetNodeResource(ctx, nodename, opts, nil); err != nil {
+			return nil, err
+		}
+	}
+	if opts.SkipInspect {
+		return nr, nil
+	}
+
+	// inspect concurrently, one stuck workload won't block the others
+	inspectErrs := make([]error, len(nr.Workloads))
+	advisories := make([]string, len(nr.Workloads))
+	utils.Parallel(len(nr.Workloads), c.config.MaxConcurrency, func(i int) {
+		workload := nr.Workloads[i]
+		inspectCtx := ctx
+		if c.config.InspectTimeout > 0 {
+			var cancel context.CancelFunc
+			inspectCtx, cancel = context.WithTimeout(ctx, c.config.InspectTimeout)
+			defer cancel()
+		}
+		if _, inspectErrs[i] = workload.Inspect(inspectCtx); inspectErrs[i] != nil { // 用于探测节点上容器是否存在
+			return
 		}
+		advisories[i] = c.doCheckCPUDrift(inspectCtx, workload)
+	})
+
+	for i, workload := range nr.Workloads {
+		if inspectErrs[i] != nil {
+			nr.AddDiff(types.ResourceDiff{Dimension: "workload", WorkloadID: workload.ID}, fmt.Sprintf("workload %s inspect failed %v \n", workload.ID, inspectErrs[i]))
+		}
+		if advisories[i] != "" {
+			nr.Advisories = append(nr.Advisories, advisories[i])
+		}
+	}
+	return nr, nil
+}
+
+// doCheckCPUDrift returns an advisory if live cpu usage is far from request
+// workloads without cpu quota or on engines without stats are skipped
+func (c *Calcium) doCheckCPUDrift(ctx context.Context, workload *types.Workload) string {
+	if c.config.CPUDriftRatio <= 0 || workload.CPUQuotaRequest <= 0 || !workload.Engine.Capabilities().Has(enginetypes.CapVirtualizationStats) {
+		return ""
+	}
+	stats, err := workload.Engine.VirtualizationStats(ctx, workload.ID)
+	if err != nil {
+		log.Warnf("[doCheckCPUDrift] get workload %s stats failed %v", workload.ID, err)
+		return ""
+	}
+	drift := (stats.CPUUsage - workload.CPUQuotaRequest) / workload.CPUQuotaRequest
+	switch {
+	case drift > c.config.CPUDriftRatio:
+		return fmt.Sprintf("workload %s cpu usage %f over request %f", workload.ID, stats.CPUUsage, workload.CPUQuotaRequest)
+	case drift < -c.config.CPUDriftRatio:
+		return fmt.Sprintf("workload %s cpu usage %f under request %f", workload.ID, stats.CPUUsage, workload.CPUQuotaRequest)
 	}
-	return nr, err
+	return ""
 }
 
-func (c *Calcium) doGetNodeResource(ctx context.Context, nodename string, fix bool) (*types.NodeResource, error) {
+// doGetNodeResource lists reservations under node lock if not given
+// fixing must not be given ones listed before locking, they may be stale
+func (c *Calcium) doGetNodeResource(ctx context.Context, nodename string, opts *types.NodeResourceOptions, reservations []*types.Reservation) (*types.NodeResource, error) {
 	var nr *types.NodeResource
-	return nr, c.withNodeLocked(ctx, nodename, func(ctx context.Context, node *types.Node) error {
+	fixed := false
+	withNode := c.withNodeLocked
+	if opts.Force {
+		withNode = c.withNodeUnlocked
+	}
+	if err := withNode(ctx, nodename, func(ctx context.Context, node *types.Node) error {
+		// stale store data of a down engine is not drift, don't report diffs on it
+		// info is got once for reachability, validation and version, it costs round trips on remote engines
+		info, err := node.Info(ctx)
+		if err != nil {
+			nr = &types.NodeResource{Name: node.Name, Diffs: []string{}, StructuredDiffs: []types.ResourceDiff{}}
+			return errors.WithStack(types.NewDetailedErr(types.ErrEngineUnreachable, fmt.Sprintf("node %s: %v", node.Name, err)))
+		}
 		workloads, err := c.ListNodeWorkloads(ctx, node.Name, nil)
 		if err != nil {
-			return err
+			return errors.Wrap(err, "list workloads")
 		}
+		if reservations == nil {
+			if reservations, err = c.store.ListReservations(ctx); err != nil {
+				return errors.Wrap(err, "list reservations")
+			}
+		}
+		reserved := reservedResources(reservations, node.Name)
 		nr = &types.NodeResource{
 			Name: node.Name, CPU: node.CPU, MemCap: node.MemCap, StorageCap: node.StorageCap,
-			Workloads: workloads, Diffs: []string{},
+			Workloads: workloads, Diffs: []string{}, StructuredDiffs: []types.ResourceDiff{}, EngineReachable: true, LockFree: opts.Force,
+		}
+		if opts.WithWorkloads {
+			nr.WorkloadsResource = map[string]*types.WorkloadResource{}
 		}
 
-		cpus := 0.0
-		memory := int64(0)
-		storage := int64(0)
-		cpumap := types.CPUMap{}
+		usage := newResourceUsage()
+		// reserved resources are taken from node as well
+		for _, resource := range reserved {
+			usage.add(resource)
+		}
 		for _, workload := range workloads {
-			cpus = utils.Round(cpus + workload.CPUQuotaRequest)
-			memory += workload.MemoryRequest
-			storage += workload.StorageRequest
-			cpumap.Add(workload.CPU)
+			workloadVolume := workload.VolumePlanRequest.IntoVolumeMap().Total()
+			usage.add(&workload.ResourceMeta)
+			if opts.WithWorkloads {
+				nr.WorkloadsResource[workload.ID] = &types.WorkloadResource{
+					ID:              workload.ID,
+					CPUQuotaRequest: workload.CPUQuotaRequest,
+					MemoryRequest:   workload.MemoryRequest,
+					MemoryLimit:     workload.MemoryLimit,
+					StorageRequest:  workload.StorageRequest,
+					VolumeRequest:   workloadVolume,
+				}
+			}
+		}
+		cpus, memory, storage, volume, cpumap, numaMemory, storagePools := usage.cpus, usage.memory, usage.storage, usage.volume, usage.cpumap, usage.numaMemory, usage.storagePools
+		// mis-initialized node leaves percent 0 instead of NaN spreading into pod resource
+		if len(node.InitCPU) > 0 {
+			nr.CPUPercent = cpus / float64(len(node.InitCPU))
+		} else {
+			nr.AddDiff(types.ResourceDiff{Dimension: "init_cpu"}, "node mis-initialized: init cpu is empty")
+		}
+		if node.InitMemCap > 0 {
+			nr.MemoryPercent = float64(memory) / float64(node.InitMemCap)
+			// limits beyond capacity are allowed, not an accounting error
+			if nr.MemoryOvercommit = float64(usage.memoryLimit) / float64(node.InitMemCap); nr.MemoryOvercommit > 1 {
+				nr.Advisories = append(nr.Advisories, fmt.Sprintf("memory limits %d over init memory %d, overcommit %.2f", usage.memoryLimit, node.InitMemCap, nr.MemoryOvercommit))
+			}
+		} else {
+			nr.AddDiff(types.ResourceDiff{Dimension: "init_memory"}, "node mis-initialized: init memory is 0")
 		}
-		nr.CPUPercent = cpus / float64(len(node.InitCPU))
-		nr.MemoryPercent = float64(memory) / float64(node.InitMemCap)
 		nr.NUMAMemoryPercent = map[string]float64{}
-		nr.VolumePercent = float64(node.VolumeUsed) / float64(node.InitVolume.Total())
+		// node without volume is fine as long as nothing is used
+		if initVolume := node.InitVolume.Total(); initVolume > 0 {
+			nr.VolumePercent = float64(node.VolumeUsed) / float64(initVolume)
+		} else if node.VolumeUsed != 0 {
+			nr.AddDiff(types.ResourceDiff{Dimension: "volume", Actual: float64(node.VolumeUsed)}, fmt.Sprintf("node mis-initialized: init volume is 0, used %d", node.VolumeUsed))
+		}
+		nr.CPUFragmentation = node.CPUFragmentation()
 		for nodeID, nmemory := range node.NUMAMemory {
-			if initMemory, ok := node.InitNUMAMemory[nodeID]; ok {
+			if initMemory, ok := node.InitNUMAMemory[nodeID]; ok && initMemory > 0 {
 				nr.NUMAMemoryPercent[nodeID] = float64(nmemory) / float64(initMemory)
 			}
 		}
-		if cpus != node.CPUUsed {
-			nr.Diffs = append(nr.Diffs, fmt.Sprintf("cpus used: %f diff: %f", node.CPUUsed, cpus))
+		// summed over many workloads, rounding alone can't keep float noise away
+		if math.Abs(cpus-node.CPUUsed) > c.config.CPUUsedEpsilon {
+			nr.AddDiff(types.ResourceDiff{Dimension: "cpu", Expected: cpus, Actual: node.CPUUsed}, fmt.Sprintf("cpus used: %f diff: %f", node.CPUUsed, cpus))
 		}
+		addCPUConflicts(nr, workloads, cpumap, node.GetShareBase(c.config.Scheduler.ShareBase))
 		node.CPU.Add(cpumap)
 		for i, v := range node.CPU {
 			if node.InitCPU[i] != v {
-				nr.Diffs = append(nr.Diffs, fmt.Sprintf("cpu %s diff %d", i, node.InitCPU[i]-v))
+				nr.AddDiff(types.ResourceDiff{Dimension: "cpu:" + i, Expected: float64(node.InitCPU[i] - cpumap[i]), Actual: float64(v - cpumap[i])}, fmt.Sprintf("cpu %s diff %d", i, node.InitCPU[i]-v))
 			}
 		}
 
 		if memory+node.MemCap != node.InitMemCap {
-			nr.Diffs = append(nr.Diffs, fmt.Sprintf("memory used: %d, diff %d", node.MemCap, node.InitMemCap-(memory+node.MemCap)))
+			nr.AddDiff(types.ResourceDiff{Dimension: "memory", Expected: float64(node.InitMemCap - memory), Actual: float64(node.MemCap)}, fmt.Sprintf("memory used: %d, diff %d", node.MemCap, node.InitMemCap-(memory+node.MemCap)))
+		}
+
+		for nodeID, initMemory := range node.InitNUMAMemory {
+			if nmemory := node.NUMAMemory[nodeID]; numaMemory[nodeID]+nmemory != initMemory {
+				nr.AddDiff(types.ResourceDiff{Dimension: "numa_memory:" + nodeID, Expected: float64(initMemory - numaMemory[nodeID]), Actual: float64(nmemory)}, fmt.Sprintf("numa node %s memory used: %d, diff %d", nodeID, nmemory, initMemory-(numaMemory[nodeID]+nmemory)))
+			}
 		}
 
 		nr.StoragePercent = 0
 		if node.InitStorageCap != 0 {
 			nr.StoragePercent = float64(storage) / float64(node.InitStorageCap)
 			if storage+node.StorageCap != node.InitStorageCap {
-				nr.Diffs = append(nr.Diffs, fmt.Sprintf("storage used: %d, diff %d", node.StorageCap, node.InitStorageCap-(storage+node.StorageCap)))
+				nr.AddDiff(types.ResourceDiff{Dimension: "storage", Expected: float64(node.InitStorageCap - storage), Actual: float64(node.StorageCap)}, fmt.Sprintf("storage used: %d, diff %d", node.StorageCap, node.InitStorageCap-(storage+node.StorageCap)))
 			}
 		}
+		nr.StoragePoolPercent = map[string]float64{}
+		for pool, initStorage := range node.InitStoragePools {
+			if initStorage > 0 {
+				nr.StoragePoolPercent[pool] = float64(storagePools[pool]) / float64(initStorage)
+			}
+			if pstorage := node.StoragePools[pool]; storagePools[pool]+pstorage != initStorage {
+				nr.AddDiff(types.ResourceDiff{Dimension: "storage:" + pool, Expected: float64(initStorage - storagePools[pool]), Actual: float64(pstorage)}, fmt.Sprintf("storage pool %s used: %d, diff %d", pool, pstorage, initStorage-(storagePools[pool]+pstorage)))
+			}
+		}
+		nr.MarkCapacity(c.config.ResourceWarnThreshold, c.config.ResourceCriticalThreshold)
+
+		if volume != node.VolumeUsed {
+			nr.AddDiff(types.ResourceDiff{Dimension: "volume", Expected: float64(volume), Actual: float64(node.VolumeUsed)}, fmt.Sprintf("volume used: %d, diff %d", node.VolumeUsed, volume-node.VolumeUsed))
+		}
 
-		if err := node.Engine.ResourceValidate(ctx, cpus, cpumap, memory, storage); err != nil {
-			nr.Diffs = append(nr.Diffs, err.Error())
+		// engines without validation are skipped, they know nothing to diff with
+		if err := node.Engine.ResourceValidate(ctx, info, cpus, cpumap, memory, storage); errors.Is(err, types.ErrEngineNotImplemented) {
+			log.Debugf("[doGetNodeResource] engine of node %s can't validate resource", node.Name)
+		} else if err != nil {
+			var validateErrs enginetypes.ResourceValidateErrors
+			if !errors.As(err, &validateErrs) {
+				nr.AddDiff(types.ResourceDiff{Dimension: "engine"}, err.Error())
+			}
+			for _, validateErr := range validateErrs {
+				nr.AddDiff(types.ResourceDiff{Dimension: validateErr.Resource}, validateErr.Error())
+			}
 		}
 
-		if fix {
-			if err := c.doFixDiffResource(ctx, node, cpus, memory, storage); err != nil {
+		addWorkloadDrifts(ctx, nr, node, workloads)
+
+		nr.EngineType, nr.EngineVersion = info.Type, info.Version
+
+		switch {
+		case opts.DryRun:
+			nr.FixPlan = c.doMakeFixPlan(node, cpus, memory, storage, volume, numaMemory, storagePools)
+		case opts.Fix && len(nr.Diffs) > 0:
+			defer c.doInvalidateNodeResource(node.Name)
+			nr.FixPlan = c.doMakeFixPlan(node, cpus, memory, storage, volume, numaMemory, storagePools)
+			if err := c.doFixDiffResource(ctx, nr.FixPlan); err != nil {
 				log.Warnf("[doGetNodeResource] fix node resource failed %v", err)
+				return err
 			}
+			fixed = true
+		case !opts.WithWorkloads && !opts.Force:
+			// cached while still locked, nothing can change it in between
+			c.doCacheNodeResource(nr)
 		}
 
 		return nil
-	})
+	}); err != nil || !fixed {
+		return nr, errors.Wrapf(err, "get resource of node %s", nodename)
+	}
+
+	// check again on refreshed node, diffs left mean the fix didn't converge, e.g. engine disagrees
+	checked, err := c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
+	if err != nil {
+		return nr, err
+	}
+	nr.ResidualDiffs = checked.Diffs
+	if len(nr.ResidualDiffs) > 0 {
+		log.Warnf("[doGetNodeResource] node %s still has %d diffs after fixing: %v", nodename, len(nr.ResidualDiffs), nr.ResidualDiffs)
+	}
+	return nr, nil
 }
 
-func (c *Calcium) doFixDiffResource(ctx context.Context, node *types.Node, cpus float64, memory, storage int64) error {
-	var n *types.Node
-	var err error
-	return utils.Txn(ctx,
-		func(ctx context.Context) error {
-			if n, err = c.GetNode(ctx, node.Name); err != nil {
-				return err
-			}
-			n.CPUUsed = cpus
-			for i, v := range node.CPU {
-				n.CPU[i] += node.InitCPU[i] - v
+// addWorkloadDrifts compares workloads in store with those the engine runs
+// ghosts are only known by store, orphans are only known by engine
+func addWorkloadDrifts(ctx context.Context, nr *types.NodeResource, node *types.Node, workloads []*types.Workload) {
+	IDs, err := node.Engine.ListWorkloadIDs(ctx)
+	if errors.Is(err, types.ErrEngineNotImplemented) {
+		return
+	}
+	if err != nil {
+		log.Warnf("[doGetNodeResource] list workloads of node %s engine failed %v", node.Name, err)
+		return
+	}
+	onEngine := map[string]bool{}
+	for _, ID := range IDs {
+		onEngine[ID] = true
+	}
+	inStore := map[string]bool{}
+	for _, workload := range workloads {
+		inStore[workload.ID] = true
+		if !onEngine[workload.ID] {
+			nr.AddDiff(types.ResourceDiff{Dimension: "workload", WorkloadID: workload.ID}, fmt.Sprintf("ghost workload %s not on engine", workload.ID))
+		}
+	}
+	sort.Strings(IDs)
+	for _, ID := range IDs {
+		if !inStore[ID] {
+			nr.AddDiff(types.ResourceDiff{Dimension: "workload", WorkloadID: ID}, fmt.Sprintf("orphan workload %s not in store", ID))
+		}
+	}
+}
+
+// resourceUsage sums requests of workloads or reservations on a node
+type resourceUsage struct {
+	cpus         float64
+	memory       int64
+	memoryLimit  int64
+	storage      int64
+	volume       int64
+	volumemap    types.VolumeMap
+	cpumap       types.CPUMap
+	numaMemory   types.NUMAMemory
+	storagePools types.StoragePools
+}
+
+func newResourceUsage() *resourceUsage {
+	return &resourceUsage{volumemap: types.VolumeMap{}, cpumap: types.CPUMap{}, numaMemory: types.NUMAMemory{}, storagePools: types.StoragePools{}}
+}
+
+func (u *resourceUsage) add(resource *types.ResourceMeta) {
+	u.cpus = utils.Round(u.cpus + resource.CPUQuotaRequest)
+	u.memory += resource.MemoryRequest
+	u.memoryLimit += resource.MemoryLimit
+	u.storage += resource.StorageRequest
+	u.volume += resource.VolumePlanRequest.IntoVolumeMap().Total()
+	u.volumemap.Add(resource.VolumePlanRequest.IntoVolumeMap())
+	u.cpumap.Add(resource.CPU)
+	if resource.NUMANode != "" {
+		u.numaMemory[resource.NUMANode] += resource.MemoryRequest
+	}
+	if resource.StoragePool != "" {
+		u.storagePools[resource.StoragePool] += resource.StorageRequest
+	}
+}
+
+// addCPUConflicts records cores claimed over share base, e.g. after buggy reallocs
+// unlike the cpu diff of node, it names the workloads competing
+func addCPUConflicts(nr *types.NodeResource, workloads []*types.Workload, cpumap types.CPUMap, shareBase int) {
+	if shareBase <= 0 {
+		return
+	}
+	owners := map[string][]string{}
+	for _, workload := range workloads {
+		for core, shares := range workload.CPU {
+			if shares > 0 {
+				owners[core] = append(owners[core], workload.ID)
 			}
-			n.MemCap += node.InitMemCap - (memory + node.MemCap)
-			n.StorageCap += node.InitStorageCap - (storage + node.StorageCap)
-			return nil
-		},
-		func(ctx context.Context) error {
-			return c.store.UpdateNodes(ctx, n)
-		},
-		nil,
-		c.config.GlobalTimeout,
-	)
+		}
+	}
+	cores := []string{}
+	for core, shares := range cpumap {
+		if shares > int64(shareBase) {
+			cores = append(cores, core)
+		}
+	}
+	sort.Strings(cores)
+	for _, core := range cores {
+		nr.AddDiff(
+			types.ResourceDiff{Dimension: "cpu:" + core, Expected: float64(shareBase), Actual: float64(cpumap[core])},
+			fmt.Sprintf("cpu %s conflict: %d shares over %d claimed by %s", core, cpumap[core], shareBase, strings.Join(owners[core], ",")),
+		)
+	}
+}
+
+// cached one is copied both in and out, since callers may append to Diffs
+func (c *Calcium) doGetCachedNodeResource(nodename string) *types.NodeResource {
+	if c.nodeResources == nil {
+		return nil
+	}
+	if nr, ok := c.nodeResources.Get(nodename); ok {
+		return copyNodeResource(nr.(*types.NodeResource))
+	}
+	return nil
+}
+
+func (c *Calcium) doCacheNodeResource(nr *types.NodeResource) {
+	if c.nodeResources != nil {
+		c.nodeResources.Set(nr.Name, copyNodeResource(nr), cache.DefaultExpiration)
+	}
+}
+
+// doInvalidateNodeResource must be called after node resource or workloads on it changed
+func (c *Calcium) doInvalidateNodeResource(nodename string) {
+	if c.nodeResources != nil {
+		c.nodeResources.Delete(nodename)
+	}
+}
+
+func copyNodeResource(nr *types.NodeResource) *types.NodeResource {
+	copied := *nr
+	copied.Diffs = append([]string{}, nr.Diffs...)
+	copied.StructuredDiffs = append([]types.ResourceDiff{}, nr.StructuredDiffs...)
+	copied.Advisories = append([]string{}, nr.Advisories...)
+	return &copied
+}
+
+// doMakeFixPlan calculates changes will be written by doFixDiffResource
+// node.CPU must already contain cpumap of all workloads
+func (c *Calcium) doMakeFixPlan(node *types.Node, cpus float64, memory, storage, volume int64, numaMemory types.NUMAMemory, storagePools types.StoragePools) *types.ResourceFixPlan {
+	plan := &types.ResourceFixPlan{
+		Nodename:     node.Name,
+		CPUUsed:      cpus,
+		VolumeUsed:   volume,
+		CPU:          types.CPUMap{},
+		MemCap:       node.InitMemCap - (memory + node.MemCap),
+		StorageCap:   node.InitStorageCap - (storage + node.StorageCap),
+		NUMAMemory:   types.NUMAMemory{},
+		StoragePools: types.StoragePools{},
+	}
+	for i, v := range node.CPU {
+		if delta := node.InitCPU[i] - v; delta != 0 {
+			plan.CPU[i] = delta
+		}
+	}
+	for nodeID, initMemory := range node.InitNUMAMemory {
+		if delta := initMemory - (numaMemory[nodeID] + node.NUMAMemory[nodeID]); delta != 0 {
+			plan.NUMAMemory[nodeID] = delta
+		}
+	}
+	for pool, initStorage := range node.InitStoragePools {
+		if delta := initStorage - (storagePools[pool] + node.StoragePools[pool]); delta != 0 {
+			plan.StoragePools[pool] = delta
+		}
+	}
+	return plan
+}
+
+// read and write are bounded separately, slow read can't starve the write
+// node and its fix record are written at once, failed one is logged to be retried
+func (c *Calcium) doFixDiffResource(ctx context.Context, plan *types.ResourceFixPlan) error {
+	readCtx, readCancel := context.WithTimeout(ctx, c.config.GlobalTimeout)
+	defer readCancel()
+	n, err := c.GetNode(readCtx, plan.Nodename)
+	if err != nil {
+		return errors.Wrapf(err, "get node %s", plan.Nodename)
+	}
+	record := &types.ResourceFixRecord{
+		Nodename:   plan.Nodename,
+		Time:       time.Now(),
+		Operator:   c.config.Auth.Username,
+		OldCPUUsed: n.CPUUsed,
+		NewCPUUsed: plan.CPUUsed,
+		MemCap:     plan.MemCap,
+		StorageCap: plan.StorageCap,
+	}
+	plan.Apply(n)
+
+	writeCtx, writeCancel := context.WithTimeout(ctx, c.config.GlobalTimeout)
+	defer writeCancel()
+	if err = c.store.AddResourceFixRecord(writeCtx, record, n); err != nil {
+		log.Errorf("[doFixDiffResource] write fix of node %s failed, plan to retry %+v, err: %v", plan.Nodename, plan, err)
+	}
+	return errors.Wrapf(err, "write fix of node %s", plan.Nodename)
 }
 
 func (c *Calcium) doAllocResource(ctx context.Context, nodeMap map[string]*types.Node, opts *types.DeployOptions) ([]resourcetypes.ResourcePlans, map[string]int, error) {
@@ -151,7 +655,7 @@ func (c *Calcium) doAllocResource(ctx context.Context, nodeMap map[string]*types
 	if err := c.store.MakeDeployStatus(ctx, opts, strategyInfos); err != nil {
 		return nil, nil, errors.WithStack(err)
 	}
-	deployMap, err := strategy.Deploy(opts, strategyInfos, total)
+	deployMap, err := c.doDeployByStrategy(nodeMap, opts, plans, strategyInfos, total)
 	if err != nil {
 		return nil, nil, errors.WithStack(err)
 	}
@@ -7,11 +7,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/stretchr/testify/mock"
 
 	enginemocks "github.com/projecteru2/core/engine/mocks"
+	enginetypes "github.com/projecteru2/core/engine/types"
 	lockmocks "github.com/projecteru2/core/lock/mocks"
 	resourcetypes "github.com/projecteru2/core/resources/types"
 	"github.com/projecteru2/core/scheduler"
@@ -28,13 +31,14 @@ func TestPodResource(t *testing.T) {
 	nodename := "testnode"
 	store := &storemocks.Store{}
 	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
 	lock := &lockmocks.DistributedLock{}
 	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
 	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
 	lock.On("Unlock", mock.Anything).Return(nil)
 	// failed by GetNodesByPod
 	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD).Once()
-	_, err := c.PodResource(ctx, podname)
+	_, err := c.PodResource(ctx, podname, false)
 	assert.Error(t, err)
 	node := &types.Node{
 		NodeMeta: types.NodeMeta{
@@ -48,12 +52,21 @@ func TestPodResource(t *testing.T) {
 	}
 	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{node}, nil)
 	store.On("GetNode", mock.Anything, mock.Anything).Return(node, nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		fmt.Errorf("%s", "not validate"),
+	)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node.Engine = engine
 	// failed by ListNodeWorkloads
 	store.On("ListNodeWorkloads", mock.Anything, mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD).Once()
-	_, err = c.PodResource(ctx, podname)
+	r, err := c.PodResource(ctx, podname, false)
 	assert.Error(t, err)
+	assert.Empty(t, r.NodesResource)
 	workloads := []*types.Workload{
 		{
+			ID: "w1",
 			ResourceMeta: types.ResourceMeta{
 				MemoryRequest:   1,
 				MemoryLimit:     1,
@@ -63,6 +76,7 @@ func TestPodResource(t *testing.T) {
 			},
 		},
 		{
+			ID: "w2",
 			ResourceMeta: types.ResourceMeta{
 				MemoryLimit:     2,
 				MemoryRequest:   2,
@@ -75,18 +89,77 @@ func TestPodResource(t *testing.T) {
 		},
 	}
 	store.On("ListNodeWorkloads", mock.Anything, mock.Anything, mock.Anything).Return(workloads, nil)
-	engine := &enginemocks.API{}
-	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
-		fmt.Errorf("%s", "not validate"),
-	)
-	node.Engine = engine
 	// success
-	r, err := c.PodResource(ctx, podname)
+	r, err = c.PodResource(ctx, podname, false)
 	assert.NoError(t, err)
 	assert.Equal(t, r.NodesResource[0].CPUPercent, 0.9)
 	assert.Equal(t, r.NodesResource[0].MemoryPercent, 0.5)
 	assert.Equal(t, r.NodesResource[0].StoragePercent, 0.1)
+	assert.Equal(t, r.NodesResource[0].CPUFragmentation, 1)
 	assert.NotEmpty(t, r.NodesResource[0].Diffs)
+	assert.Nil(t, r.NodesResource[0].WorkloadsResource)
+	assert.Equal(t, "docker", r.NodesResource[0].EngineType)
+	assert.Equal(t, "20.10.0", r.NodesResource[0].EngineVersion)
+	assert.Equal(t, map[string][]string{"docker 20.10.0": {nodename}}, r.EngineVersions)
+	assert.Equal(t, types.ResourceUsage{Total: 2, Used: 1.8, Percent: 0.9}, r.CPU)
+	assert.Equal(t, types.ResourceUsage{Total: 6, Used: 3, Percent: 0.5}, r.Memory)
+	assert.Equal(t, types.ResourceUsage{Total: 10, Used: 1, Percent: 0.1}, r.Storage)
+	assert.Equal(t, types.ResourceUsage{}, r.Volume)
+	// with workloads
+	r, err = c.PodResource(ctx, podname, true)
+	assert.NoError(t, err)
+	assert.Len(t, r.NodesResource[0].WorkloadsResource, 2)
+	assert.Equal(t, r.NodesResource[0].WorkloadsResource["w1"].CPUQuotaRequest, 1.3)
+	assert.Equal(t, r.NodesResource[0].WorkloadsResource["w2"].StorageRequest, int64(1))
+}
+
+func TestPodResourceConcurrently(t *testing.T) {
+	c := NewTestCluster()
+	c.config.MaxConcurrency = 2
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	// n4 is busy
+	busyLock := &lockmocks.DistributedLock{}
+	busyLock.On("Lock", mock.Anything).Return(nil, context.DeadlineExceeded)
+	store.On("CreateLock", mock.MatchedBy(func(name string) bool { return strings.HasSuffix(name, "n4") }), mock.Anything).Return(busyLock, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	nodes := []*types.Node{}
+	for _, name := range []string{"n3", "n1", "n2", "n4"} {
+		nodes = append(nodes, &types.Node{NodeMeta: types.NodeMeta{Name: name, MemCap: 1, InitMemCap: 1}})
+	}
+	// skipped ones don't fail the call
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{nodes[1], nodes[3]}, nil).Once()
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nodes, nil)
+	store.On("GetNode", mock.Anything, mock.Anything).Return(func(_ context.Context, nodename string) *types.Node {
+		return &types.Node{NodeMeta: types.NodeMeta{Name: nodename, MemCap: 1, InitMemCap: 1}, Engine: engine}
+	}, nil)
+	store.On("ListNodeWorkloads", mock.Anything, "n2", mock.Anything).Return(nil, types.ErrNoETCD)
+	store.On("ListNodeWorkloads", mock.Anything, mock.Anything, mock.Anything).Return([]*types.Workload{}, nil)
+	r, err := c.PodResource(ctx, "testpod", false)
+	assert.NoError(t, err)
+	assert.Len(t, r.NodesResource, 1)
+	assert.Equal(t, []string{"n4"}, r.Skipped)
+
+	// partial result
+	r, err = c.PodResource(ctx, "testpod", false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "n2")
+	assert.Len(t, r.NodesResource, 2)
+	assert.Equal(t, r.NodesResource[0].Name, "n1")
+	assert.Equal(t, r.NodesResource[1].Name, "n3")
+	assert.Equal(t, []string{"n4"}, r.Skipped)
+	assert.NotContains(t, err.Error(), "n4")
+	// reservations are listed once per call, not per node
+	store.AssertNumberOfCalls(t, "ListReservations", 2)
 }
 
 func TestNodeResource(t *testing.T) {
@@ -95,6 +168,7 @@ func TestNodeResource(t *testing.T) {
 	nodename := "testnode"
 	store := &storemocks.Store{}
 	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
 	lock := &lockmocks.DistributedLock{}
 	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
 	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
@@ -108,24 +182,28 @@ func TestNodeResource(t *testing.T) {
 			InitMemCap:     6,
 			NUMAMemory:     types.NUMAMemory{"0": 1, "1": 1},
 			InitNUMAMemory: types.NUMAMemory{"0": 3, "1": 3},
+			InitVolume:     types.VolumeMap{"/sda1": 200},
 		},
+		VolumeUsed: 100,
 	}
 	engine := &enginemocks.API{}
-	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
 		fmt.Errorf("%s", "not validate"),
 	)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
 	node.Engine = engine
 	// fail by validating
-	_, err := c.NodeResource(ctx, "", false)
+	_, err := c.NodeResource(ctx, "", &types.NodeResourceOptions{})
 	assert.Error(t, err)
 	// failed by GetNode
 	store.On("GetNode", ctx, nodename).Return(nil, types.ErrNoETCD).Once()
-	_, err = c.NodeResource(ctx, nodename, false)
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
 	assert.Error(t, err)
 	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
 	// failed by list node workloads
 	store.On("ListNodeWorkloads", mock.Anything, mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD).Once()
-	_, err = c.NodeResource(ctx, nodename, false)
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
 	assert.Error(t, err)
 	workloads := []*types.Workload{
 		{
@@ -135,6 +213,7 @@ func TestNodeResource(t *testing.T) {
 				CPU:             types.CPUMap{"0": 100, "1": 30},
 				CPUQuotaRequest: 1.3,
 				CPUQuotaLimit:   1.3,
+				NUMANode:        "0",
 			},
 		},
 		{
@@ -148,14 +227,535 @@ func TestNodeResource(t *testing.T) {
 		},
 	}
 	store.On("ListNodeWorkloads", mock.Anything, mock.Anything, mock.Anything).Return(workloads, nil)
-	store.On("UpdateNodes", mock.Anything, mock.Anything).Return(nil)
+	// dry run
+	nr, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{Fix: true, DryRun: true})
+	assert.NoError(t, err)
+	assert.NotNil(t, nr.FixPlan)
+	assert.Equal(t, nr.FixPlan.CPUUsed, 1.8)
+	assert.Equal(t, nr.FixPlan.CPU, types.CPUMap{"1": 10})
+	assert.Equal(t, nr.FixPlan.MemCap, int64(1))
+	assert.Equal(t, nr.FixPlan.VolumeUsed, int64(0))
+	assert.Equal(t, nr.FixPlan.NUMAMemory, types.NUMAMemory{"0": 1, "1": 2})
+	assert.Contains(t, strings.Join(nr.Diffs, ","), "numa node 0 memory used: 1, diff 1")
+	assert.Contains(t, strings.Join(nr.Diffs, ","), "volume used: 100, diff -100")
+	assert.Len(t, nr.StructuredDiffs, len(nr.Diffs))
+	assert.Contains(t, nr.StructuredDiffs, types.ResourceDiff{Dimension: "numa_memory:0", Expected: 2, Actual: 1, Delta: 1})
+	assert.Contains(t, nr.StructuredDiffs, types.ResourceDiff{Dimension: "volume", Expected: 0, Actual: 100, Delta: -100})
+	store.AssertNotCalled(t, "AddResourceFixRecord", mock.Anything, mock.Anything, mock.Anything)
+	var record *types.ResourceFixRecord
+	store.On("AddResourceFixRecord", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		record = args.Get(1).(*types.ResourceFixRecord)
+		assert.Equal(t, nodename, args.Get(2).(*types.Node).Name)
+	}).Return(nil)
 	// success but workload inspect failed
-	nr, err := c.NodeResource(ctx, nodename, true)
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{Fix: true})
 	assert.NoError(t, err)
+	assert.NotNil(t, record)
+	assert.Equal(t, nodename, record.Nodename)
+	assert.Equal(t, 1.8, record.NewCPUUsed)
+	assert.Equal(t, int64(1), record.MemCap)
 	assert.Equal(t, nr.Name, nodename)
+	// engine still disagrees after fixing
+	assert.Contains(t, nr.ResidualDiffs, "not validate")
 	assert.NotEmpty(t, nr.Diffs)
 	details := strings.Join(nr.Diffs, ",")
 	assert.Contains(t, details, "inspect failed")
+
+	// validate errors of each dimension
+	engine = &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		enginetypes.ResourceValidateErrors{{Resource: "cpu", Reason: "core 3 not exists"}, {Resource: "memory", Reason: "used 3 exceeds total 1"}},
+	)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node.Engine = engine
+	engineWithValidation := engine
+	c.config.ResourceWarnThreshold, c.config.ResourceCriticalThreshold = 0.8, 0.95
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
+	assert.NoError(t, err)
+	assert.True(t, nr.EngineReachable)
+	assert.True(t, nr.NearCapacity)
+	assert.False(t, nr.AtCapacity)
+	assert.False(t, nr.Overcommitted)
+	assert.Contains(t, nr.Diffs, "cpu: core 3 not exists")
+	assert.Contains(t, nr.Diffs, "memory: used 3 exceeds total 1")
+	assert.Contains(t, nr.StructuredDiffs, types.ResourceDiff{Dimension: "memory"})
+	assert.Empty(t, nr.EngineVersion)
+	engine.AssertNumberOfCalls(t, "Info", 1)
+
+	// engine without validation adds no diff
+	engine = &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "virt"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.WithStack(types.ErrEngineNotImplemented))
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node.Engine = engine
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, nr.StructuredDiffs, types.ResourceDiff{Dimension: "engine"})
+	node.Engine = engineWithValidation
+
+	// skip inspect
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true})
+	assert.NoError(t, err)
+	assert.NotContains(t, strings.Join(nr.Diffs, ","), "inspect failed")
+
+	// stuck workload is bounded by inspect timeout
+	c.config.InspectTimeout = 100 * time.Millisecond
+	workloadEngine := &enginemocks.API{}
+	workloadEngine.On("VirtualizationInspect", mock.Anything, "stuck").Return(
+		func(ctx context.Context, _ string) *enginetypes.VirtualizationInfo {
+			<-ctx.Done()
+			return nil
+		},
+		func(ctx context.Context, _ string) error { return ctx.Err() },
+	)
+	workloadEngine.On("VirtualizationInspect", mock.Anything, "ok").Return(&enginetypes.VirtualizationInfo{}, nil)
+	workloads[0].ID, workloads[0].Engine = "stuck", workloadEngine
+	workloads[1].ID, workloads[1].Engine = "ok", workloadEngine
+	start := time.Now()
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
+	assert.NoError(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	details = strings.Join(nr.Diffs, ",")
+	assert.Contains(t, details, "workload stuck inspect failed")
+	assert.Contains(t, nr.StructuredDiffs, types.ResourceDiff{Dimension: "workload", WorkloadID: "stuck"})
+	assert.NotContains(t, details, "workload ok inspect failed")
+}
+
+func TestNodeResourceCache(t *testing.T) {
+	c := NewTestCluster()
+	c.nodeResources = cache.New(time.Minute, time.Minute)
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{Name: nodename, InitCPU: types.CPUMap{"0": 100}, InitMemCap: 6, MemCap: 6},
+		Engine:   engine,
+	}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	workloadEngine := &enginemocks.API{}
+	workloadEngine.On("VirtualizationInspect", mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD)
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return([]*types.Workload{{ID: "w1", Engine: workloadEngine}}, nil)
+
+	nr, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, nr.Diffs, 1)
+	store.AssertNumberOfCalls(t, "ListNodeWorkloads", 1)
+
+	// cached, inspect diffs are not kept in cache
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, nr.Diffs, 1)
+	store.AssertNumberOfCalls(t, "ListNodeWorkloads", 1)
+
+	// refresh
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true, Refresh: true})
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "ListNodeWorkloads", 2)
+
+	// invalidated by changes on node
+	c.doInvalidateNodeResource(nodename)
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true})
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "ListNodeWorkloads", 3)
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true})
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "ListNodeWorkloads", 3)
+
+	// invalidated by capacity edits only
+	store.On("UpdateNodes", mock.Anything, mock.Anything).Return(nil)
+	_, err = c.SetNode(ctx, &types.SetNodeOptions{Nodename: nodename, Labels: map[string]string{"a": "1"}})
+	assert.NoError(t, err)
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true})
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "ListNodeWorkloads", 3)
+	_, err = c.SetNode(ctx, &types.SetNodeOptions{Nodename: nodename, DeltaMemory: 1})
+	assert.NoError(t, err)
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true})
+	assert.NoError(t, err)
+	store.AssertNumberOfCalls(t, "ListNodeWorkloads", 4)
+}
+
+func TestRefreshNodeResource(t *testing.T) {
+	c := NewTestCluster()
+	c.nodeResources = cache.New(time.Minute, time.Minute)
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	// numa topology and memory edited without init ones
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{
+			Name:           nodename,
+			CPU:            types.CPUMap{"0": 50, "1": 100},
+			InitCPU:        types.CPUMap{"0": 100},
+			MemCap:         8,
+			InitMemCap:     6,
+			NUMA:           types.NUMA{"0": "0", "1": "1"},
+			NUMAMemory:     types.NUMAMemory{"0": 3, "1": 5},
+			InitNUMAMemory: types.NUMAMemory{"0": 6},
+			Volume:         types.VolumeMap{"/data": 10},
+			InitVolume:     types.VolumeMap{"/data": 20},
+		},
+		VolumeUsed: 10,
+		Engine:     engine,
+	}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	workloads := []*types.Workload{{ID: "w1", ResourceMeta: types.ResourceMeta{
+		CPU:               types.CPUMap{"0": 50},
+		MemoryRequest:     2,
+		NUMANode:          "0",
+		VolumePlanRequest: types.VolumePlan{types.MustToVolumeBinding("AUTO:/data:rw:10"): types.VolumeMap{"/data": 10}},
+	}}}
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return(workloads, nil)
+
+	_, err := c.RefreshNodeResource(ctx, "")
+	assert.True(t, errors.Is(err, types.ErrEmptyNodeName))
+	store.On("UpdateNodes", mock.Anything, mock.Anything).Return(types.ErrNoETCD).Once()
+	_, err = c.RefreshNodeResource(ctx, nodename)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+
+	store.On("UpdateNodes", mock.Anything, mock.Anything).Return(nil)
+	nr, err := c.RefreshNodeResource(ctx, nodename)
+	assert.NoError(t, err)
+	assert.Empty(t, nr.Diffs)
+	assert.Equal(t, types.CPUMap{"0": 100, "1": 100}, node.InitCPU)
+	assert.EqualValues(t, 10, node.InitMemCap)
+	assert.Equal(t, types.NUMAMemory{"0": 5, "1": 5}, node.InitNUMAMemory)
+	assert.Equal(t, types.VolumeMap{"/data": 20}, node.InitVolume)
+}
+
+func TestNodeResourceEngineUnreachable(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(nil, types.ErrNoETCD)
+	node := &types.Node{NodeMeta: types.NodeMeta{Name: nodename}, Engine: engine}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+
+	_, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{Fix: true, Refresh: true})
+	assert.True(t, errors.Is(err, types.ErrEngineUnreachable))
+	nr, err := c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{Fix: true}, nil)
+	assert.True(t, errors.Is(err, types.ErrEngineUnreachable))
+	assert.False(t, nr.EngineReachable)
+	assert.Empty(t, nr.Diffs)
+	store.AssertNotCalled(t, "ListNodeWorkloads", mock.Anything, mock.Anything, mock.Anything)
+	store.AssertNotCalled(t, "UpdateNodes", mock.Anything, mock.Anything)
+}
+
+func TestNodeResourceLockFree(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	// lock is stuck
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(nil, context.DeadlineExceeded)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node := &types.Node{NodeMeta: types.NodeMeta{Name: nodename, CPU: types.CPUMap{"0": 100}, InitCPU: types.CPUMap{"0": 100}, MemCap: 1, InitMemCap: 2}, Engine: engine}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return([]*types.Workload{}, nil)
+
+	_, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true})
+	assert.Error(t, err)
+
+	// never for fixing
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{Fix: true, SkipInspect: true, Force: true})
+	assert.True(t, errors.Is(err, types.ErrForceFix))
+	_, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{DryRun: true, SkipInspect: true, Force: true})
+	assert.True(t, errors.Is(err, types.ErrForceFix))
+
+	nr, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true, Force: true})
+	assert.NoError(t, err)
+	assert.True(t, nr.LockFree)
+	assert.NotEmpty(t, nr.Diffs)
+	assert.Nil(t, c.doGetCachedNodeResource(nodename))
+	store.AssertNotCalled(t, "UpdateNodes", mock.Anything, mock.Anything)
+}
+
+func TestNodeResourceMemoryOvercommit(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{Name: nodename, InitCPU: types.CPUMap{"0": 100}, CPU: types.CPUMap{"0": 100}, InitMemCap: 4, MemCap: 1},
+		Engine:   engine,
+	}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	workloads := []*types.Workload{
+		{ID: "w1", ResourceMeta: types.ResourceMeta{MemoryRequest: 1, MemoryLimit: 4}},
+		{ID: "w2", ResourceMeta: types.ResourceMeta{MemoryRequest: 2, MemoryLimit: 2}},
+	}
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return(workloads, nil)
+
+	nr, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true, Refresh: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.75, nr.MemoryPercent)
+	assert.Equal(t, 1.5, nr.MemoryOvercommit)
+	assert.False(t, nr.Overcommitted)
+	assert.Empty(t, nr.Diffs)
+	assert.Equal(t, []string{"memory limits 6 over init memory 4, overcommit 1.50"}, nr.Advisories)
+
+	workloads[0].MemoryLimit = 1
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true, Refresh: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.75, nr.MemoryOvercommit)
+	assert.Empty(t, nr.Advisories)
+}
+
+func TestNodeResourceMisInitialized(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{Name: nodename, InitNUMAMemory: types.NUMAMemory{"0": 0}, NUMAMemory: types.NUMAMemory{"0": 0}},
+		Engine:   engine,
+	}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return([]*types.Workload{}, nil)
+
+	nr, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true, Refresh: true})
+	assert.NoError(t, err)
+	assert.Zero(t, nr.CPUPercent)
+	assert.Zero(t, nr.MemoryPercent)
+	assert.Zero(t, nr.VolumePercent)
+	assert.Empty(t, nr.NUMAMemoryPercent)
+	assert.Equal(t, []string{"node mis-initialized: init cpu is empty", "node mis-initialized: init memory is 0"}, nr.Diffs)
+
+	node.VolumeUsed = 10
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{SkipInspect: true, Refresh: true})
+	assert.NoError(t, err)
+	assert.Zero(t, nr.VolumePercent)
+	assert.Contains(t, nr.Diffs, "node mis-initialized: init volume is 0, used 10")
+}
+
+func TestCPUConflicts(t *testing.T) {
+	workloads := []*types.Workload{
+		{ID: "w1", ResourceMeta: types.ResourceMeta{CPU: types.CPUMap{"0": 100, "1": 50}}},
+		{ID: "w2", ResourceMeta: types.ResourceMeta{CPU: types.CPUMap{"0": 100}}},
+		{ID: "w3", ResourceMeta: types.ResourceMeta{CPU: types.CPUMap{"1": 50, "2": 30}}},
+	}
+	cpumap := types.CPUMap{}
+	for _, workload := range workloads {
+		cpumap.Add(workload.CPU)
+	}
+	nr := &types.NodeResource{}
+	addCPUConflicts(nr, workloads, cpumap, 100)
+	assert.Equal(t, []string{"cpu 0 conflict: 200 shares over 100 claimed by w1,w2"}, nr.Diffs)
+	assert.Equal(t, []types.ResourceDiff{{Dimension: "cpu:0", Expected: 100, Actual: 200, Delta: -100}}, nr.StructuredDiffs)
+	nr = &types.NodeResource{}
+	addCPUConflicts(nr, workloads, cpumap, 0)
+	addCPUConflicts(nr, nil, types.CPUMap{}, 100)
+	assert.Empty(t, nr.Diffs)
+	assert.Empty(t, nr.StructuredDiffs)
+}
+
+func TestNodeResourceCPUDrift(t *testing.T) {
+	c := NewTestCluster()
+	c.config.CPUDriftRatio = 0.5
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	store.On("GetNode", mock.Anything, nodename).Return(&types.Node{
+		NodeMeta: types.NodeMeta{Name: nodename, InitCPU: types.CPUMap{"0": 100}, InitMemCap: 6, MemCap: 6},
+		Engine:   engine,
+	}, nil)
+	workloadEngine := &enginemocks.API{}
+	workloadEngine.On("Capabilities").Return(enginetypes.Capabilities{enginetypes.CapVirtualizationStats: true})
+	workloadEngine.On("VirtualizationInspect", mock.Anything, mock.Anything).Return(&enginetypes.VirtualizationInfo{}, nil)
+	workloadEngine.On("VirtualizationStats", mock.Anything, "over").Return(&enginetypes.VirtualizationStats{CPUUsage: 1.6}, nil)
+	workloadEngine.On("VirtualizationStats", mock.Anything, "under").Return(&enginetypes.VirtualizationStats{CPUUsage: 0.4}, nil)
+	workloadEngine.On("VirtualizationStats", mock.Anything, "fit").Return(&enginetypes.VirtualizationStats{CPUUsage: 1.2}, nil)
+	workloadEngine.On("VirtualizationStats", mock.Anything, "failed").Return(nil, types.ErrNoETCD)
+	workloads := []*types.Workload{}
+	for _, ID := range []string{"over", "under", "fit", "failed", "unlimited"} {
+		workload := &types.Workload{ID: ID, Engine: workloadEngine, ResourceMeta: types.ResourceMeta{CPUQuotaRequest: 1}}
+		workloads = append(workloads, workload)
+	}
+	workloads[4].CPUQuotaRequest = 0
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return(workloads, nil)
+
+	nr, err := c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, nr.Advisories, 2)
+	assert.Contains(t, nr.Advisories[0], "workload over cpu usage 1.600000 over request")
+	assert.Contains(t, nr.Advisories[1], "workload under cpu usage 0.400000 under request")
+	assert.NotContains(t, strings.Join(nr.Diffs, ","), "inspect failed")
+	workloadEngine.AssertNotCalled(t, "VirtualizationStats", mock.Anything, "unlimited")
+
+	// disabled
+	c.config.CPUDriftRatio = 0
+	nr, err = c.NodeResource(ctx, nodename, &types.NodeResourceOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, nr.Advisories)
+	workloadEngine.AssertNumberOfCalls(t, "VirtualizationStats", 4)
+}
+
+func TestFixPodResource(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker", Version: "20.10.0"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, types.ErrNoETCD).Once()
+	_, err := c.FixPodResource(ctx, "testpod", false)
+	assert.Error(t, err)
+
+	newNode := func(nodename string) *types.Node {
+		node := &types.Node{NodeMeta: types.NodeMeta{Name: nodename, CPU: types.CPUMap{"0": 100}, InitCPU: types.CPUMap{"0": 100}, MemCap: 1, InitMemCap: 1}, Engine: engine}
+		if nodename == "n3" {
+			node.InitMemCap = 2
+		}
+		return node
+	}
+	store.On("GetNodesByPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*types.Node{newNode("n3"), newNode("n1"), newNode("n2")}, nil)
+	store.On("GetNode", mock.Anything, mock.Anything).Return(func(_ context.Context, nodename string) *types.Node { return newNode(nodename) }, nil)
+	store.On("ListNodeWorkloads", mock.Anything, "n2", mock.Anything).Return(nil, types.ErrNoETCD)
+	store.On("ListNodeWorkloads", mock.Anything, mock.Anything, mock.Anything).Return([]*types.Workload{}, nil)
+
+	// dry run
+	r, err := c.FixPodResource(ctx, "testpod", true)
+	assert.NoError(t, err)
+	assert.Len(t, r.Nodes, 3)
+	assert.Equal(t, "n1", r.Nodes[0].Nodename)
+	assert.Empty(t, r.Nodes[0].Diffs)
+	assert.Error(t, r.Nodes[1].Error)
+	assert.Equal(t, int64(1), r.Nodes[2].Plan.MemCap)
+	store.AssertNotCalled(t, "AddResourceFixRecord", mock.Anything, mock.Anything, mock.Anything)
+
+	// only nodes with diffs are fixed
+	store.On("AddResourceFixRecord", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	store.On("AddResourceFixRecord", mock.Anything, mock.Anything, mock.Anything).Return(types.ErrNoETCD)
+	r, err = c.FixPodResource(ctx, "testpod", false)
+	assert.NoError(t, err)
+	assert.Nil(t, r.Nodes[0].Plan)
+	assert.Error(t, r.Nodes[1].Error)
+	assert.Equal(t, int64(1), r.Nodes[2].Plan.MemCap)
+	assert.NoError(t, r.Nodes[2].Error)
+	store.AssertNumberOfCalls(t, "AddResourceFixRecord", 1)
+
+	// fix failure is reported
+	r, err = c.FixPodResource(ctx, "testpod", false)
+	assert.NoError(t, err)
+	assert.Error(t, r.Nodes[2].Error)
+	store.AssertNumberOfCalls(t, "AddResourceFixRecord", 2)
+	store.AssertNotCalled(t, "UpdateNodes", mock.Anything, mock.Anything)
+}
+
+func TestFixDiffResource(t *testing.T) {
+	c := NewTestCluster()
+	store := &storemocks.Store{}
+	c.store = store
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node := &types.Node{NodeMeta: types.NodeMeta{Name: "n1", CPU: types.CPUMap{}, MemCap: 1, InitMemCap: 2}}
+	plan := &types.ResourceFixPlan{Nodename: "n1", CPU: types.CPUMap{}, MemCap: 1}
+
+	// failed by read, nothing written
+	store.On("GetNode", mock.Anything, "n1").Return(nil, types.ErrNoETCD).Once()
+	err := c.doFixDiffResource(ctx, plan)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	assert.Contains(t, err.Error(), "get node n1")
+	store.AssertNotCalled(t, "AddResourceFixRecord", mock.Anything, mock.Anything, mock.Anything)
+
+	// caller gives up after read, write is canceled with it
+	store.On("GetNode", mock.Anything, "n1").Run(func(_ mock.Arguments) { cancel() }).Return(node, nil).Once()
+	store.On("AddResourceFixRecord", mock.Anything, mock.Anything, mock.Anything).Return(func(ctx context.Context, _ *types.ResourceFixRecord, _ *types.Node) error {
+		return ctx.Err()
+	})
+	assert.True(t, errors.Is(c.doFixDiffResource(ctx, plan), context.Canceled))
+
+	store.On("GetNode", mock.Anything, "n1").Return(node, nil)
+	assert.NoError(t, c.doFixDiffResource(context.Background(), plan))
+	store.AssertNotCalled(t, "UpdateNodes", mock.Anything, mock.Anything)
+}
+
+func TestListResourceFixes(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	store := &storemocks.Store{}
+	c.store = store
+
+	_, err := c.ListResourceFixes(ctx, "")
+	assert.Error(t, err)
+	store.On("ListResourceFixRecords", mock.Anything, "n1").Return([]*types.ResourceFixRecord{{Nodename: "n1"}}, nil)
+	records, err := c.ListResourceFixes(ctx, "n1")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
 }
 
 func TestAllocResource(t *testing.T) {
@@ -279,3 +879,209 @@ func testAllocFailedAsCommonDivisionError(t *testing.T, c *Calcium, opts *types.
 	_, _, err := c.doAllocResource(context.Background(), nodeMap, opts)
 	assert.Error(t, err)
 }
+
+func TestNodeResourceStoragePools(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	// total is right, pool ssd lost 40
+	node := &types.Node{
+		NodeMeta: types.NodeMeta{
+			Name:             nodename,
+			CPU:              types.CPUMap{"0": 100},
+			InitCPU:          types.CPUMap{"0": 100},
+			MemCap:           2,
+			InitMemCap:       2,
+			StorageCap:       100,
+			InitStorageCap:   200,
+			StoragePools:     types.StoragePools{"ssd": 10, "hdd": 50},
+			InitStoragePools: types.StoragePools{"ssd": 150, "hdd": 50},
+		},
+		Engine: engine,
+	}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	workloads := []*types.Workload{{ID: "w1", ResourceMeta: types.ResourceMeta{StorageRequest: 100, StoragePool: "ssd"}}}
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return(workloads, nil)
+	store.On("AddResourceFixRecord", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	nr, err := c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{DryRun: true}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, nr.Diffs, 1)
+	assert.Equal(t, "storage:ssd", nr.StructuredDiffs[0].Dimension)
+	assert.EqualValues(t, 50, nr.StructuredDiffs[0].Expected)
+	assert.EqualValues(t, 10, nr.StructuredDiffs[0].Actual)
+	assert.Equal(t, map[string]float64{"ssd": float64(100) / 150, "hdd": 0}, nr.StoragePoolPercent)
+	assert.Equal(t, types.StoragePools{"ssd": 40}, nr.FixPlan.StoragePools)
+
+	nr, err = c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{Fix: true}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, nr.ResidualDiffs)
+	assert.Equal(t, types.StoragePools{"ssd": 50, "hdd": 50}, node.StoragePools)
+	assert.EqualValues(t, 100, node.StorageCap)
+}
+
+func TestNodeResourceMetrics(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node := &types.Node{NodeMeta: types.NodeMeta{Name: nodename, Podname: "testpod", CPU: types.CPUMap{"0": 100}, InitCPU: types.CPUMap{"0": 100}, MemCap: 1, InitMemCap: 2}, Engine: engine}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return([]*types.Workload{}, nil)
+
+	_, err := c.NodeResourceMetrics(ctx, "")
+	assert.Error(t, err)
+	text, err := c.NodeResourceMetrics(ctx, nodename)
+	assert.NoError(t, err)
+	assert.Contains(t, text, `node_resource_diffs{podname="testpod",nodename="testnode"} 1`)
+	store.AssertNotCalled(t, "UpdateNodes", mock.Anything, mock.Anything)
+}
+
+func TestNodeResourceCPUUsedEpsilon(t *testing.T) {
+	c := NewTestCluster()
+	c.config.CPUUsedEpsilon = 0.001
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node := &types.Node{NodeMeta: types.NodeMeta{Name: nodename, CPU: types.CPUMap{"0": 100}, InitCPU: types.CPUMap{"0": 100}, MemCap: 2, InitMemCap: 2}, Engine: engine}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	workloads := []*types.Workload{
+		{ID: "w1", ResourceMeta: types.ResourceMeta{CPUQuotaRequest: 0.1}},
+		{ID: "w2", ResourceMeta: types.ResourceMeta{CPUQuotaRequest: 0.2}},
+	}
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return(workloads, nil)
+
+	// noise is tolerated
+	node.CPUUsed = 0.3005
+	nr, err := c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, nr.Diffs)
+	// real one is not
+	node.CPUUsed = 0.31
+	nr, err = c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, nr.StructuredDiffs, 1)
+	assert.Equal(t, "cpu", nr.StructuredDiffs[0].Dimension)
+	// exact without epsilon
+	c.config.CPUUsedEpsilon = 0
+	node.CPUUsed = 0.3005
+	nr, err = c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, nr.StructuredDiffs, 1)
+}
+
+func TestNodeResourceWorkloadDrifts(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return([]string{"w2", "w3"}, nil).Once()
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrNoETCD).Once()
+	node := &types.Node{NodeMeta: types.NodeMeta{Name: nodename, CPU: types.CPUMap{"0": 100}, InitCPU: types.CPUMap{"0": 100}, MemCap: 2, InitMemCap: 2}, Engine: engine}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	workloads := []*types.Workload{{ID: "w1"}, {ID: "w2"}}
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return(workloads, nil)
+
+	nr, err := c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.ResourceDiff{{Dimension: "workload", WorkloadID: "w1"}, {Dimension: "workload", WorkloadID: "w3"}}, nr.StructuredDiffs)
+	assert.Contains(t, nr.Diffs, "ghost workload w1 not on engine")
+	assert.Contains(t, nr.Diffs, "orphan workload w3 not in store")
+
+	// listing failure is not drift
+	nr, err = c.doGetNodeResource(ctx, nodename, &types.NodeResourceOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, nr.Diffs)
+}
+
+func TestSnapshotAndDiffNodeResource(t *testing.T) {
+	c := NewTestCluster()
+	ctx := context.Background()
+	nodename := "testnode"
+	store := &storemocks.Store{}
+	c.store = store
+	store.On("ListReservations", mock.Anything).Return([]*types.Reservation{}, nil)
+	lock := &lockmocks.DistributedLock{}
+	store.On("CreateLock", mock.Anything, mock.Anything).Return(lock, nil)
+	lock.On("Lock", mock.Anything).Return(context.TODO(), nil)
+	lock.On("Unlock", mock.Anything).Return(nil)
+	engine := &enginemocks.API{}
+	engine.On("Info", mock.Anything).Return(&enginetypes.Info{Type: "docker"}, nil)
+	engine.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine.On("ListWorkloadIDs", mock.Anything).Return(nil, types.ErrEngineNotImplemented)
+	node := &types.Node{NodeMeta: types.NodeMeta{Name: nodename, CPU: types.CPUMap{"0": 100}, InitCPU: types.CPUMap{"0": 100}, MemCap: 2, InitMemCap: 2}, Engine: engine}
+	store.On("GetNode", mock.Anything, nodename).Return(node, nil)
+	workloads := []*types.Workload{{ID: "w1"}}
+	store.On("ListNodeWorkloads", mock.Anything, nodename, mock.Anything).Return(func(context.Context, string, map[string]string) []*types.Workload { return workloads }, nil)
+
+	_, err := c.SnapshotNodeResource(ctx, "")
+	assert.Error(t, err)
+	store.On("AddNodeResourceSnapshot", mock.Anything, mock.Anything).Return(types.ErrNoETCD).Once()
+	_, err = c.SnapshotNodeResource(ctx, nodename)
+	assert.True(t, errors.Is(err, types.ErrNoETCD))
+	var saved *types.NodeResourceSnapshot
+	store.On("AddNodeResourceSnapshot", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*types.NodeResourceSnapshot)
+	}).Return(nil)
+	snapshot, err := c.SnapshotNodeResource(ctx, nodename)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, snapshot)
+	assert.Equal(t, []string{"w1"}, snapshot.WorkloadIDs)
+
+	_, err = c.DiffNodeResource(ctx, "", snapshot.ID)
+	assert.Error(t, err)
+	store.On("GetNodeResourceSnapshot", mock.Anything, nodename, "missing").Return(nil, types.ErrSnapshotNotExists)
+	_, err = c.DiffNodeResource(ctx, nodename, "missing")
+	assert.True(t, errors.Is(err, types.ErrSnapshotNotExists))
+
+	// w1 replaced by w2 taking 1 memory
+	workloads = []*types.Workload{{ID: "w2", ResourceMeta: types.ResourceMeta{MemoryRequest: 1}}}
+	node.MemCap = 1
+	store.On("GetNodeResourceSnapshot", mock.Anything, nodename, snapshot.ID).Return(snapshot, nil)
+	delta, err := c.DiffNodeResource(ctx, nodename, snapshot.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"w2"}, delta.AddedWorkloads)
+	assert.Equal(t, []string{"w1"}, delta.RemovedWorkloads)
+	assert.Equal(t, 0.5, delta.MemoryPercent)
+	assert.Empty(t, delta.NewDiffs)
+}
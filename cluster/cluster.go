@@ -35,28 +35,51 @@ type Cluster interface {
 	// meta service
 	WatchServiceStatus(context.Context) (<-chan types.ServiceStatus, error)
 	// meta networks
-	ListNetworks(ctx context.Context, podname string, driver string) ([]*enginetypes.Network, error)
+	ListNetworks(ctx context.Context, opts *types.ListNetworksOptions) ([]*enginetypes.Network, bool, error)
+	InspectNetwork(ctx context.Context, podname string, network string) (*enginetypes.Network, error)
+	NetworkUsage(ctx context.Context, podname string, driver string) ([]*types.NetworkUsage, error)
+	NetworkWorkloads(ctx context.Context, podname string, network string) (*types.NetworkWorkloads, error)
+	CreateNetwork(ctx context.Context, podname string, opts *enginetypes.NetworkCreateOptions) (*enginetypes.Network, error)
+	RemoveNetwork(ctx context.Context, podname string, network string) error
 	ConnectNetwork(ctx context.Context, network, target, ipv4, ipv6 string) ([]string, error)
-	DisconnectNetwork(ctx context.Context, network, target string, force bool) error
+	ConnectNetworks(ctx context.Context, target string, attachments []*types.NetworkAttachment) ([]string, error)
+	DisconnectNetwork(ctx context.Context, network, target string, force bool, graceSeconds int) error
+	DisconnectAllNetworks(ctx context.Context, target string, force bool) ([]string, error)
 	// meta pod
 	AddPod(ctx context.Context, podname, desc string) (*types.Pod, error)
 	RemovePod(ctx context.Context, podname string) error
 	GetPod(ctx context.Context, podname string) (*types.Pod, error)
+	SetPodDefaultStrategy(ctx context.Context, podname, deployStrategy string) (*types.Pod, error)
 	ListPods(ctx context.Context) ([]*types.Pod, error)
 	// pod resource
-	PodResource(ctx context.Context, podname string) (*types.PodResource, error)
+	PodResource(ctx context.Context, podname string, withWorkloads bool) (*types.PodResource, error)
+	FixPodResource(ctx context.Context, podname string, dryRun bool) (*types.PodResourceFix, error)
+	PlanDrain(ctx context.Context, nodename string) (*types.DrainPlan, error)
+	PreviewDeploy(ctx context.Context, opts *types.DeployOptions) (map[string]int, error)
+	ReserveCapacity(ctx context.Context, opts *types.ReserveOptions) (*types.Reservation, error)
+	ReleaseReservation(ctx context.Context, token string) error
+	ReclaimReservations(ctx context.Context) error
 	// meta node
 	AddNode(context.Context, *types.AddNodeOptions) (*types.Node, error)
 	RemoveNode(ctx context.Context, nodename string) error
+	CordonNode(ctx context.Context, nodename string) error
+	UncordonNode(ctx context.Context, nodename string) error
 	ListPodNodes(ctx context.Context, podname string, labels map[string]string, all bool) ([]*types.Node, error)
 	GetNode(ctx context.Context, nodename string) (*types.Node, error)
+	NodeCapabilities(ctx context.Context, nodename string) (enginetypes.Capabilities, error)
 	SetNode(ctx context.Context, opts *types.SetNodeOptions) (*types.Node, error)
 	SetNodeStatus(ctx context.Context, nodename string, ttl int64) error
 	NodeStatusStream(ctx context.Context) chan *types.NodeStatus
 	// node resource
-	NodeResource(ctx context.Context, nodename string, fix bool) (*types.NodeResource, error)
+	NodeResource(ctx context.Context, nodename string, opts *types.NodeResourceOptions) (*types.NodeResource, error)
+	NodeResourceMetrics(ctx context.Context, nodename string) (string, error)
+	RefreshNodeResource(ctx context.Context, nodename string) (*types.NodeResource, error)
+	ListResourceFixes(ctx context.Context, nodename string) ([]*types.ResourceFixRecord, error)
+	SnapshotNodeResource(ctx context.Context, nodename string) (*types.NodeResourceSnapshot, error)
+	DiffNodeResource(ctx context.Context, nodename, snapshotID string) (*types.NodeResourceDelta, error)
 	// calculate capacity
 	CalculateCapacity(context.Context, *types.DeployOptions) (*types.CapacityMessage, error)
+	CalculateBatchCapacity(ctx context.Context, batch []*types.DeployOptions) (*types.BatchCapacity, error)
 	// meta workloads
 	GetWorkload(ctx context.Context, id string) (*types.Workload, error)
 	GetWorkloads(ctx context.Context, ids []string) ([]*types.Workload, error)
@@ -78,6 +101,7 @@ type Cluster interface {
 	RemoveWorkload(ctx context.Context, ids []string, force bool, step int) (chan *types.RemoveWorkloadMessage, error)
 	DissociateWorkload(ctx context.Context, ids []string) (chan *types.DissociateWorkloadMessage, error)
 	ControlWorkload(ctx context.Context, ids []string, t string, force bool) (chan *types.ControlWorkloadMessage, error)
+	UpdateWorkloadRestartPolicy(ctx context.Context, id, restartPolicy string) error
 	ExecuteWorkload(ctx context.Context, opts *types.ExecuteWorkloadOptions, inCh <-chan []byte) chan *types.AttachWorkloadMessage
 	ReallocResource(ctx context.Context, opts *types.ReallocOptions) error
 	LogStream(ctx context.Context, opts *types.LogStreamOptions) (chan *types.LogStreamMessage, error)
@@ -108,6 +108,29 @@ func (_m *Cluster) CacheImage(ctx context.Context, opts *types.ImageOptions) (ch
 	return r0, r1
 }
 
+// CalculateBatchCapacity provides a mock function with given fields: ctx, batch
+func (_m *Cluster) CalculateBatchCapacity(ctx context.Context, batch []*types.DeployOptions) (*types.BatchCapacity, error) {
+	ret := _m.Called(ctx, batch)
+
+	var r0 *types.BatchCapacity
+	if rf, ok := ret.Get(0).(func(context.Context, []*types.DeployOptions) *types.BatchCapacity); ok {
+		r0 = rf(ctx, batch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.BatchCapacity)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*types.DeployOptions) error); ok {
+		r1 = rf(ctx, batch)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CalculateCapacity provides a mock function with given fields: _a0, _a1
 func (_m *Cluster) CalculateCapacity(_a0 context.Context, _a1 *types.DeployOptions) (*types.CapacityMessage, error) {
 	ret := _m.Called(_a0, _a1)
@@ -154,6 +177,29 @@ func (_m *Cluster) ConnectNetwork(ctx context.Context, network string, target st
 	return r0, r1
 }
 
+// ConnectNetworks provides a mock function with given fields: ctx, target, attachments
+func (_m *Cluster) ConnectNetworks(ctx context.Context, target string, attachments []*types.NetworkAttachment) ([]string, error) {
+	ret := _m.Called(ctx, target, attachments)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, []*types.NetworkAttachment) []string); ok {
+		r0 = rf(ctx, target, attachments)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, []*types.NetworkAttachment) error); ok {
+		r1 = rf(ctx, target, attachments)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ControlWorkload provides a mock function with given fields: ctx, ids, t, force
 func (_m *Cluster) ControlWorkload(ctx context.Context, ids []string, t string, force bool) (chan *types.ControlWorkloadMessage, error) {
 	ret := _m.Called(ctx, ids, t, force)
@@ -200,6 +246,43 @@ func (_m *Cluster) Copy(ctx context.Context, opts *types.CopyOptions) (chan *typ
 	return r0, r1
 }
 
+// CordonNode provides a mock function with given fields: ctx, nodename
+func (_m *Cluster) CordonNode(ctx context.Context, nodename string) error {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateNetwork provides a mock function with given fields: ctx, podname, opts
+func (_m *Cluster) CreateNetwork(ctx context.Context, podname string, opts *enginetypes.NetworkCreateOptions) (*enginetypes.Network, error) {
+	ret := _m.Called(ctx, podname, opts)
+
+	var r0 *enginetypes.Network
+	if rf, ok := ret.Get(0).(func(context.Context, string, *enginetypes.NetworkCreateOptions) *enginetypes.Network); ok {
+		r0 = rf(ctx, podname, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*enginetypes.Network)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *enginetypes.NetworkCreateOptions) error); ok {
+		r1 = rf(ctx, podname, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateWorkload provides a mock function with given fields: ctx, opts
 func (_m *Cluster) CreateWorkload(ctx context.Context, opts *types.DeployOptions) (chan *types.CreateWorkloadMessage, error) {
 	ret := _m.Called(ctx, opts)
@@ -223,13 +306,59 @@ func (_m *Cluster) CreateWorkload(ctx context.Context, opts *types.DeployOptions
 	return r0, r1
 }
 
-// DisconnectNetwork provides a mock function with given fields: ctx, network, target, force
-func (_m *Cluster) DisconnectNetwork(ctx context.Context, network string, target string, force bool) error {
-	ret := _m.Called(ctx, network, target, force)
+// DiffNodeResource provides a mock function with given fields: ctx, nodename, snapshotID
+func (_m *Cluster) DiffNodeResource(ctx context.Context, nodename string, snapshotID string) (*types.NodeResourceDelta, error) {
+	ret := _m.Called(ctx, nodename, snapshotID)
+
+	var r0 *types.NodeResourceDelta
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *types.NodeResourceDelta); ok {
+		r0 = rf(ctx, nodename, snapshotID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.NodeResourceDelta)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, nodename, snapshotID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DisconnectAllNetworks provides a mock function with given fields: ctx, target, force
+func (_m *Cluster) DisconnectAllNetworks(ctx context.Context, target string, force bool) ([]string, error) {
+	ret := _m.Called(ctx, target, force)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) []string); ok {
+		r0 = rf(ctx, target, force)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, target, force)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DisconnectNetwork provides a mock function with given fields: ctx, network, target, force, graceSeconds
+func (_m *Cluster) DisconnectNetwork(ctx context.Context, network string, target string, force bool, graceSeconds int) error {
+	ret := _m.Called(ctx, network, target, force, graceSeconds)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) error); ok {
-		r0 = rf(ctx, network, target, force)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool, int) error); ok {
+		r0 = rf(ctx, network, target, force, graceSeconds)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -281,6 +410,29 @@ func (_m *Cluster) Finalizer() {
 	_m.Called()
 }
 
+// FixPodResource provides a mock function with given fields: ctx, podname, dryRun
+func (_m *Cluster) FixPodResource(ctx context.Context, podname string, dryRun bool) (*types.PodResourceFix, error) {
+	ret := _m.Called(ctx, podname, dryRun)
+
+	var r0 *types.PodResourceFix
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) *types.PodResourceFix); ok {
+		r0 = rf(ctx, podname, dryRun)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.PodResourceFix)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, podname, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetNode provides a mock function with given fields: ctx, nodename
 func (_m *Cluster) GetNode(ctx context.Context, nodename string) (*types.Node, error) {
 	ret := _m.Called(ctx, nodename)
@@ -396,22 +548,22 @@ func (_m *Cluster) GetWorkloadsStatus(ctx context.Context, ids []string) ([]*typ
 	return r0, r1
 }
 
-// ListNetworks provides a mock function with given fields: ctx, podname, driver
-func (_m *Cluster) ListNetworks(ctx context.Context, podname string, driver string) ([]*enginetypes.Network, error) {
-	ret := _m.Called(ctx, podname, driver)
+// InspectNetwork provides a mock function with given fields: ctx, podname, network
+func (_m *Cluster) InspectNetwork(ctx context.Context, podname string, network string) (*enginetypes.Network, error) {
+	ret := _m.Called(ctx, podname, network)
 
-	var r0 []*enginetypes.Network
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*enginetypes.Network); ok {
-		r0 = rf(ctx, podname, driver)
+	var r0 *enginetypes.Network
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *enginetypes.Network); ok {
+		r0 = rf(ctx, podname, network)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*enginetypes.Network)
+			r0 = ret.Get(0).(*enginetypes.Network)
 		}
 	}
 
 	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, podname, driver)
+		r1 = rf(ctx, podname, network)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -419,6 +571,36 @@ func (_m *Cluster) ListNetworks(ctx context.Context, podname string, driver stri
 	return r0, r1
 }
 
+// ListNetworks provides a mock function with given fields: ctx, opts
+func (_m *Cluster) ListNetworks(ctx context.Context, opts *types.ListNetworksOptions) ([]*enginetypes.Network, bool, error) {
+	ret := _m.Called(ctx, opts)
+
+	var r0 []*enginetypes.Network
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ListNetworksOptions) []*enginetypes.Network); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*enginetypes.Network)
+		}
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ListNetworksOptions) bool); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, *types.ListNetworksOptions) error); ok {
+		r2 = rf(ctx, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // ListNodeWorkloads provides a mock function with given fields: ctx, nodename, labels
 func (_m *Cluster) ListNodeWorkloads(ctx context.Context, nodename string, labels map[string]string) ([]*types.Workload, error) {
 	ret := _m.Called(ctx, nodename, labels)
@@ -488,6 +670,29 @@ func (_m *Cluster) ListPods(ctx context.Context) ([]*types.Pod, error) {
 	return r0, r1
 }
 
+// ListResourceFixes provides a mock function with given fields: ctx, nodename
+func (_m *Cluster) ListResourceFixes(ctx context.Context, nodename string) ([]*types.ResourceFixRecord, error) {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 []*types.ResourceFixRecord
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*types.ResourceFixRecord); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*types.ResourceFixRecord)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, nodename)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListWorkloads provides a mock function with given fields: ctx, opts
 func (_m *Cluster) ListWorkloads(ctx context.Context, opts *types.ListWorkloadsOptions) ([]*types.Workload, error) {
 	ret := _m.Called(ctx, opts)
@@ -534,13 +739,80 @@ func (_m *Cluster) LogStream(ctx context.Context, opts *types.LogStreamOptions)
 	return r0, r1
 }
 
-// NodeResource provides a mock function with given fields: ctx, nodename, fix
-func (_m *Cluster) NodeResource(ctx context.Context, nodename string, fix bool) (*types.NodeResource, error) {
-	ret := _m.Called(ctx, nodename, fix)
+// NetworkUsage provides a mock function with given fields: ctx, podname, driver
+func (_m *Cluster) NetworkUsage(ctx context.Context, podname string, driver string) ([]*types.NetworkUsage, error) {
+	ret := _m.Called(ctx, podname, driver)
+
+	var r0 []*types.NetworkUsage
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*types.NetworkUsage); ok {
+		r0 = rf(ctx, podname, driver)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*types.NetworkUsage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, podname, driver)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NetworkWorkloads provides a mock function with given fields: ctx, podname, network
+func (_m *Cluster) NetworkWorkloads(ctx context.Context, podname string, network string) (*types.NetworkWorkloads, error) {
+	ret := _m.Called(ctx, podname, network)
+
+	var r0 *types.NetworkWorkloads
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *types.NetworkWorkloads); ok {
+		r0 = rf(ctx, podname, network)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.NetworkWorkloads)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, podname, network)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NodeCapabilities provides a mock function with given fields: ctx, nodename
+func (_m *Cluster) NodeCapabilities(ctx context.Context, nodename string) (enginetypes.Capabilities, error) {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 enginetypes.Capabilities
+	if rf, ok := ret.Get(0).(func(context.Context, string) enginetypes.Capabilities); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		r0 = ret.Get(0).(enginetypes.Capabilities)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, nodename)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NodeResource provides a mock function with given fields: ctx, nodename, opts
+func (_m *Cluster) NodeResource(ctx context.Context, nodename string, opts *types.NodeResourceOptions) (*types.NodeResource, error) {
+	ret := _m.Called(ctx, nodename, opts)
 
 	var r0 *types.NodeResource
-	if rf, ok := ret.Get(0).(func(context.Context, string, bool) *types.NodeResource); ok {
-		r0 = rf(ctx, nodename, fix)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *types.NodeResourceOptions) *types.NodeResource); ok {
+		r0 = rf(ctx, nodename, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*types.NodeResource)
@@ -548,8 +820,29 @@ func (_m *Cluster) NodeResource(ctx context.Context, nodename string, fix bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
-		r1 = rf(ctx, nodename, fix)
+	if rf, ok := ret.Get(1).(func(context.Context, string, *types.NodeResourceOptions) error); ok {
+		r1 = rf(ctx, nodename, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NodeResourceMetrics provides a mock function with given fields: ctx, nodename
+func (_m *Cluster) NodeResourceMetrics(ctx context.Context, nodename string) (string, error) {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, nodename)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -573,13 +866,36 @@ func (_m *Cluster) NodeStatusStream(ctx context.Context) chan *types.NodeStatus
 	return r0
 }
 
-// PodResource provides a mock function with given fields: ctx, podname
-func (_m *Cluster) PodResource(ctx context.Context, podname string) (*types.PodResource, error) {
-	ret := _m.Called(ctx, podname)
+// PlanDrain provides a mock function with given fields: ctx, nodename
+func (_m *Cluster) PlanDrain(ctx context.Context, nodename string) (*types.DrainPlan, error) {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 *types.DrainPlan
+	if rf, ok := ret.Get(0).(func(context.Context, string) *types.DrainPlan); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.DrainPlan)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, nodename)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PodResource provides a mock function with given fields: ctx, podname, withWorkloads
+func (_m *Cluster) PodResource(ctx context.Context, podname string, withWorkloads bool) (*types.PodResource, error) {
+	ret := _m.Called(ctx, podname, withWorkloads)
 
 	var r0 *types.PodResource
-	if rf, ok := ret.Get(0).(func(context.Context, string) *types.PodResource); ok {
-		r0 = rf(ctx, podname)
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) *types.PodResource); ok {
+		r0 = rf(ctx, podname, withWorkloads)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*types.PodResource)
@@ -587,8 +903,31 @@ func (_m *Cluster) PodResource(ctx context.Context, podname string) (*types.PodR
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, podname)
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, podname, withWorkloads)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PreviewDeploy provides a mock function with given fields: ctx, opts
+func (_m *Cluster) PreviewDeploy(ctx context.Context, opts *types.DeployOptions) (map[string]int, error) {
+	ret := _m.Called(ctx, opts)
+
+	var r0 map[string]int
+	if rf, ok := ret.Get(0).(func(context.Context, *types.DeployOptions) map[string]int); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]int)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.DeployOptions) error); ok {
+		r1 = rf(ctx, opts)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -610,6 +949,57 @@ func (_m *Cluster) ReallocResource(ctx context.Context, opts *types.ReallocOptio
 	return r0
 }
 
+// ReclaimReservations provides a mock function with given fields: ctx
+func (_m *Cluster) ReclaimReservations(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefreshNodeResource provides a mock function with given fields: ctx, nodename
+func (_m *Cluster) RefreshNodeResource(ctx context.Context, nodename string) (*types.NodeResource, error) {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 *types.NodeResource
+	if rf, ok := ret.Get(0).(func(context.Context, string) *types.NodeResource); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.NodeResource)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, nodename)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseReservation provides a mock function with given fields: ctx, token
+func (_m *Cluster) ReleaseReservation(ctx context.Context, token string) error {
+	ret := _m.Called(ctx, token)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // RemoveImage provides a mock function with given fields: ctx, opts
 func (_m *Cluster) RemoveImage(ctx context.Context, opts *types.ImageOptions) (chan *types.RemoveImageMessage, error) {
 	ret := _m.Called(ctx, opts)
@@ -633,6 +1023,20 @@ func (_m *Cluster) RemoveImage(ctx context.Context, opts *types.ImageOptions) (c
 	return r0, r1
 }
 
+// RemoveNetwork provides a mock function with given fields: ctx, podname, network
+func (_m *Cluster) RemoveNetwork(ctx context.Context, podname string, network string) error {
+	ret := _m.Called(ctx, podname, network)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, podname, network)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // RemoveNode provides a mock function with given fields: ctx, nodename
 func (_m *Cluster) RemoveNode(ctx context.Context, nodename string) error {
 	ret := _m.Called(ctx, nodename)
@@ -707,6 +1111,29 @@ func (_m *Cluster) ReplaceWorkload(ctx context.Context, opts *types.ReplaceOptio
 	return r0, r1
 }
 
+// ReserveCapacity provides a mock function with given fields: ctx, opts
+func (_m *Cluster) ReserveCapacity(ctx context.Context, opts *types.ReserveOptions) (*types.Reservation, error) {
+	ret := _m.Called(ctx, opts)
+
+	var r0 *types.Reservation
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReserveOptions) *types.Reservation); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Reservation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReserveOptions) error); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RunAndWait provides a mock function with given fields: ctx, opts, inCh
 func (_m *Cluster) RunAndWait(ctx context.Context, opts *types.DeployOptions, inCh <-chan []byte) (<-chan *types.AttachWorkloadMessage, error) {
 	ret := _m.Called(ctx, opts, inCh)
@@ -790,6 +1217,29 @@ func (_m *Cluster) SetNodeStatus(ctx context.Context, nodename string, ttl int64
 	return r0
 }
 
+// SetPodDefaultStrategy provides a mock function with given fields: ctx, podname, deployStrategy
+func (_m *Cluster) SetPodDefaultStrategy(ctx context.Context, podname string, deployStrategy string) (*types.Pod, error) {
+	ret := _m.Called(ctx, podname, deployStrategy)
+
+	var r0 *types.Pod
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *types.Pod); ok {
+		r0 = rf(ctx, podname, deployStrategy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Pod)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, podname, deployStrategy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetWorkloadsStatus provides a mock function with given fields: ctx, status, ttls
 func (_m *Cluster) SetWorkloadsStatus(ctx context.Context, status []*types.StatusMeta, ttls map[string]int64) ([]*types.StatusMeta, error) {
 	ret := _m.Called(ctx, status, ttls)
@@ -813,6 +1263,57 @@ func (_m *Cluster) SetWorkloadsStatus(ctx context.Context, status []*types.Statu
 	return r0, r1
 }
 
+// SnapshotNodeResource provides a mock function with given fields: ctx, nodename
+func (_m *Cluster) SnapshotNodeResource(ctx context.Context, nodename string) (*types.NodeResourceSnapshot, error) {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 *types.NodeResourceSnapshot
+	if rf, ok := ret.Get(0).(func(context.Context, string) *types.NodeResourceSnapshot); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.NodeResourceSnapshot)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, nodename)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UncordonNode provides a mock function with given fields: ctx, nodename
+func (_m *Cluster) UncordonNode(ctx context.Context, nodename string) error {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateWorkloadRestartPolicy provides a mock function with given fields: ctx, id, restartPolicy
+func (_m *Cluster) UpdateWorkloadRestartPolicy(ctx context.Context, id string, restartPolicy string) error {
+	ret := _m.Called(ctx, id, restartPolicy)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, id, restartPolicy)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WatchServiceStatus provides a mock function with given fields: _a0
 func (_m *Cluster) WatchServiceStatus(_a0 context.Context) (<-chan types.ServiceStatus, error) {
 	ret := _m.Called(_a0)
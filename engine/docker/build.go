@@ -62,8 +62,8 @@ func (e *Engine) BuildRefs(ctx context.Context, name string, tags []string) []st
 //
 // build directory is like:
 //
-//    buildDir ├─ :appname ├─ code
-//             ├─ Dockerfile
+//	buildDir ├─ :appname ├─ code
+//	         ├─ Dockerfile
 func (e *Engine) BuildContent(ctx context.Context, scm coresource.Source, opts *enginetypes.BuildContentOptions) (string, io.Reader, error) {
 	if opts.Builds == nil {
 		return "", nil, coretypes.ErrNoBuildsInSpec
@@ -20,20 +20,22 @@ import (
 
 	dockertypes "github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
+	dockerfilters "github.com/docker/docker/api/types/filters"
 	dockernetwork "github.com/docker/docker/api/types/network"
 	dockerslice "github.com/docker/docker/api/types/strslice"
 
 	"encoding/json"
 
+	corecluster "github.com/projecteru2/core/cluster"
 	enginetypes "github.com/projecteru2/core/engine/types"
 	coretypes "github.com/projecteru2/core/types"
 )
 
 const (
-	minMemory     = units.MiB * 4
-	maxMemory     = math.MaxInt64
-	restartAlways = "always"
-	root          = "root"
+	minMemory        = units.MiB * 4
+	maxMemory        = math.MaxInt64
+	restartOnFailure = "on-failure"
+	root             = "root"
 )
 
 type rawArgs struct {
@@ -46,6 +48,9 @@ type rawArgs struct {
 // VirtualizationCreate create a workload
 func (e *Engine) VirtualizationCreate(ctx context.Context, opts *enginetypes.VirtualizationCreateOptions) (*enginetypes.VirtualizationCreated, error) {
 	r := &enginetypes.VirtualizationCreated{}
+	if opts.DryRun {
+		return r, coretypes.NewDetailedErr(coretypes.ErrEngineUnsupported, enginetypes.CapVirtualizationDryRun)
+	}
 	// memory should more than 4MiB
 	if opts.Memory > 0 && opts.Memory < minMemory || opts.Memory < 0 {
 		return r, coretypes.ErrBadMemory
@@ -54,11 +59,6 @@ func (e *Engine) VirtualizationCreate(ctx context.Context, opts *enginetypes.Vir
 	if opts.Lambda {
 		opts.LogType = "json-file"
 	}
-	// set restart always
-	restartRetryCount := 3
-	if opts.RestartPolicy == restartAlways {
-		restartRetryCount = 0
-	}
 	// no longer use opts.Network as networkmode
 	// always get network name from networks
 	// -----------------------------------------
@@ -166,7 +166,7 @@ func (e *Engine) VirtualizationCreate(ctx context.Context, opts *enginetypes.Vir
 		NetworkMode: networkMode,
 		RestartPolicy: dockercontainer.RestartPolicy{
 			Name:              opts.RestartPolicy,
-			MaximumRetryCount: restartRetryCount,
+			MaximumRetryCount: restartRetryCount(opts.RestartPolicy),
 		},
 		CapAdd:     capAdds,
 		ExtraHosts: opts.Hosts,
@@ -269,6 +269,42 @@ func (e *Engine) VirtualizationInspect(ctx context.Context, ID string) (*enginet
 	return r, nil
 }
 
+// ListWorkloadIDs lists IDs of all containers marked by eru, stopped ones included
+func (e *Engine) ListWorkloadIDs(ctx context.Context) ([]string, error) {
+	if e.client == nil {
+		return nil, coretypes.ErrNilEngine
+	}
+	filters := dockerfilters.NewArgs()
+	filters.Add("label", fmt.Sprintf("%s=1", corecluster.ERUMark))
+	containers, err := e.client.ContainerList(ctx, dockertypes.ContainerListOptions{All: true, Filters: filters})
+	if err != nil {
+		return nil, err
+	}
+	IDs := []string{}
+	for _, container := range containers {
+		IDs = append(IDs, container.ID)
+	}
+	return IDs, nil
+}
+
+// VirtualizationStats samples cpu usage, docker primes precpu stats when not streaming
+func (e *Engine) VirtualizationStats(ctx context.Context, ID string) (*enginetypes.VirtualizationStats, error) {
+	if e.client == nil {
+		return nil, coretypes.ErrNilEngine
+	}
+
+	resp, err := e.client.ContainerStats(ctx, ID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	stats := &dockertypes.StatsJSON{}
+	if err := json.NewDecoder(resp.Body).Decode(stats); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &enginetypes.VirtualizationStats{CPUUsage: cpuUsage(stats)}, nil
+}
+
 // VirtualizationLogs show virtualization logs
 func (e *Engine) VirtualizationLogs(ctx context.Context, opts *enginetypes.VirtualizationLogStreamOptions) (stdout, stderr io.ReadCloser, err error) {
 	logsOpts := dockertypes.ContainerLogsOptions{
@@ -338,6 +374,17 @@ func (e *Engine) VirtualizationWait(ctx context.Context, ID, state string) (*eng
 	}
 }
 
+// VirtualizationUpdateRestartPolicy updates restart policy only, retry count follows create
+func (e *Engine) VirtualizationUpdateRestartPolicy(ctx context.Context, ID, restartPolicy string) error {
+	_, err := e.client.ContainerUpdate(ctx, ID, dockercontainer.UpdateConfig{
+		RestartPolicy: dockercontainer.RestartPolicy{
+			Name:              restartPolicy,
+			MaximumRetryCount: restartRetryCount(restartPolicy),
+		},
+	})
+	return err
+}
+
 // VirtualizationUpdateResource update virtualization resource
 func (e *Engine) VirtualizationUpdateResource(ctx context.Context, ID string, opts *enginetypes.VirtualizationResource) error {
 	if opts.Memory > 0 && opts.Memory < minMemory || opts.Memory < 0 {
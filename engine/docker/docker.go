@@ -71,6 +71,19 @@ func MakeClient(ctx context.Context, config coretypes.Config, nodename, endpoint
 	return makeRawClient(ctx, config, client, endpoint)
 }
 
+// Capabilities docker supports all operations
+func (e *Engine) Capabilities() enginetypes.Capabilities {
+	return enginetypes.Capabilities{
+		enginetypes.CapNetworkConnect:      true,
+		enginetypes.CapNetworkDisconnect:   true,
+		enginetypes.CapNetworkList:         true,
+		enginetypes.CapNetworkInspect:      true,
+		enginetypes.CapNetworkCreate:       true,
+		enginetypes.CapNetworkRemove:       true,
+		enginetypes.CapVirtualizationStats: true,
+	}
+}
+
 // Info show node info
 // 2 seconds timeout
 // used to be 5, but client won't wait that long
@@ -79,11 +92,10 @@ func (e *Engine) Info(ctx context.Context) (*enginetypes.Info, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &enginetypes.Info{ID: r.ID, NCPU: r.NCPU, MemTotal: r.MemTotal}, nil
+	return &enginetypes.Info{ID: r.ID, NCPU: r.NCPU, MemTotal: r.MemTotal, Type: "docker", Version: r.ServerVersion}, nil
 }
 
-// ResourceValidate validate resource usage
-func (e *Engine) ResourceValidate(ctx context.Context, cpu float64, cpumap map[string]int64, memory, storage int64) error {
-	// TODO list all workloads, calcuate resource
-	return nil
+// ResourceValidate validate resource usage against info got by caller
+func (e *Engine) ResourceValidate(ctx context.Context, info *enginetypes.Info, cpu float64, cpumap map[string]int64, memory, storage int64) error {
+	return info.ValidateResource(cpu, cpumap, memory, storage)
 }
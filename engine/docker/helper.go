@@ -67,7 +67,7 @@ func FuckDockerStream(stream dockertypes.HijackedResponse) io.ReadCloser {
 // make mount paths
 // 使用volumes, 参数格式跟docker一样
 // volumes:
-//     - "/foo-data:$SOMEENV/foodata:rw"
+//   - "/foo-data:$SOMEENV/foodata:rw"
 func makeMountPaths(opts *enginetypes.VirtualizationCreateOptions) ([]string, map[string]struct{}) {
 	binds := []string{}
 	volumes := make(map[string]struct{})
@@ -129,6 +129,14 @@ func makeResourceSetting(cpu float64, memory int64, cpuMap map[string]int64, num
 	return resource
 }
 
+// docker only takes max retry count with on-failure, it's rejected by the others
+func restartRetryCount(restartPolicy string) int {
+	if restartPolicy == restartOnFailure {
+		return 3
+	}
+	return 0
+}
+
 // 只要一个image的前面, tag不要
 func normalizeImage(image string) string {
 	if strings.Contains(image, ":") {
@@ -274,3 +282,17 @@ func dumpFromString(ca, cert, key *os.File, caStr, certStr, keyStr string) error
 	log.Debug("[dumpFromString] Dump ca.pem, cert.pem, key.pem from string")
 	return nil
 }
+
+// cpuUsage in cores, same as `docker stats` without multiplying 100
+func cpuUsage(stats *dockertypes.StatsJSON) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * onlineCPUs
+}
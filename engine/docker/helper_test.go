@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	dockertypes "github.com/docker/docker/api/types"
 	coreutils "github.com/projecteru2/core/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -40,3 +41,26 @@ func TestWithDumpFiles(t *testing.T) {
 		assert.True(t, os.IsNotExist(err))
 	}
 }
+
+func TestRestartRetryCount(t *testing.T) {
+	assert.Equal(t, 3, restartRetryCount("on-failure"))
+	for _, policy := range []string{"", "no", "always", "unless-stopped"} {
+		assert.Equal(t, 0, restartRetryCount(policy))
+	}
+}
+
+func TestCPUUsage(t *testing.T) {
+	stats := &dockertypes.StatsJSON{}
+	stats.PreCPUStats.CPUUsage.TotalUsage = 100
+	stats.PreCPUStats.SystemUsage = 1000
+	stats.CPUStats.CPUUsage.TotalUsage = 600
+	stats.CPUStats.SystemUsage = 3000
+	stats.CPUStats.CPUUsage.PercpuUsage = []uint64{1, 2, 3, 4}
+	assert.Equal(t, 1.0, cpuUsage(stats))
+	stats.CPUStats.OnlineCPUs = 8
+	assert.Equal(t, 2.0, cpuUsage(stats))
+	// not primed
+	stats.PreCPUStats = dockertypes.CPUStats{}
+	stats.CPUStats.SystemUsage = 0
+	assert.Equal(t, 0.0, cpuUsage(stats))
+}
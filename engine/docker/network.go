@@ -2,11 +2,15 @@ package docker
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"sort"
+	"strings"
 
 	dockertypes "github.com/docker/docker/api/types"
 	dockerfilters "github.com/docker/docker/api/types/filters"
 	dockernetwork "github.com/docker/docker/api/types/network"
+	dockerapi "github.com/docker/docker/client"
 
 	enginetypes "github.com/projecteru2/core/engine/types"
 	coretypes "github.com/projecteru2/core/types"
@@ -18,6 +22,14 @@ func (e *Engine) NetworkConnect(ctx context.Context, network, target, ipv4, ipv6
 	if err != nil {
 		return nil, err
 	}
+	// v6 only networks are connected with ipv6 alone, ipv4 left unset
+	if ipv6 != "" {
+		ip := net.ParseIP(ipv6)
+		if ip == nil {
+			return nil, coretypes.NewDetailedErr(coretypes.ErrBadIPAddress, ipv6)
+		}
+		config.IPAMConfig.IPv6Address = ip.String()
+	}
 	if err := e.client.NetworkConnect(ctx, network, target, config); err != nil {
 		return nil, err
 	}
@@ -26,10 +38,16 @@ func (e *Engine) NetworkConnect(ctx context.Context, network, target, ipv4, ipv6
 		return nil, err
 	}
 	ns := workload.NetworkSettings.Networks[network]
+	addresses := []string{}
 	if ns == nil {
-		return []string{}, nil
+		return addresses, nil
 	}
-	return []string{ns.IPAddress}, nil
+	for _, address := range []string{ns.IPAddress, ns.GlobalIPv6Address} {
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
 }
 
 // NetworkDisconnect disconnect from a network
@@ -38,12 +56,15 @@ func (e *Engine) NetworkDisconnect(ctx context.Context, network, target string,
 }
 
 // NetworkList show all networks
-func (e *Engine) NetworkList(ctx context.Context, drivers []string) ([]*enginetypes.Network, error) {
+func (e *Engine) NetworkList(ctx context.Context, drivers []string, labels map[string]string) ([]*enginetypes.Network, error) {
 	networks := []*enginetypes.Network{}
 	filters := dockerfilters.NewArgs()
 	for _, driver := range drivers {
 		filters.Add("driver", driver)
 	}
+	for key, value := range labels {
+		filters.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
 
 	ns, err := e.client.NetworkList(ctx, dockertypes.NetworkListOptions{Filters: filters})
 	if err != nil {
@@ -55,11 +76,70 @@ func (e *Engine) NetworkList(ctx context.Context, drivers []string) ([]*enginety
 		for _, config := range n.IPAM.Config {
 			subnets = append(subnets, config.Subnet)
 		}
-		networks = append(networks, &enginetypes.Network{Name: n.Name, Subnets: subnets})
+		networks = append(networks, &enginetypes.Network{Name: n.Name, Subnets: subnets, Labels: n.Labels})
 	}
 	return networks, nil
 }
 
+// NetworkInspect inspect a network
+func (e *Engine) NetworkInspect(ctx context.Context, network string) (*enginetypes.Network, error) {
+	n, err := e.client.NetworkInspect(ctx, network, dockertypes.NetworkInspectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &enginetypes.Network{Name: n.Name, ID: n.ID, Driver: n.Driver, Subnets: []string{}, IPAM: []*enginetypes.IPAMConfig{}, Containers: []string{}, Addresses: []string{}, Endpoints: map[string][]string{}}
+	for _, config := range n.IPAM.Config {
+		r.Subnets = append(r.Subnets, config.Subnet)
+		r.IPAM = append(r.IPAM, &enginetypes.IPAMConfig{Subnet: config.Subnet, IPRange: config.IPRange, Gateway: config.Gateway})
+	}
+	for ID, endpoint := range n.Containers {
+		r.Containers = append(r.Containers, ID)
+		r.Endpoints[ID] = []string{}
+		for _, address := range []string{endpoint.IPv4Address, endpoint.IPv6Address} {
+			if address != "" {
+				r.Addresses = append(r.Addresses, strings.Split(address, "/")[0])
+				r.Endpoints[ID] = append(r.Endpoints[ID], strings.Split(address, "/")[0])
+			}
+		}
+	}
+	sort.Strings(r.Containers)
+	sort.Strings(r.Addresses)
+	return r, nil
+}
+
+// NetworkCreate creates a network
+func (e *Engine) NetworkCreate(ctx context.Context, opts *enginetypes.NetworkCreateOptions) (string, error) {
+	config := dockertypes.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         opts.Driver,
+		Labels:         opts.Labels,
+	}
+	if opts.Subnet != "" {
+		config.IPAM = &dockernetwork.IPAM{
+			Config: []dockernetwork.IPAMConfig{{Subnet: opts.Subnet, Gateway: opts.Gateway}},
+		}
+		if ip, _, err := net.ParseCIDR(opts.Subnet); err == nil && ip.To4() == nil {
+			config.EnableIPv6 = true
+		}
+	}
+	resp, err := e.client.NetworkCreate(ctx, opts.Name, config)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// NetworkRemove removes a network
+// missing network is told by ErrNetworkNotExists
+func (e *Engine) NetworkRemove(ctx context.Context, network string) error {
+	err := e.client.NetworkRemove(ctx, network)
+	if dockerapi.IsErrNotFound(err) {
+		return coretypes.NewDetailedErr(enginetypes.ErrNetworkNotExists, network)
+	}
+	return err
+}
+
 func (e *Engine) makeIPV4EndpointSetting(ipv4 string) (*dockernetwork.EndpointSettings, error) {
 	config := &dockernetwork.EndpointSettings{
 		IPAMConfig: &dockernetwork.EndpointIPAMConfig{},
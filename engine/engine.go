@@ -11,6 +11,7 @@ import (
 // API define a remote engine
 type API interface {
 	Info(ctx context.Context) (*enginetypes.Info, error)
+	Capabilities() enginetypes.Capabilities
 
 	Execute(ctx context.Context, target string, config *enginetypes.ExecConfig) (execID string, stdout, stderr io.ReadCloser, stdin io.WriteCloser, _ error)
 	ExecResize(ctx context.Context, execID string, height, width uint) (err error)
@@ -18,7 +19,10 @@ type API interface {
 
 	NetworkConnect(ctx context.Context, network, target, ipv4, ipv6 string) ([]string, error)
 	NetworkDisconnect(ctx context.Context, network, target string, force bool) error
-	NetworkList(ctx context.Context, drivers []string) ([]*enginetypes.Network, error)
+	NetworkList(ctx context.Context, drivers []string, labels map[string]string) ([]*enginetypes.Network, error)
+	NetworkInspect(ctx context.Context, network string) (*enginetypes.Network, error)
+	NetworkCreate(ctx context.Context, opts *enginetypes.NetworkCreateOptions) (string, error)
+	NetworkRemove(ctx context.Context, network string) error
 
 	ImageList(ctx context.Context, image string) ([]*enginetypes.Image, error)
 	ImageRemove(ctx context.Context, image string, force, prune bool) ([]string, error)
@@ -40,12 +44,15 @@ type API interface {
 	VirtualizationStop(ctx context.Context, ID string) error
 	VirtualizationRemove(ctx context.Context, ID string, volumes, force bool) error
 	VirtualizationInspect(ctx context.Context, ID string) (*enginetypes.VirtualizationInfo, error)
+	ListWorkloadIDs(ctx context.Context) ([]string, error)
+	VirtualizationStats(ctx context.Context, ID string) (*enginetypes.VirtualizationStats, error)
 	VirtualizationLogs(ctx context.Context, opts *enginetypes.VirtualizationLogStreamOptions) (stdout, stderr io.ReadCloser, err error)
 	VirtualizationAttach(ctx context.Context, ID string, stream, openStdin bool) (stdout, stderr io.ReadCloser, stdin io.WriteCloser, err error)
 	VirtualizationResize(ctx context.Context, ID string, height, width uint) error
 	VirtualizationWait(ctx context.Context, ID, state string) (*enginetypes.VirtualizationWaitResult, error)
 	VirtualizationUpdateResource(ctx context.Context, ID string, opts *enginetypes.VirtualizationResource) error
+	VirtualizationUpdateRestartPolicy(ctx context.Context, ID, restartPolicy string) error
 	VirtualizationCopyFrom(ctx context.Context, ID, path string) (io.ReadCloser, string, error)
 
-	ResourceValidate(ctx context.Context, cpu float64, cpumap map[string]int64, memory, storage int64) error
+	ResourceValidate(ctx context.Context, info *enginetypes.Info, cpu float64, cpumap map[string]int64, memory, storage int64) error
 }
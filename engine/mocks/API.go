@@ -65,6 +65,20 @@ func (_m *API) BuildRefs(ctx context.Context, name string, tags []string) []stri
 	return r0
 }
 
+// Capabilities provides a mock function with given fields:
+func (_m *API) Capabilities() types.Capabilities {
+	ret := _m.Called()
+
+	var r0 types.Capabilities
+	if rf, ok := ret.Get(0).(func() types.Capabilities); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(types.Capabilities)
+	}
+
+	return r0
+}
+
 // ExecExitCode provides a mock function with given fields: ctx, execID
 func (_m *API) ExecExitCode(ctx context.Context, execID string) (int, error) {
 	ret := _m.Called(ctx, execID)
@@ -386,6 +400,29 @@ func (_m *API) Info(ctx context.Context) (*types.Info, error) {
 	return r0, r1
 }
 
+// ListWorkloadIDs provides a mock function with given fields: ctx
+func (_m *API) ListWorkloadIDs(ctx context.Context) ([]string, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NetworkConnect provides a mock function with given fields: ctx, network, target, ipv4, ipv6
 func (_m *API) NetworkConnect(ctx context.Context, network string, target string, ipv4 string, ipv6 string) ([]string, error) {
 	ret := _m.Called(ctx, network, target, ipv4, ipv6)
@@ -409,6 +446,27 @@ func (_m *API) NetworkConnect(ctx context.Context, network string, target string
 	return r0, r1
 }
 
+// NetworkCreate provides a mock function with given fields: ctx, opts
+func (_m *API) NetworkCreate(ctx context.Context, opts *types.NetworkCreateOptions) (string, error) {
+	ret := _m.Called(ctx, opts)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, *types.NetworkCreateOptions) string); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.NetworkCreateOptions) error); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NetworkDisconnect provides a mock function with given fields: ctx, network, target, force
 func (_m *API) NetworkDisconnect(ctx context.Context, network string, target string, force bool) error {
 	ret := _m.Called(ctx, network, target, force)
@@ -423,13 +481,36 @@ func (_m *API) NetworkDisconnect(ctx context.Context, network string, target str
 	return r0
 }
 
-// NetworkList provides a mock function with given fields: ctx, drivers
-func (_m *API) NetworkList(ctx context.Context, drivers []string) ([]*types.Network, error) {
-	ret := _m.Called(ctx, drivers)
+// NetworkInspect provides a mock function with given fields: ctx, network
+func (_m *API) NetworkInspect(ctx context.Context, network string) (*types.Network, error) {
+	ret := _m.Called(ctx, network)
+
+	var r0 *types.Network
+	if rf, ok := ret.Get(0).(func(context.Context, string) *types.Network); ok {
+		r0 = rf(ctx, network)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Network)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, network)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NetworkList provides a mock function with given fields: ctx, drivers, labels
+func (_m *API) NetworkList(ctx context.Context, drivers []string, labels map[string]string) ([]*types.Network, error) {
+	ret := _m.Called(ctx, drivers, labels)
 
 	var r0 []*types.Network
-	if rf, ok := ret.Get(0).(func(context.Context, []string) []*types.Network); ok {
-		r0 = rf(ctx, drivers)
+	if rf, ok := ret.Get(0).(func(context.Context, []string, map[string]string) []*types.Network); ok {
+		r0 = rf(ctx, drivers, labels)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*types.Network)
@@ -437,8 +518,8 @@ func (_m *API) NetworkList(ctx context.Context, drivers []string) ([]*types.Netw
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
-		r1 = rf(ctx, drivers)
+	if rf, ok := ret.Get(1).(func(context.Context, []string, map[string]string) error); ok {
+		r1 = rf(ctx, drivers, labels)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -446,13 +527,27 @@ func (_m *API) NetworkList(ctx context.Context, drivers []string) ([]*types.Netw
 	return r0, r1
 }
 
-// ResourceValidate provides a mock function with given fields: ctx, cpu, cpumap, memory, storage
-func (_m *API) ResourceValidate(ctx context.Context, cpu float64, cpumap map[string]int64, memory int64, storage int64) error {
-	ret := _m.Called(ctx, cpu, cpumap, memory, storage)
+// NetworkRemove provides a mock function with given fields: ctx, network
+func (_m *API) NetworkRemove(ctx context.Context, network string) error {
+	ret := _m.Called(ctx, network)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, network)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResourceValidate provides a mock function with given fields: ctx, info, cpu, cpumap, memory, storage
+func (_m *API) ResourceValidate(ctx context.Context, info *types.Info, cpu float64, cpumap map[string]int64, memory int64, storage int64) error {
+	ret := _m.Called(ctx, info, cpu, cpumap, memory, storage)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, float64, map[string]int64, int64, int64) error); ok {
-		r0 = rf(ctx, cpu, cpumap, memory, storage)
+	if rf, ok := ret.Get(0).(func(context.Context, *types.Info, float64, map[string]int64, int64, int64) error); ok {
+		r0 = rf(ctx, info, cpu, cpumap, memory, storage)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -665,6 +760,29 @@ func (_m *API) VirtualizationStart(ctx context.Context, ID string) error {
 	return r0
 }
 
+// VirtualizationStats provides a mock function with given fields: ctx, ID
+func (_m *API) VirtualizationStats(ctx context.Context, ID string) (*types.VirtualizationStats, error) {
+	ret := _m.Called(ctx, ID)
+
+	var r0 *types.VirtualizationStats
+	if rf, ok := ret.Get(0).(func(context.Context, string) *types.VirtualizationStats); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.VirtualizationStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // VirtualizationStop provides a mock function with given fields: ctx, ID
 func (_m *API) VirtualizationStop(ctx context.Context, ID string) error {
 	ret := _m.Called(ctx, ID)
@@ -693,6 +811,20 @@ func (_m *API) VirtualizationUpdateResource(ctx context.Context, ID string, opts
 	return r0
 }
 
+// VirtualizationUpdateRestartPolicy provides a mock function with given fields: ctx, ID, restartPolicy
+func (_m *API) VirtualizationUpdateRestartPolicy(ctx context.Context, ID string, restartPolicy string) error {
+	ret := _m.Called(ctx, ID, restartPolicy)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, ID, restartPolicy)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // VirtualizationWait provides a mock function with given fields: ctx, ID, state
 func (_m *API) VirtualizationWait(ctx context.Context, ID string, state string) (*types.VirtualizationWaitResult, error) {
 	ret := _m.Called(ctx, ID, state)
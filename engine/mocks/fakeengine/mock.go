@@ -34,7 +34,15 @@ func (wc *writeCloser) Close() error {
 func MakeClient(ctx context.Context, config coretypes.Config, nodename, endpoint, ca, cert, key string) (engine.API, error) {
 	e := &enginemocks.API{}
 	// info
-	e.On("Info", mock.Anything).Return(&enginetypes.Info{NCPU: 1, MemTotal: units.GiB + 100}, nil)
+	e.On("Capabilities").Return(enginetypes.Capabilities{
+		enginetypes.CapNetworkConnect:    true,
+		enginetypes.CapNetworkDisconnect: true,
+		enginetypes.CapNetworkList:       true,
+		enginetypes.CapNetworkInspect:    true,
+		enginetypes.CapNetworkCreate:     true,
+		enginetypes.CapNetworkRemove:     true,
+	})
+	e.On("Info", mock.Anything).Return(&enginetypes.Info{NCPU: 1, MemTotal: units.GiB + 100, Type: "fake", Version: "v0"}, nil)
 	// exec
 	execID := utils.RandomString(64)
 	bw1 := bufio.NewWriter(bytes.NewBuffer([]byte{}))
@@ -50,6 +58,12 @@ func MakeClient(ctx context.Context, config coretypes.Config, nodename, endpoint
 	e.On("NetworkList", mock.Anything, mock.Anything).Return([]*enginetypes.Network{{
 		Name: "mock-network", Subnets: []string{"1.1.1.1/8", "2.2.2.2/8"},
 	}}, nil)
+	e.On("NetworkInspect", mock.Anything, mock.Anything).Return(&enginetypes.Network{
+		Name: "mock-network", Subnets: []string{"1.1.1.1/8", "2.2.2.2/8"},
+		IPAM: []*enginetypes.IPAMConfig{{Subnet: "1.1.1.1/8", Gateway: "1.0.0.1"}, {Subnet: "2.2.2.2/8", Gateway: "2.0.0.1"}},
+	}, nil)
+	e.On("NetworkCreate", mock.Anything, mock.Anything).Return("mock-network-id", nil)
+	e.On("NetworkRemove", mock.Anything, mock.Anything).Return(nil)
 	// image
 	e.On("ImageList", mock.Anything, mock.Anything).Return(
 		[]*enginetypes.Image{{ID: "mock-image", Tags: []string{"latest"}}}, nil)
@@ -80,6 +94,8 @@ func MakeClient(ctx context.Context, config coretypes.Config, nodename, endpoint
 	e.On("VirtualizationRemove", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	vcJSON := &enginetypes.VirtualizationInfo{ID: ID, Image: "mock-image", Running: true, Networks: map[string]string{"mock-network": "1.1.1.1"}}
 	e.On("VirtualizationInspect", mock.Anything, mock.Anything).Return(vcJSON, nil)
+	e.On("ListWorkloadIDs", mock.Anything).Return([]string{ID}, nil)
+	e.On("VirtualizationStats", mock.Anything, mock.Anything).Return(&enginetypes.VirtualizationStats{CPUUsage: 0.1}, nil)
 	logs := ioutil.NopCloser(bytes.NewBufferString("logs1...\nlogs2...\n"))
 	e.On("VirtualizationLogs", mock.Anything, mock.Anything).Return(logs, nil)
 	attachData := ioutil.NopCloser(bytes.NewBufferString("logs1...\nlogs2...\n"))
@@ -89,8 +105,9 @@ func MakeClient(ctx context.Context, config coretypes.Config, nodename, endpoint
 	e.On("VirtualizationResize", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	e.On("VirtualizationWait", mock.Anything, mock.Anything, mock.Anything).Return(&enginetypes.VirtualizationWaitResult{Message: "", Code: 0}, nil)
 	e.On("VirtualizationUpdateResource", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	e.On("VirtualizationUpdateRestartPolicy", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	copyData := ioutil.NopCloser(bytes.NewBufferString("d1...\nd2...\n"))
 	e.On("VirtualizationCopyFrom", mock.Anything, mock.Anything, mock.Anything).Return(copyData, "", nil)
-	e.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	e.On("ResourceValidate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	return e, nil
 }
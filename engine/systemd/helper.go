@@ -1,15 +1,193 @@
 package systemd
 
 import (
+	"bytes"
 	"fmt"
+	"hash/fnv"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	enginetypes "github.com/projecteru2/core/engine/types"
+	"github.com/projecteru2/core/types"
 )
 
 const (
 	eruSystemdUnitPath = `/usr/local/lib/systemd/system/`
+	eruSystemdEnvPath  = `/usr/local/lib/systemd/eru-env/`
+	eruSystemdLogPath  = `/var/log/`
+	eruUnitIDPrefix    = "SYSTEMD-"
+
+	tcClassMinors = 0xfffe
 )
 
 func getUnitFilename(ID string) string {
 	basename := fmt.Sprintf("%s.service", ID)
 	return filepath.Join(eruSystemdUnitPath, basename)
 }
+
+func getEnvFilename(ID string) string {
+	basename := fmt.Sprintf("%s.env", ID)
+	return filepath.Join(eruSystemdEnvPath, basename)
+}
+
+func getLogFilename(ID string) string {
+	basename := fmt.Sprintf("%s.log", ID)
+	return filepath.Join(eruSystemdLogPath, basename)
+}
+
+// systemd doesn't run command lines with shell
+// but has its own quoting rules, see systemd.service(5)
+// $ is for variable expansion and % is for specifier, both need doubled to be literal
+var (
+	cmdArgEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, `$`, `$$`, `%`, `%%`)
+	envEscaper    = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, `%`, `%%`)
+	// no specifiers in environment file, and newlines are kept inside quotes
+	envFileEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+func quoteCmdArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$%;") {
+		return arg
+	}
+	return fmt.Sprintf(`"%s"`, cmdArgEscaper.Replace(arg))
+}
+
+func quoteCmd(cmd []string) string {
+	args := []string{}
+	for _, arg := range cmd {
+		args = append(args, quoteCmdArg(arg))
+	}
+	return strings.Join(args, " ")
+}
+
+func quoteEnv(env []string) string {
+	envs := []string{}
+	for _, e := range env {
+		envs = append(envs, fmt.Sprintf(`"%s"`, envEscaper.Replace(e)))
+	}
+	return strings.Join(envs, " ")
+}
+
+// useEnvFile tells whether the unit loads an env file, secrets never go to the unit file
+func useEnvFile(opts *enginetypes.VirtualizationCreateOptions) bool {
+	return opts.EnvFile || len(opts.Secrets) > 0
+}
+
+// envFileEnv is the env kept in the env file
+func envFileEnv(opts *enginetypes.VirtualizationCreateOptions) []string {
+	if !opts.EnvFile {
+		return opts.Secrets
+	}
+	return append(append([]string{}, opts.Env...), opts.Secrets...)
+}
+
+// renderEnvFile renders env as lines of KEY="VALUE"
+func renderEnvFile(env []string) *bytes.Buffer {
+	buffer := &bytes.Buffer{}
+	for _, e := range env {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		fmt.Fprintf(buffer, "%s=\"%s\"\n", kv[0], envFileEscaper.Replace(kv[1]))
+	}
+	return buffer
+}
+
+// parseCPUList expands kernel cpulist format, e.g. "0-3,8,10-11"
+// duplicated ones are only kept once
+func parseCPUList(cpulist string) ([]string, error) {
+	cpus := []string{}
+	seen := map[int]bool{}
+	for _, part := range strings.Split(strings.TrimSpace(cpulist), ",") {
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, types.NewDetailedErr(enginetypes.ErrInvalidCPUList, cpulist)
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil || end < start {
+				return nil, types.NewDetailedErr(enginetypes.ErrInvalidCPUList, cpulist)
+			}
+		}
+		for i := start; i <= end; i++ {
+			if !seen[i] {
+				seen[i] = true
+				cpus = append(cpus, strconv.Itoa(i))
+			}
+		}
+	}
+	return cpus, nil
+}
+
+// parseBlockDevice converts stat output like `block special file 8:0` (hex) to `8:0` (dec)
+func parseBlockDevice(stat string) (string, error) {
+	fields := strings.Fields(strings.TrimSpace(stat))
+	if len(fields) == 0 || strings.Join(fields[:len(fields)-1], " ") != "block special file" {
+		return "", types.NewDetailedErr(enginetypes.ErrInvalidIOLimit, fmt.Sprintf("not a block device: %s", stat))
+	}
+	numbers := strings.SplitN(fields[len(fields)-1], ":", 2)
+	if len(numbers) != 2 {
+		return "", types.NewDetailedErr(enginetypes.ErrInvalidIOLimit, fmt.Sprintf("invalid device number: %s", stat))
+	}
+	major, majorErr := strconv.ParseUint(numbers[0], 16, 32)
+	minor, minorErr := strconv.ParseUint(numbers[1], 16, 32)
+	if majorErr != nil || minorErr != nil {
+		return "", types.NewDetailedErr(enginetypes.ErrInvalidIOLimit, fmt.Sprintf("invalid device number: %s", stat))
+	}
+	return fmt.Sprintf("%d:%d", major, minor), nil
+}
+
+// parseTCClassMinors takes minors of classes under 1: from `tc class show` output
+// like `class htb 1:1a2b root prio 0 rate 10Mbit ceil 10Mbit burst 1600b cburst 1600b`
+func parseTCClassMinors(output string) map[uint32]bool {
+	minors := map[uint32]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "class" || !strings.HasPrefix(fields[2], "1:") {
+			continue
+		}
+		if minor, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "1:"), 16, 16); err == nil {
+			minors[uint32(minor)] = true
+		}
+	}
+	return minors
+}
+
+// freeTCClassMinor probes from the hash of ID for a minor not used, 0 and ffff are reserved
+func freeTCClassMinor(ID string, used map[uint32]bool) (uint32, error) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(ID))
+	start := h.Sum32() % tcClassMinors
+	for i := uint32(0); i < tcClassMinors; i++ {
+		if minor := (start+i)%tcClassMinors + 1; !used[minor] {
+			return minor, nil
+		}
+	}
+	return 0, types.NewDetailedErr(enginetypes.ErrInvalidBandwidth, "no htb class left")
+}
+
+// parseSystemdVersion takes `245` from `systemd 245 (245.4-4ubuntu3)`, empty if unknown
+func parseSystemdVersion(output string) string {
+	fields := strings.Fields(strings.SplitN(strings.TrimSpace(output), "\n", 2)[0])
+	if len(fields) < 2 || fields[0] != "systemd" {
+		return ""
+	}
+	return fields[1]
+}
+
+// parseUnitIDs picks IDs of eru services from unit filenames
+func parseUnitIDs(filenames string) []string {
+	IDs := []string{}
+	for _, filename := range strings.Fields(filenames) {
+		if ID := strings.TrimSuffix(filename, ".service"); ID != filename && strings.HasPrefix(ID, eruUnitIDPrefix) {
+			IDs = append(IDs, ID)
+		}
+	}
+	return IDs
+}
@@ -0,0 +1,100 @@
+package systemd
+
+import (
+	"errors"
+	"testing"
+
+	enginetypes "github.com/projecteru2/core/engine/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteCmd(t *testing.T) {
+	assert.Equal(t, `/bin/echo hello`, quoteCmd([]string{"/bin/echo", "hello"}))
+	assert.Equal(t, `/bin/echo "hello world"`, quoteCmd([]string{"/bin/echo", "hello world"}))
+	assert.Equal(t, `/bin/echo ""`, quoteCmd([]string{"/bin/echo", ""}))
+	assert.Equal(t, `/bin/app "--config={\"name\": \"eru\"}"`, quoteCmd([]string{"/bin/app", `--config={"name": "eru"}`}))
+	assert.Equal(t, `/bin/echo "it's"`, quoteCmd([]string{"/bin/echo", "it's"}))
+	assert.Equal(t, `/bin/echo "$$HOME"`, quoteCmd([]string{"/bin/echo", "$HOME"}))
+	assert.Equal(t, `/bin/echo "100%%"`, quoteCmd([]string{"/bin/echo", "100%"}))
+	assert.Equal(t, `/bin/echo "a\\b"`, quoteCmd([]string{"/bin/echo", `a\b`}))
+	assert.Equal(t, `/bin/echo "a\nb"`, quoteCmd([]string{"/bin/echo", "a\nb"}))
+}
+
+func TestQuoteEnv(t *testing.T) {
+	assert.Equal(t, `"A=1" "B=hello world"`, quoteEnv([]string{"A=1", "B=hello world"}))
+	assert.Equal(t, `"JSON={\"a\": 1}"`, quoteEnv([]string{`JSON={"a": 1}`}))
+	assert.Equal(t, `"PRICE=$5" "RATE=1%%"`, quoteEnv([]string{"PRICE=$5", "RATE=1%"}))
+	assert.Equal(t, ``, quoteEnv(nil))
+}
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,8,10-11\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0", "1", "2", "3", "8", "10", "11"}, cpus)
+
+	cpus, err = parseCPUList("1,0-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "0"}, cpus)
+
+	cpus, err = parseCPUList("")
+	assert.NoError(t, err)
+	assert.Empty(t, cpus)
+
+	_, err = parseCPUList("3-1")
+	assert.Error(t, err)
+	_, err = parseCPUList("a-b")
+	assert.Error(t, err)
+}
+
+func TestParseBlockDevice(t *testing.T) {
+	device, err := parseBlockDevice("block special file 8:0\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "8:0", device)
+	device, err = parseBlockDevice("block special file fd:10")
+	assert.NoError(t, err)
+	assert.Equal(t, "253:16", device)
+
+	for _, stat := range []string{"", "regular file 0:0", "block special file 8", "block special file x:0"} {
+		_, err = parseBlockDevice(stat)
+		assert.Error(t, err, stat)
+	}
+}
+
+func TestRenderEnvFile(t *testing.T) {
+	buffer := renderEnvFile([]string{"A=1", `B=say "hi" \o/`, "C=line1\nline2", "D=", "invalid"})
+	assert.Equal(t, "A=\"1\"\nB=\"say \\\"hi\\\" \\\\o/\"\nC=\"line1\nline2\"\nD=\"\"\n", buffer.String())
+}
+
+func TestParseTCClassMinors(t *testing.T) {
+	output := "class htb 1:1a2b root prio 0 rate 10Mbit ceil 10Mbit burst 1600b cburst 1600b\nclass htb 2:3 root rate 1Mbit\nclass htb 1:ff parent 1: rate 1Mbit\n"
+	assert.Equal(t, map[uint32]bool{0x1a2b: true, 0xff: true}, parseTCClassMinors(output))
+	assert.Empty(t, parseTCClassMinors(""))
+}
+
+func TestFreeTCClassMinor(t *testing.T) {
+	minor, err := freeTCClassMinor("SYSTEMD-abc", nil)
+	assert.NoError(t, err)
+	assert.True(t, minor >= 1 && minor <= tcClassMinors)
+	// taken one is skipped
+	next, err := freeTCClassMinor("SYSTEMD-abc", map[uint32]bool{minor: true})
+	assert.NoError(t, err)
+	assert.Equal(t, minor%tcClassMinors+1, next)
+
+	used := map[uint32]bool{}
+	for i := uint32(1); i <= tcClassMinors; i++ {
+		used[i] = true
+	}
+	_, err = freeTCClassMinor("SYSTEMD-abc", used)
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidBandwidth))
+}
+
+func TestParseSystemdVersion(t *testing.T) {
+	assert.Equal(t, "245", parseSystemdVersion("systemd 245 (245.4-4ubuntu3)\n+PAM +AUDIT +SELINUX\n"))
+	assert.Equal(t, "", parseSystemdVersion(""))
+	assert.Equal(t, "", parseSystemdVersion("bash: systemctl: command not found"))
+}
+
+func TestParseUnitIDs(t *testing.T) {
+	assert.Equal(t, []string{"SYSTEMD-abc", "SYSTEMD-def"}, parseUnitIDs("SYSTEMD-abc.service\nother.service\nSYSTEMD-def.service\nSYSTEMD-ghi.timer\n"))
+	assert.Equal(t, []string{}, parseUnitIDs(""))
+}
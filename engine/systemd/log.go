@@ -0,0 +1,59 @@
+package systemd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	enginetypes "github.com/projecteru2/core/engine/types"
+	"github.com/projecteru2/core/types"
+)
+
+// units are named by ID, matching by unit keeps lines of children and of systemd about it
+const cmdJournal = `/bin/journalctl -u %s.service --no-pager -o cat`
+
+// journalCmd builds journalctl command from docker style options
+// since and until are normalized to unix time, nothing from opts goes to shell unchecked
+func journalCmd(opts *enginetypes.VirtualizationLogStreamOptions, now time.Time) (string, error) {
+	if !cgroupPathPattern.MatchString(opts.ID) {
+		return "", types.NewDetailedErr(enginetypes.ErrInvalidLogOptions, fmt.Sprintf("id %s", opts.ID))
+	}
+	args := []string{fmt.Sprintf(cmdJournal, opts.ID)}
+	if opts.Tail != "" && opts.Tail != "all" {
+		lines, err := strconv.Atoi(opts.Tail)
+		if err != nil || lines < 0 {
+			return "", types.NewDetailedErr(enginetypes.ErrInvalidLogOptions, fmt.Sprintf("tail %s", opts.Tail))
+		}
+		args = append(args, fmt.Sprintf("-n %d", lines))
+	}
+	for _, bound := range []struct{ flag, value string }{{"--since", opts.Since}, {"--until", opts.Until}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := parseLogTime(bound.value, now)
+		if err != nil {
+			return "", types.NewDetailedErr(enginetypes.ErrInvalidLogOptions, fmt.Sprintf("%s %s", strings.TrimPrefix(bound.flag, "--"), bound.value))
+		}
+		args = append(args, fmt.Sprintf("%s @%d", bound.flag, t.Unix()))
+	}
+	if opts.Follow {
+		args = append(args, "-f")
+	}
+	return strings.Join(args, " "), nil
+}
+
+// parseLogTime accepts unix timestamp, RFC3339 time, or duration before now like docker does
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(int64(seconds), 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,35 @@
+package systemd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	enginetypes "github.com/projecteru2/core/engine/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalCmd(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cmd, err := journalCmd(&enginetypes.VirtualizationLogStreamOptions{ID: "SYSTEMD-abc"}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "/bin/journalctl -u SYSTEMD-abc.service --no-pager -o cat", cmd)
+
+	cmd, err = journalCmd(&enginetypes.VirtualizationLogStreamOptions{ID: "SYSTEMD-abc", Tail: "10", Since: "1h", Until: "2020-09-13T12:26:40Z", Follow: true}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "/bin/journalctl -u SYSTEMD-abc.service --no-pager -o cat -n 10 --since @1599996400 --until @1600000000 -f", cmd)
+	cmd, err = journalCmd(&enginetypes.VirtualizationLogStreamOptions{ID: "SYSTEMD-abc", Tail: "all", Since: "1599990000.5"}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "/bin/journalctl -u SYSTEMD-abc.service --no-pager -o cat --since @1599990000", cmd)
+
+	for _, opts := range []*enginetypes.VirtualizationLogStreamOptions{
+		{ID: "abc; rm -rf /"},
+		{ID: "SYSTEMD-abc", Tail: "-1"},
+		{ID: "SYSTEMD-abc", Tail: "1 && id"},
+		{ID: "SYSTEMD-abc", Since: "yesterday"},
+		{ID: "SYSTEMD-abc", Until: "'; id"},
+	} {
+		_, err = journalCmd(opts, now)
+		assert.True(t, errors.Is(err, enginetypes.ErrInvalidLogOptions), opts)
+	}
+}
@@ -20,7 +20,25 @@ func (s *SSHClient) NetworkDisconnect(ctx context.Context, network, target strin
 }
 
 // NetworkList lists networks
-func (s *SSHClient) NetworkList(ctx context.Context, driver []string) (networks []*enginetypes.Network, err error) {
+func (s *SSHClient) NetworkList(ctx context.Context, driver []string, labels map[string]string) (networks []*enginetypes.Network, err error) {
+	err = types.ErrEngineNotImplemented
+	return
+}
+
+// NetworkCreate creates a network
+func (s *SSHClient) NetworkCreate(ctx context.Context, opts *enginetypes.NetworkCreateOptions) (ID string, err error) {
+	err = types.ErrEngineNotImplemented
+	return
+}
+
+// NetworkRemove removes a network
+func (s *SSHClient) NetworkRemove(ctx context.Context, network string) (err error) {
+	err = types.ErrEngineNotImplemented
+	return
+}
+
+// NetworkInspect inspects a network
+func (s *SSHClient) NetworkInspect(ctx context.Context, network string) (n *enginetypes.Network, err error) {
 	err = types.ErrEngineNotImplemented
 	return
 }
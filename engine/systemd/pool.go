@@ -0,0 +1,205 @@
+package systemd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	enginetypes "github.com/projecteru2/core/engine/types"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/projecteru2/core/log"
+)
+
+const keepaliveRequest = "keepalive@openssh.com"
+
+// sshConn is the part of *ssh.Client the pool relies on
+type sshConn interface {
+	NewSession() (*ssh.Session, error)
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+	Close() error
+}
+
+// pooledConn is a connection shared by sessions, it's dialed by the first one taking it
+type pooledConn struct {
+	conn     sshConn
+	sessions int
+	broken   bool
+	ready    chan struct{}
+	err      error
+}
+
+// NewSession opens one more session on the shared connection
+func (c *pooledConn) NewSession() (*ssh.Session, error) {
+	return c.conn.NewSession()
+}
+
+// sshPool keeps at most maxConns connections to one sshd, each carries at most maxSessions sessions
+// idle connections are health checked before reuse and redialed if broken
+// long running streams have connections of their own outside the bound
+type sshPool struct {
+	dial        func() (sshConn, error)
+	maxSessions int
+	slots       chan struct{}
+
+	mu      sync.Mutex
+	conns   []*pooledConn
+	streams map[*pooledConn]struct{}
+	closed  bool
+}
+
+func newSSHPool(maxConns, maxSessions int, dial func() (sshConn, error)) *sshPool {
+	if maxConns <= 0 {
+		maxConns = 1
+	}
+	if maxSessions <= 0 {
+		maxSessions = 1
+	}
+	return &sshPool{
+		dial:        dial,
+		maxSessions: maxSessions,
+		slots:       make(chan struct{}, maxConns*maxSessions),
+		streams:     map[*pooledConn]struct{}{},
+	}
+}
+
+// get takes a connection for one session, it blocks while all sessions are in use
+func (p *sshPool) get(ctx context.Context) (*pooledConn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, errors.WithStack(ctx.Err())
+	}
+
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			<-p.slots
+			return nil, errors.WithStack(enginetypes.ErrSSHPoolClosed)
+		}
+		// slots guarantee there is either a free session or room for one more connection
+		c := p.pick()
+		if c == nil {
+			c = &pooledConn{sessions: 1, ready: make(chan struct{})}
+			p.conns = append(p.conns, c)
+			p.mu.Unlock()
+			if c.conn, c.err = p.dial(); c.err != nil {
+				p.mu.Lock()
+				p.remove(c)
+				p.mu.Unlock()
+			}
+			close(c.ready)
+		} else {
+			idle := c.sessions == 0
+			c.sessions++
+			p.mu.Unlock()
+			<-c.ready
+			if idle && c.err == nil {
+				if _, _, err := c.conn.SendRequest(keepaliveRequest, true, nil); err != nil {
+					log.Warn("[sshPool] idle connection is broken, reconnecting")
+					// slot is kept for the next try
+					p.release(c, true)
+					continue
+				}
+			}
+		}
+		if c.err != nil {
+			p.put(c, false)
+			return nil, errors.WithStack(c.err)
+		}
+		return c, nil
+	}
+}
+
+// put gives the session back, broken connections are dropped once their sessions are all back
+func (p *sshPool) put(c *pooledConn, broken bool) {
+	defer func() { <-p.slots }()
+	p.release(c, broken)
+}
+
+func (p *sshPool) release(c *pooledConn, broken bool) {
+	p.mu.Lock()
+	c.sessions--
+	if broken && !c.broken {
+		c.broken = true
+		p.remove(c)
+	}
+	drop := (c.broken || p.closed) && c.sessions == 0 && c.conn != nil
+	p.mu.Unlock()
+	if drop {
+		_ = c.conn.Close()
+	}
+}
+
+// stream dials a connection for one long running session, e.g. following logs
+// it takes no slot, so streams never starve commands, drop it by closeStream once done
+func (p *sshPool) stream() (*pooledConn, error) {
+	conn, err := p.dial()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c := &pooledConn{conn: conn, sessions: 1}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		_ = conn.Close()
+		return nil, errors.WithStack(enginetypes.ErrSSHPoolClosed)
+	}
+	p.streams[c] = struct{}{}
+	return c, nil
+}
+
+// closeStream closes connection of the stream, it's fine to be called more than once
+func (p *sshPool) closeStream(c *pooledConn) {
+	p.mu.Lock()
+	_, ok := p.streams[c]
+	delete(p.streams, c)
+	p.mu.Unlock()
+	if ok {
+		_ = c.conn.Close()
+	}
+}
+
+// close drops idle connections and stops streams,
+// connections in use are closed when their sessions are all back
+func (p *sshPool) close() (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, c := range p.conns {
+		if c.sessions > 0 || c.conn == nil {
+			continue
+		}
+		if e := c.conn.Close(); e != nil {
+			err = e
+		}
+	}
+	for c := range p.streams {
+		if e := c.conn.Close(); e != nil {
+			err = e
+		}
+	}
+	p.conns, p.streams = nil, map[*pooledConn]struct{}{}
+	return errors.WithStack(err)
+}
+
+// pick returns the least busy connection not full, must be called with mu held
+func (p *sshPool) pick() (picked *pooledConn) {
+	for _, c := range p.conns {
+		if c.sessions < p.maxSessions && (picked == nil || c.sessions < picked.sessions) {
+			picked = c
+		}
+	}
+	return picked
+}
+
+// remove must be called with mu held
+func (p *sshPool) remove(c *pooledConn) {
+	for i, conn := range p.conns {
+		if conn == c {
+			p.conns = append(p.conns[:i], p.conns[i+1:]...)
+			return
+		}
+	}
+}
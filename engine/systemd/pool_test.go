@@ -0,0 +1,187 @@
+package systemd
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	enginetypes "github.com/projecteru2/core/engine/types"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+type fakeConn struct {
+	broken bool
+	closed bool
+}
+
+func (c *fakeConn) NewSession() (*ssh.Session, error) {
+	return nil, errors.New("no session")
+}
+
+func (c *fakeConn) SendRequest(_ string, _ bool, _ []byte) (bool, []byte, error) {
+	if c.broken {
+		return false, nil, errors.New("broken")
+	}
+	return true, nil, nil
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSSHPool(t *testing.T) {
+	dialed := []*fakeConn{}
+	pool := newSSHPool(2, 2, func() (sshConn, error) {
+		conn := &fakeConn{}
+		dialed = append(dialed, conn)
+		return conn, nil
+	})
+	ctx := context.Background()
+
+	// reused after put back
+	c1, err := pool.get(ctx)
+	assert.NoError(t, err)
+	pool.put(c1, false)
+	c2, err := pool.get(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, c1, c2)
+	assert.Len(t, dialed, 1)
+
+	// sessions are multiplexed, then spread on connections bounded by max
+	c3, err := pool.get(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, c2, c3)
+	assert.Len(t, dialed, 1)
+	c4, err := pool.get(ctx)
+	assert.NoError(t, err)
+	c5, err := pool.get(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, c4, c5)
+	assert.NotEqual(t, c3, c4)
+	assert.Len(t, dialed, 2)
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.get(timeout)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// broken ones are dropped once all sessions are back
+	pool.put(c4, true)
+	assert.False(t, dialed[1].closed)
+	c6, err := pool.get(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, dialed, 3)
+	assert.Equal(t, dialed[2], c6.conn)
+	pool.put(c5, false)
+	assert.True(t, dialed[1].closed)
+
+	// unhealthy idle ones are closed, healthy one is taken instead
+	pool.put(c2, false)
+	pool.put(c3, false)
+	pool.put(c6, false)
+	dialed[0].broken = true
+	c7, err := pool.get(ctx)
+	assert.NoError(t, err)
+	assert.True(t, dialed[0].closed)
+	assert.Equal(t, dialed[2], c7.conn)
+	// redialed if none left
+	dialed[2].broken = true
+	pool.put(c7, false)
+	c7, err = pool.get(ctx)
+	assert.NoError(t, err)
+	assert.True(t, dialed[2].closed)
+	assert.Len(t, dialed, 4)
+	assert.Equal(t, dialed[3], c7.conn)
+
+	// failed by dial, slot given back
+	failing := newSSHPool(1, 1, func() (sshConn, error) { return nil, errors.New("refused") })
+	_, err = failing.get(ctx)
+	assert.Error(t, err)
+	_, err = failing.get(ctx)
+	assert.Error(t, err)
+
+	// closing, the one in use is closed when put back
+	c8, err := pool.get(ctx)
+	assert.NoError(t, err)
+	pool.put(c7, false)
+	assert.NoError(t, pool.close())
+	assert.False(t, dialed[3].closed)
+	pool.put(c8, false)
+	assert.True(t, dialed[3].closed)
+	_, err = pool.get(ctx)
+	assert.True(t, errors.Is(err, enginetypes.ErrSSHPoolClosed))
+}
+
+func TestSSHPoolStream(t *testing.T) {
+	dialed := []*fakeConn{}
+	pool := newSSHPool(1, 1, func() (sshConn, error) {
+		conn := &fakeConn{}
+		dialed = append(dialed, conn)
+		return conn, nil
+	})
+	ctx := context.Background()
+
+	// streams don't take slots, nor do they stop commands
+	s1, err := pool.stream()
+	assert.NoError(t, err)
+	c1, err := pool.get(ctx)
+	assert.NoError(t, err)
+	s2, err := pool.stream()
+	assert.NoError(t, err)
+	assert.Len(t, dialed, 3)
+	assert.Same(t, dialed[0], s1.conn)
+	assert.Same(t, dialed[1], c1.conn)
+	assert.Same(t, dialed[2], s2.conn)
+	pool.put(c1, false)
+
+	// finished one is closed once
+	pool.closeStream(s1)
+	assert.True(t, dialed[0].closed)
+	dialed[0].closed = false
+	pool.closeStream(s1)
+	assert.False(t, dialed[0].closed)
+
+	// failed by dial
+	failing := newSSHPool(1, 1, func() (sshConn, error) { return nil, errors.New("refused") })
+	_, err = failing.stream()
+	assert.Error(t, err)
+
+	// closing stops streams
+	assert.NoError(t, pool.close())
+	assert.True(t, dialed[2].closed)
+	_, err = pool.stream()
+	assert.True(t, errors.Is(err, enginetypes.ErrSSHPoolClosed))
+	assert.True(t, dialed[3].closed)
+}
+
+func TestSSHPoolConcurrent(t *testing.T) {
+	var dialed int32
+	pool := newSSHPool(2, 3, func() (sshConn, error) {
+		atomic.AddInt32(&dialed, 1)
+		return &fakeConn{}, nil
+	})
+	ctx := context.Background()
+	var inUse, peak int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := pool.get(ctx)
+			assert.NoError(t, err)
+			if n := atomic.AddInt32(&inUse, 1); n > atomic.LoadInt32(&peak) {
+				atomic.StoreInt32(&peak, n)
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&inUse, -1)
+			pool.put(c, false)
+		}()
+	}
+	wg.Wait()
+	assert.True(t, atomic.LoadInt32(&peak) <= 6)
+	assert.True(t, atomic.LoadInt32(&dialed) <= 2)
+}
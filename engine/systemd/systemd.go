@@ -3,15 +3,17 @@ package systemd
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/projecteru2/core/engine"
 	enginetypes "github.com/projecteru2/core/engine/types"
-	"github.com/projecteru2/core/types"
 	coretypes "github.com/projecteru2/core/types"
 	"golang.org/x/crypto/ssh"
 
@@ -24,22 +26,49 @@ const (
 
 	cmdInspectCPUNumber          = "/bin/grep -c processor /proc/cpuinfo"
 	cmdInspectMemoryTotalInBytes = "/usr/bin/awk '/^Mem/ {print $2}' <(/usr/bin/free -bt)"
+	cmdInspectCgroupFSType       = "/usr/bin/stat -fc %T /sys/fs/cgroup/"
+	cmdInspectNUMANodeCPUs       = "/bin/cat /sys/devices/system/node/node%s/cpulist"
+	cmdInspectNUMANodesOnline    = "/bin/cat /sys/devices/system/node/online"
+	cmdInspectBlockDevice        = "/usr/bin/stat -L -c '%%F %%t:%%T' '%s'"
+	cmdInspectTCClasses          = "/usr/sbin/tc class show dev %s"
+	cmdInspectSystemdVersion     = "/bin/systemctl --version"
+
+	cgroupV2FSType = "cgroup2fs"
 )
 
-// SSHClient contains a connection to sshd
+// SSHClient contains a pool of connections to sshd
 type SSHClient struct {
 	hostIP string
-	client *ssh.Client
+	pool   *sshPool
+
+	// cgroupV2 indicates remote host is running unified cgroup hierarchy
+	cgroupV2 bool
+
+	// restart backoff defaults, can be overridden by create options
+	restartSec         time.Duration
+	startLimitInterval time.Duration
+
+	// dependencies of every unit, can be omitted by create options
+	requiredTargets []string
+	wantedTargets   []string
 }
 
 // NewSSHClient creates a SSHClient pointer
-func NewSSHClient(endpoint string, config *ssh.ClientConfig) (*SSHClient, error) {
+// the first connection is dialed here so unreachable sshd fails early
+func NewSSHClient(endpoint string, config *ssh.ClientConfig, maxConnections, maxSessions int) (*SSHClient, error) {
 	parts := strings.Split(endpoint, ":")
-	client, err := ssh.Dial("tcp", endpoint, config)
+	pool := newSSHPool(maxConnections, maxSessions, func() (sshConn, error) {
+		return ssh.Dial("tcp", endpoint, config)
+	})
+	conn, err := pool.get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	pool.put(conn, false)
 	return &SSHClient{
 		hostIP: parts[0],
-		client: client,
-	}, err
+		pool:   pool,
+	}, nil
 }
 
 // MakeClient makes systemd engine instance
@@ -55,19 +84,52 @@ func MakeClient(ctx context.Context, config coretypes.Config, nodename, endpoint
 		},
 		HostKeyCallback: func(_ string, _ net.Addr, _ ssh.PublicKey) error { return nil },
 	}
-	return NewSSHClient(
+	client, err := NewSSHClient(
 		strings.TrimPrefix(endpoint, SSHPrefixKey),
 		sshConfig,
+		config.Systemd.MaxConnections,
+		config.Systemd.MaxSessions,
 	)
+	if err != nil {
+		return
+	}
+	if client.cgroupV2, err = client.detectCgroupV2(ctx); err != nil {
+		return
+	}
+	client.restartSec = config.Systemd.RestartSec
+	client.startLimitInterval = config.Systemd.StartLimitInterval
+	client.requiredTargets, client.wantedTargets = config.Systemd.RequiredTargets, config.Systemd.WantedTargets
+	return client, nil
 }
 
-func (s *SSHClient) withSession(f func(*ssh.Session) error) (err error) {
-	session, err := s.client.NewSession()
+// Close closes connections to sshd
+func (s *SSHClient) Close() error {
+	return s.pool.close()
+}
+
+func (s *SSHClient) withSession(ctx context.Context, f func(*ssh.Session) error) (err error) {
+	conn, err := s.pool.get(ctx)
 	if err != nil {
 		return
 	}
-	defer session.Close()
-	return f(session)
+	session, err := conn.NewSession()
+	if err != nil {
+		s.pool.put(conn, true)
+		return errors.WithStack(err)
+	}
+	err = f(session)
+	_ = session.Close()
+	// non zero exit code still leaves the connection usable
+	var exitErr *ssh.ExitError
+	s.pool.put(conn, err != nil && !errors.As(err, &exitErr))
+	return
+}
+
+// Capabilities network operations are not supported
+func (s *SSHClient) Capabilities() enginetypes.Capabilities {
+	return enginetypes.Capabilities{
+		enginetypes.CapVirtualizationDryRun: true,
+	}
 }
 
 // Info fetches cpu info of remote
@@ -80,13 +142,28 @@ func (s *SSHClient) Info(ctx context.Context) (info *enginetypes.Info, err error
 	if err != nil {
 		return
 	}
+	version, err := s.versionInfo(ctx)
+	if err != nil {
+		return
+	}
 
 	return &enginetypes.Info{
 		NCPU:     cpu,
 		MemTotal: memory,
+		Type:     "systemd",
+		Version:  version,
 	}, nil
 }
 
+// versionInfo takes version from first line of systemctl, e.g. "systemd 245 (245.4-4ubuntu3)"
+func (s *SSHClient) versionInfo(ctx context.Context) (version string, err error) {
+	stdout, stderr, err := s.runSingleCommand(ctx, cmdInspectSystemdVersion, nil)
+	if err != nil {
+		return "", errors.Wrap(err, stderr.String())
+	}
+	return parseSystemdVersion(stdout.String()), nil
+}
+
 func (s *SSHClient) cpuInfo(ctx context.Context) (cpu int, err error) {
 	stdout, stderr, err := s.runSingleCommand(ctx, cmdInspectCPUNumber, nil)
 	if err != nil {
@@ -104,18 +181,136 @@ func (s *SSHClient) memoryInfo(ctx context.Context) (memoryTotalInBytes int64, e
 	return int64(memory), err
 }
 
-// ResourceValidate validates resources
-func (s *SSHClient) ResourceValidate(ctx context.Context, cpu float64, cpumap map[string]int64, memory, storage int64) (err error) {
-	return types.ErrEngineNotImplemented
+func (s *SSHClient) numaNodeCPUs(ctx context.Context, numaNode string) ([]string, error) {
+	stdout, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdInspectNUMANodeCPUs, numaNode), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, stderr.String())
+	}
+	return parseCPUList(stdout.String())
+}
+
+// numaNodesCPUs returns union of cpus on the numa nodes, each must be online
+func (s *SSHClient) numaNodesCPUs(ctx context.Context, numaNodes []string) ([]string, error) {
+	stdout, stderr, err := s.runSingleCommand(ctx, cmdInspectNUMANodesOnline, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, stderr.String())
+	}
+	onlineNodes, err := parseCPUList(stdout.String())
+	if err != nil {
+		return nil, err
+	}
+	online := map[string]bool{}
+	for _, node := range onlineNodes {
+		online[node] = true
+	}
+
+	CPUs := []string{}
+	for _, node := range numaNodes {
+		if !online[node] {
+			return nil, coretypes.NewDetailedErr(enginetypes.ErrInvalidNUMANode, fmt.Sprintf("numa node %s not online", node))
+		}
+		nodeCPUs, err := s.numaNodeCPUs(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+		CPUs = append(CPUs, nodeCPUs...)
+	}
+	return CPUs, nil
+}
+
+// blockDevice returns major:minor of the block device
+func (s *SSHClient) blockDevice(ctx context.Context, device string) (string, error) {
+	stdout, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdInspectBlockDevice, device), nil)
+	if err != nil {
+		return "", coretypes.NewDetailedErr(enginetypes.ErrInvalidIOLimit, fmt.Sprintf("device %s: %s", device, stderr.String()))
+	}
+	return parseBlockDevice(stdout.String())
+}
+
+// tcClassMinor allocates a htb class of the device not taken by others, the hash of ID is probed first
+func (s *SSHClient) tcClassMinor(ctx context.Context, device, ID string) (uint32, error) {
+	stdout, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdInspectTCClasses, device), nil)
+	if err != nil {
+		return 0, coretypes.NewDetailedErr(enginetypes.ErrInvalidBandwidth, fmt.Sprintf("device %s: %s", device, stderr.String()))
+	}
+	return freeTCClassMinor(ID, parseTCClassMinors(stdout.String()))
+}
+
+func (s *SSHClient) detectCgroupV2(ctx context.Context) (bool, error) {
+	stdout, stderr, err := s.runSingleCommand(ctx, cmdInspectCgroupFSType, nil)
+	if err != nil {
+		return false, errors.Wrap(err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()) == cgroupV2FSType, nil
+}
+
+// ResourceValidate validates resources against info got by caller, no more round trips
+func (s *SSHClient) ResourceValidate(ctx context.Context, info *enginetypes.Info, cpu float64, cpumap map[string]int64, memory, storage int64) error {
+	return info.ValidateResource(cpu, cpumap, memory, storage)
+}
+
+// runStreamCommand starts cmd and streams its stdout
+// remote command is stopped when ctx is done or the stream is closed
+func (s *SSHClient) runStreamCommand(ctx context.Context, cmd string) (io.ReadCloser, error) {
+	log.Debugf("[runStreamCommand] %s", cmd)
+	// streams may last as long as caller wants, they don't take the bounded sessions
+	conn, err := s.pool.stream()
+	if err != nil {
+		return nil, err
+	}
+	session, err := conn.NewSession()
+	if err != nil {
+		s.pool.closeStream(conn)
+		return nil, errors.WithStack(err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err == nil {
+		err = session.Start(cmd)
+	}
+	if err != nil {
+		_ = session.Close()
+		s.pool.closeStream(conn)
+		return nil, errors.WithStack(err)
+	}
+
+	stream := &sessionStream{Reader: stdout, stop: make(chan struct{})}
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stream.stop:
+		case <-finished:
+			return
+		}
+		_ = session.Close()
+	}()
+	go func() {
+		_ = session.Wait()
+		close(finished)
+		s.pool.closeStream(conn)
+	}()
+	return stream, nil
+}
+
+// sessionStream is stdout of a running session, closing stops the session
+type sessionStream struct {
+	io.Reader
+	stop chan struct{}
+	once sync.Once
+}
+
+func (s *sessionStream) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
 }
 
-func (s *SSHClient) runSingleCommand(_ context.Context, cmd string, stdin io.Reader) (stdout, stderr *bytes.Buffer, err error) {
-	// what a pathetic library that leaves context completely useless
+func (s *SSHClient) runSingleCommand(ctx context.Context, cmd string, stdin io.Reader) (stdout, stderr *bytes.Buffer, err error) {
+	// what a pathetic library that leaves context completely useless, it only bounds waiting for a connection
 	log.Debugf("[runSingleCommand] %s", cmd)
 
 	stdout = &bytes.Buffer{}
 	stderr = &bytes.Buffer{}
-	return stdout, stderr, s.withSession(func(session *ssh.Session) error {
+	return stdout, stderr, s.withSession(ctx, func(session *ssh.Session) error {
 		session.Stdin = stdin
 		session.Stdout = stdout
 		session.Stderr = stderr
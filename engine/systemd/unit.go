@@ -1,10 +1,16 @@
 package systemd
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/projecteru2/core/log"
@@ -16,6 +22,13 @@ import (
 )
 
 const (
+	// full description in json, systemd ignores keys starting with X-
+	unitMetaKey = "X-Eru-Meta"
+	// labels shown in Description at most
+	summaryLabels = 3
+	// longer label values are not shown in Description
+	summaryValueLength = 32
+
 	unitTemplate = `
 [Unit]
 %s
@@ -25,12 +38,39 @@ const (
 	`
 )
 
+// ID is interpolated into shell commands over ssh, so only the safe charset is allowed
+var cgroupPathPattern = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+
+// device path is also interpolated into shell commands
+var devicePathPattern = regexp.MustCompile(`^/dev/[a-zA-Z0-9/_.-]+$`)
+
+// interface name is interpolated into tc commands, see IFNAMSIZ
+var interfaceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,15}$`)
+
+// unit names with type suffix, see systemd.unit(5)
+var unitNamePattern = regexp.MustCompile(`^[a-zA-Z0-9:_.@\\-]+\.(service|socket|target|mount|path|timer)$`)
+
+// firewalld is wanted only, hosts without it don't wait for it
+var defaultWantedTargets = []string{"network-online.target", "firewalld.service"}
+
+// spaces separate paths in unit directives
+var mountPathPattern = regexp.MustCompile(`^/[a-zA-Z0-9/_.-]*$`)
+
 type unitBuilder struct {
-	ID            string
-	opts          *enginetypes.VirtualizationCreateOptions
-	unitBuffer    []string
-	serviceBuffer []string
-	err           error
+	ID                 string
+	opts               *enginetypes.VirtualizationCreateOptions
+	cgroupV2           bool
+	restartSec         time.Duration
+	startLimitInterval time.Duration
+	requiredTargets    []string
+	wantedTargets      []string
+	numaNodes          []string // parsed from NUMANode
+	numaCPUs           []string // union of cpus on numaNodes
+	ioDevice           string   // major:minor of IOLimit.Device
+	tcClassMinor       uint32   // htb class of Bandwidth.Device allocated on host, written in unit to be removed on stop
+	unitBuffer         []string
+	serviceBuffer      []string
+	err                error
 }
 
 type unitDesciption struct {
@@ -39,46 +79,181 @@ type unitDesciption struct {
 	Labels map[string]string
 }
 
+// summary is human readable, like `name (a=1, b=2, +3 more)`
+func (d *unitDesciption) summary() string {
+	keys := []string{}
+	for key := range d.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	shown := []string{}
+	for _, key := range keys {
+		if len(shown) == summaryLabels {
+			break
+		}
+		if value := d.Labels[key]; len(value) <= summaryValueLength && !strings.Contains(value, "\n") {
+			shown = append(shown, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	if len(shown) < len(keys) {
+		shown = append(shown, fmt.Sprintf("+%d more", len(keys)-len(shown)))
+	}
+	if len(shown) == 0 {
+		return d.Name
+	}
+	return fmt.Sprintf("%s (%s)", d.Name, strings.Join(shown, ", "))
+}
+
+// parseUnitMeta reads description back from unit file
+// nil if unit is created without meta
+func parseUnitMeta(unit io.Reader) (*unitDesciption, error) {
+	prefix := unitMetaKey + "="
+	scanner := bufio.NewScanner(unit)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		desc := &unitDesciption{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, prefix)), desc); err != nil {
+			return nil, types.NewDetailedErr(enginetypes.ErrInvalidDescription, err)
+		}
+		return desc, nil
+	}
+	return nil, errors.WithStack(scanner.Err())
+}
+
 func (s *SSHClient) newUnitBuilder(ID string, opts *enginetypes.VirtualizationCreateOptions) *unitBuilder {
-	return &unitBuilder{
-		ID:   ID,
-		opts: opts,
+	b := &unitBuilder{
+		ID:                 ID,
+		opts:               opts,
+		cgroupV2:           s.cgroupV2,
+		restartSec:         s.restartSec,
+		startLimitInterval: s.startLimitInterval,
+		requiredTargets:    s.requiredTargets,
+		wantedTargets:      s.wantedTargets,
+	}
+	if b.requiredTargets == nil && b.wantedTargets == nil {
+		b.wantedTargets = defaultWantedTargets
+	}
+	if opts.RestartDelay > 0 {
+		b.restartSec = opts.RestartDelay
+	}
+	if opts.RestartInterval > 0 {
+		b.startLimitInterval = opts.RestartInterval
+	}
+	if !cgroupPathPattern.MatchString(ID) {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidCgroupPath, ID)
+	}
+	if numaNodes, err := parseCPUList(opts.NUMANode); err != nil {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidNUMANode, opts.NUMANode)
+	} else {
+		b.numaNodes = numaNodes
 	}
+	return b
 }
 
 func (b *unitBuilder) cgroupPath() string {
 	return b.ID
 }
 
+// cgroupControllers are derived from limits requested, cpuset is always set
+// cgcreate, cgexec and cgdelete must share the same list
+func (b *unitBuilder) cgroupControllers() string {
+	controllers := []string{}
+	if b.opts.Memory != 0 || b.opts.MemorySoft != 0 {
+		controllers = append(controllers, "memory")
+	}
+	controllers = append(controllers, "cpuset")
+	if b.opts.CPUWeight != 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if b.opts.IOLimit != nil {
+		controllers = append(controllers, "blkio")
+	}
+	if b.opts.PidsLimit != 0 {
+		controllers = append(controllers, "pids")
+	}
+	if b.opts.Bandwidth != nil && b.opts.Bandwidth.Egress != "" {
+		controllers = append(controllers, "net_cls")
+	}
+	return strings.Join(controllers, ",")
+}
+
 func (b *unitBuilder) buildUnit() *unitBuilder {
 	if b.err != nil {
 		return b
 	}
 
-	description, err := json.Marshal(unitDesciption{Name: b.opts.Name, Labels: b.opts.Labels})
+	desc := &unitDesciption{Name: b.opts.Name, Labels: b.opts.Labels}
+	meta, err := json.Marshal(desc)
 	if err != nil {
-		b.err = err
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidDescription, err)
 		return b
 	}
 
+	omit := map[string]bool{}
+	deps := b.opts.Dependencies
+	if deps == nil {
+		deps = &enginetypes.UnitDependencies{}
+	}
+	for _, name := range deps.Omit {
+		omit[name] = true
+	}
+	requires := omitTargets(b.requiredTargets, omit)
+	wants := omitTargets(b.wantedTargets, omit)
+	after := append(append([]string{}, requires...), wants...)
+	for _, name := range append(append(append(append([]string{}, after...), deps.After...), deps.Requires...), deps.Wants...) {
+		if !unitNamePattern.MatchString(name) {
+			b.err = types.NewDetailedErr(enginetypes.ErrInvalidUnitName, name)
+			return b
+		}
+	}
+	after = append(after, deps.After...)
+	wants = append(wants, deps.Wants...)
+	requires = append(requires, deps.Requires...)
+
 	b.unitBuffer = append(b.unitBuffer, []string{
-		fmt.Sprintf("Description=%s", string(description)),
-		"After=network-online.target firewalld.service",
-		"Wants=network-online.target",
+		fmt.Sprintf("Description=%s", strings.ReplaceAll(desc.summary(), "%", "%%")),
+		fmt.Sprintf("%s=%s", unitMetaKey, string(meta)),
 	}...)
+	if len(after) > 0 {
+		b.unitBuffer = append(b.unitBuffer, fmt.Sprintf("After=%s", strings.Join(after, " ")))
+	}
+	if len(wants) > 0 {
+		b.unitBuffer = append(b.unitBuffer, fmt.Sprintf("Wants=%s", strings.Join(wants, " ")))
+	}
+	if len(requires) > 0 {
+		b.unitBuffer = append(b.unitBuffer, fmt.Sprintf("Requires=%s", strings.Join(requires, " ")))
+	}
 	return b
 }
 
+func omitTargets(targets []string, omit map[string]bool) []string {
+	result := []string{}
+	for _, target := range targets {
+		if !omit[target] {
+			result = append(result, target)
+		}
+	}
+	return result
+}
+
 func (b *unitBuilder) buildPreExec(cpuAmount int) *unitBuilder {
 	if b.err != nil {
 		return b
 	}
 
-	b.serviceBuffer = append(b.serviceBuffer,
-		fmt.Sprintf("ExecStartPre=/usr/bin/cgcreate -g memory,cpuset:%s", b.cgroupPath()),
-	)
+	// cgroup v2 is managed by systemd itself, no need to create by cgtools
+	if !b.cgroupV2 {
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf("ExecStartPre=/usr/bin/cgcreate -g %s:%s", b.cgroupControllers(), b.cgroupPath()),
+		)
+	}
 
-	return b.buildNetworkLimit().buildCPULimit(cpuAmount).buildMemoryLimit()
+	return b.buildNetworkLimit().buildCPULimit(cpuAmount).buildMemoryLimit().buildIOLimit().buildPidsLimit().buildBandwidthLimit()
 }
 
 func (b *unitBuilder) buildNetworkLimit() *unitBuilder {
@@ -95,7 +270,7 @@ func (b *unitBuilder) buildNetworkLimit() *unitBuilder {
 	}
 
 	if network != "" && network != "host" {
-		b.err = errors.Wrapf(types.ErrEngineNotImplemented, "systemd engine doesn't support network %s", network)
+		b.err = types.NewDetailedErr(enginetypes.ErrUnsupportedNetwork, network)
 		return b
 	}
 	return b
@@ -112,7 +287,23 @@ func (b *unitBuilder) buildCPULimit(cpuAmount int) *unitBuilder {
 		for CPU := range b.opts.CPU {
 			allowedCPUs = append(allowedCPUs, CPU)
 		}
+		sort.Strings(allowedCPUs)
+		if b.numaCPUs != nil {
+			numaCPUs := map[string]bool{}
+			for _, CPU := range b.numaCPUs {
+				numaCPUs[CPU] = true
+			}
+			for _, CPU := range allowedCPUs {
+				if !numaCPUs[CPU] {
+					b.err = types.NewDetailedErr(enginetypes.ErrCPUNotOnNUMANode, fmt.Sprintf("cpu %s, numa node %s", CPU, strings.Join(b.numaNodes, ",")))
+					return b
+				}
+			}
+		}
 		cpusetCPUs = strings.Join(allowedCPUs, ",")
+	} else if len(b.numaCPUs) > 0 {
+		// keep the workload on the cores of its numa node
+		cpusetCPUs = strings.Join(b.numaCPUs, ",")
 	}
 
 	if b.opts.Quota > 0 {
@@ -121,10 +312,34 @@ func (b *unitBuilder) buildCPULimit(cpuAmount int) *unitBuilder {
 		)
 	}
 
-	numaNode := b.opts.NUMANode
-	if numaNode == "" {
-		numaNode = "0"
+	if b.opts.CPUWeight < 0 || b.opts.CPUWeight > 10000 {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidCPUWeight, fmt.Sprintf("%d out of range [1, 10000]", b.opts.CPUWeight))
+		return b
+	}
+	if b.opts.CPUWeight > 0 {
+		if b.cgroupV2 {
+			b.serviceBuffer = append(b.serviceBuffer, fmt.Sprintf("CPUWeight=%d", b.opts.CPUWeight))
+		} else {
+			// same conversion as systemd, default weight 100 is 1024 shares
+			b.serviceBuffer = append(b.serviceBuffer,
+				fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r cpu.shares=%d %s", b.opts.CPUWeight*1024/100, b.cgroupPath()),
+			)
+		}
+	}
+
+	numaNode := "0"
+	if len(b.numaNodes) > 0 {
+		numaNode = strings.Join(b.numaNodes, ",")
+	}
+
+	if b.cgroupV2 {
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf("AllowedCPUs=%s", cpusetCPUs),
+			fmt.Sprintf("AllowedMemoryNodes=%s", numaNode),
+		)
+		return b
 	}
+
 	b.serviceBuffer = append(b.serviceBuffer,
 		fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r cpuset.cpus=%s %s", cpusetCPUs, b.cgroupPath()),
 		fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r cpuset.mems=%s %s", numaNode, b.cgroupPath()),
@@ -137,21 +352,184 @@ func (b *unitBuilder) buildMemoryLimit() *unitBuilder {
 		return b
 	}
 
+	if b.opts.OOMScoreAdjust < -1000 || b.opts.OOMScoreAdjust > 1000 {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidOOMScoreAdjust, fmt.Sprintf("%d out of range [-1000, 1000]", b.opts.OOMScoreAdjust))
+		return b
+	}
+	if b.opts.OOMScoreAdjust != 0 {
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf("OOMScoreAdjust=%d", b.opts.OOMScoreAdjust),
+		)
+	}
+
+	if b.opts.Memory == 0 && b.opts.MemorySoft == 0 {
+		return b
+	}
+
+	if b.opts.MemorySoft < 0 || b.opts.Memory != 0 && b.opts.MemorySoft > b.opts.Memory {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidMemoryLimit, fmt.Sprintf("soft limit %d out of range [0, %d]", b.opts.MemorySoft, b.opts.Memory))
+		return b
+	}
+
+	// MemorySwap follows docker's semantic: memory plus swap, equals to Memory means swap disabled
+	if b.opts.MemorySwap != 0 && b.opts.MemorySwap < b.opts.Memory {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidMemoryLimit, fmt.Sprintf("swap %d less than memory %d", b.opts.MemorySwap, b.opts.Memory))
+		return b
+	}
+
+	softLimit := b.opts.MemorySoft
+	if softLimit == 0 {
+		softLimit = int64(utils.Max(int(b.opts.Memory/2), units.MiB*4))
+	}
+
+	if b.cgroupV2 {
+		if b.opts.Memory != 0 {
+			b.serviceBuffer = append(b.serviceBuffer,
+				fmt.Sprintf("MemoryMax=%d", b.opts.Memory),
+			)
+		}
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf("MemoryHigh=%d", softLimit),
+		)
+		if b.opts.MemorySwap != 0 {
+			b.serviceBuffer = append(b.serviceBuffer,
+				fmt.Sprintf("MemorySwapMax=%d", b.opts.MemorySwap-b.opts.Memory),
+			)
+		}
+		return b
+	}
+
+	// soft limit only
 	if b.opts.Memory == 0 {
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r memory.soft_limit_in_bytes=%d %s", softLimit, b.cgroupPath()),
+		)
 		return b
 	}
 
-	//	if b.opts.SoftLimit {
-	//		b.serviceBuffer = append(b.serviceBuffer,
-	//			fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r memory.soft_limit_in_bytes=%d %s", b.opts.Memory, b.cgroupPath()),
-	//		)
-	//
-	//	} else {
 	b.serviceBuffer = append(b.serviceBuffer,
 		fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r memory.limit_in_bytes=%d %s", b.opts.Memory, b.cgroupPath()),
-		fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r memory.soft_limit_in_bytes=%d %s", utils.Max(int(b.opts.Memory/2), units.MiB*4), b.cgroupPath()),
+		fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r memory.soft_limit_in_bytes=%d %s", softLimit, b.cgroupPath()),
+	)
+	// memsw must be set after memory limit
+	if b.opts.MemorySwap != 0 {
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r memory.memsw.limit_in_bytes=%d %s", b.opts.MemorySwap, b.cgroupPath()),
+		)
+	}
+	return b
+}
+
+func (b *unitBuilder) buildIOLimit() *unitBuilder {
+	if b.err != nil || b.opts.IOLimit == nil {
+		return b
+	}
+
+	limit := b.opts.IOLimit
+	if !devicePathPattern.MatchString(limit.Device) {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidIOLimit, fmt.Sprintf("device %s", limit.Device))
+		return b
+	}
+	if limit.ReadBPS < 0 || limit.WriteBPS < 0 {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidIOLimit, fmt.Sprintf("read bps %d, write bps %d", limit.ReadBPS, limit.WriteBPS))
+		return b
+	}
+
+	if b.cgroupV2 {
+		if limit.ReadBPS > 0 {
+			b.serviceBuffer = append(b.serviceBuffer,
+				fmt.Sprintf("IOReadBandwidthMax=%s %d", limit.Device, limit.ReadBPS),
+			)
+		}
+		if limit.WriteBPS > 0 {
+			b.serviceBuffer = append(b.serviceBuffer,
+				fmt.Sprintf("IOWriteBandwidthMax=%s %d", limit.Device, limit.WriteBPS),
+			)
+		}
+		return b
+	}
+
+	// blkio in cgroup v1 is keyed by device number instead of path
+	if b.ioDevice == "" {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidIOLimit, fmt.Sprintf("device %s not inspected", limit.Device))
+		return b
+	}
+	if limit.ReadBPS > 0 {
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf(`ExecStartPre=/usr/bin/cgset -r "blkio.throttle.read_bps_device=%s %d" %s`, b.ioDevice, limit.ReadBPS, b.cgroupPath()),
+		)
+	}
+	if limit.WriteBPS > 0 {
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf(`ExecStartPre=/usr/bin/cgset -r "blkio.throttle.write_bps_device=%s %d" %s`, b.ioDevice, limit.WriteBPS, b.cgroupPath()),
+		)
+	}
+	return b
+}
+
+func (b *unitBuilder) buildPidsLimit() *unitBuilder {
+	if b.err != nil || b.opts.PidsLimit == 0 {
+		return b
+	}
+
+	if b.opts.PidsLimit < 0 {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidPidsLimit, b.opts.PidsLimit)
+		return b
+	}
+	if b.cgroupV2 {
+		b.serviceBuffer = append(b.serviceBuffer, fmt.Sprintf("TasksMax=%d", b.opts.PidsLimit))
+		return b
+	}
+	b.serviceBuffer = append(b.serviceBuffer,
+		fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r pids.max=%d %s", b.opts.PidsLimit, b.cgroupPath()),
+	)
+	return b
+}
+
+// buildBandwidthLimit shapes egress by a htb class on the device, packets are classified by net_cls of the cgroup
+// root qdisc and filter are shared by workloads on the device, only the class is removed on stop
+// class is added rather than replaced, a workload racing for the same class fails to start instead of taking it
+// ingress can't be told apart by workload on host network, so it's rejected, traffic is accounted instead
+func (b *unitBuilder) buildBandwidthLimit() *unitBuilder {
+	if b.err != nil || b.opts.Bandwidth == nil {
+		return b
+	}
+
+	bandwidth := b.opts.Bandwidth
+	if !interfaceNamePattern.MatchString(bandwidth.Device) {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidBandwidth, fmt.Sprintf("device %s", bandwidth.Device))
+		return b
+	}
+	if bandwidth.Ingress != "" {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidBandwidth, fmt.Sprintf("ingress rate %s unsupported", bandwidth.Ingress))
+		return b
+	}
+
+	b.serviceBuffer = append(b.serviceBuffer, "IPAccounting=yes")
+	if bandwidth.Egress == "" {
+		return b
+	}
+	egress, err := units.FromHumanSize(bandwidth.Egress)
+	if err != nil || egress <= 0 {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidBandwidth, fmt.Sprintf("egress rate %s", bandwidth.Egress))
+		return b
+	}
+	// net_cls is gone in cgroup v2
+	if b.cgroupV2 {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidBandwidth, "egress limit needs net_cls of cgroup v1")
+		return b
+	}
+	minor := b.tcClassMinor
+	if minor == 0 {
+		b.err = types.NewDetailedErr(enginetypes.ErrInvalidBandwidth, fmt.Sprintf("no htb class allocated on %s", bandwidth.Device))
+		return b
+	}
+	b.serviceBuffer = append(b.serviceBuffer,
+		fmt.Sprintf("ExecStartPre=-/usr/sbin/tc qdisc add dev %s root handle 1: htb", bandwidth.Device),
+		fmt.Sprintf("ExecStartPre=/usr/sbin/tc class add dev %s parent 1: classid 1:%x htb rate %dbps", bandwidth.Device, minor, egress),
+		fmt.Sprintf("ExecStartPre=/usr/sbin/tc filter replace dev %s parent 1: protocol all prio 10 handle 1: cgroup", bandwidth.Device),
+		fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r net_cls.classid=0x1%04x %s", minor, b.cgroupPath()),
 	)
-	//	}
 	return b
 }
 
@@ -165,49 +543,123 @@ func (b *unitBuilder) buildExec() *unitBuilder {
 		user = "root"
 	}
 
-	env := []string{}
-	for _, e := range b.opts.Env {
-		env = append(env, fmt.Sprintf(`"%s"`, e))
-	}
-
-	stdioType, err := b.convertToSystemdStdio(b.opts.LogType)
+	stdioType, err := b.convertToSystemdStdio(b.opts.LogType, b.opts.LogConfig)
 	if err != nil {
 		b.err = err
 		return b
 	}
 
-	restartPolicy, err := b.convertToSystemdRestartPolicy(b.opts.RestartPolicy)
+	restartPolicy, maxRetry, err := b.convertToSystemdRestartPolicy(b.opts.RestartPolicy)
 	if err != nil {
 		b.err = err
 		return b
 	}
 
-	cmds := []string{}
-	for _, cmd := range b.opts.Cmd {
-		if strings.Contains(cmd, " ") {
-			cmd = fmt.Sprintf("'%s'", cmd)
+	for _, cmd := range b.opts.PreStartCmds {
+		if len(cmd) == 0 {
+			continue
 		}
-		cmds = append(cmds, cmd)
+		b.serviceBuffer = append(b.serviceBuffer, fmt.Sprintf("ExecStartPre=%s", quoteCmd(cmd)))
 	}
 
+	execStart := fmt.Sprintf("ExecStart=/usr/bin/cgexec -g %s:%s %s", b.cgroupControllers(), b.cgroupPath(), quoteCmd(b.opts.Cmd))
+	if b.cgroupV2 {
+		execStart = fmt.Sprintf("ExecStart=%s", quoteCmd(b.opts.Cmd))
+	}
+
+	environment := []string{}
+	if !b.opts.EnvFile {
+		environment = append(environment, fmt.Sprintf("Environment=%s", quoteEnv(b.opts.Env)))
+	}
+	if useEnvFile(b.opts) {
+		environment = append(environment, fmt.Sprintf("EnvironmentFile=%s", getEnvFilename(b.ID)))
+	}
+
+	b.serviceBuffer = append(b.serviceBuffer, execStart, fmt.Sprintf("User=%s", user))
+	b.serviceBuffer = append(b.serviceBuffer, environment...)
 	b.serviceBuffer = append(b.serviceBuffer, []string{
-		fmt.Sprintf("ExecStart=/usr/bin/cgexec -g memory,cpuset:%s %s", b.cgroupPath(), strings.Join(cmds, " ")),
-		fmt.Sprintf("User=%s", user),
-		fmt.Sprintf("Environment=%s", strings.Join(env, " ")),
 		fmt.Sprintf("StandardOutput=%s", stdioType),
 		fmt.Sprintf("StandardError=%s", stdioType),
-		fmt.Sprintf("Restart=%s", restartPolicy),
 	}...)
+	// units share the default identifier, journalctl -t <ID> filters by this one
+	if stdioType == "journal" {
+		b.serviceBuffer = append(b.serviceBuffer, fmt.Sprintf("SyslogIdentifier=%s", b.ID))
+	}
+	b.serviceBuffer = append(b.serviceBuffer, fmt.Sprintf("Restart=%s", restartPolicy))
+
+	if restartPolicy != "no" && b.restartSec > 0 {
+		b.serviceBuffer = append(b.serviceBuffer, fmt.Sprintf("RestartSec=%dms", b.restartSec.Milliseconds()))
+	}
+	// start rate limiting lives in [Unit] section
+	if maxRetry > 0 {
+		b.unitBuffer = append(b.unitBuffer,
+			fmt.Sprintf("StartLimitBurst=%d", maxRetry),
+			fmt.Sprintf("StartLimitIntervalSec=%dms", b.startLimitInterval.Milliseconds()),
+		)
+	}
+	return b.buildWatchdog().buildSecurity()
+}
+
+func (b *unitBuilder) buildWatchdog() *unitBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	// watchdog kills processes never calling sd_notify, so only for notify-capable ones
+	healthCheck := b.opts.HealthCheck
+	if healthCheck == nil || !healthCheck.Notify || healthCheck.Interval <= 0 {
+		return b
+	}
+
+	b.serviceBuffer = append(b.serviceBuffer,
+		fmt.Sprintf("WatchdogSec=%dms", healthCheck.Interval.Milliseconds()),
+		"NotifyAccess=main",
+	)
 	return b
 }
 
-func (b *unitBuilder) buildPostExec() *unitBuilder {
+func (b *unitBuilder) buildSecurity() *unitBuilder {
 	if b.err != nil {
 		return b
 	}
 
+	// strict leaves /dev, /proc and /sys writable, same as docker --read-only
+	if b.opts.ReadonlyRoot {
+		b.serviceBuffer = append(b.serviceBuffer, "ProtectSystem=strict")
+	}
+
+	for _, tmpfs := range b.opts.Tmpfs {
+		if !mountPathPattern.MatchString(tmpfs.Path) {
+			b.err = types.NewDetailedErr(enginetypes.ErrInvalidTmpfs, fmt.Sprintf("path %s", tmpfs.Path))
+			return b
+		}
+		// writable by everyone with sticky bit, like /tmp
+		options := "mode=1777"
+		if tmpfs.Size != "" {
+			size, err := units.RAMInBytes(tmpfs.Size)
+			if err != nil || size <= 0 {
+				b.err = types.NewDetailedErr(enginetypes.ErrInvalidTmpfs, fmt.Sprintf("size %s", tmpfs.Size))
+				return b
+			}
+			options = fmt.Sprintf("%s,size=%d", options, size)
+		}
+		b.serviceBuffer = append(b.serviceBuffer, fmt.Sprintf("TemporaryFileSystem=%s:%s", tmpfs.Path, options))
+	}
+	return b
+}
+
+func (b *unitBuilder) buildPostExec() *unitBuilder {
+	if b.err != nil || b.cgroupV2 {
+		return b
+	}
+
+	if bandwidth := b.opts.Bandwidth; bandwidth != nil && bandwidth.Egress != "" {
+		b.serviceBuffer = append(b.serviceBuffer,
+			fmt.Sprintf("ExecStopPost=-/usr/sbin/tc class del dev %s classid 1:%x", bandwidth.Device, b.tcClassMinor),
+		)
+	}
 	b.serviceBuffer = append(b.serviceBuffer,
-		fmt.Sprintf("ExecStopPost=/usr/bin/cgdelete -g cpuset,memory:%s", b.cgroupPath()),
+		fmt.Sprintf("ExecStopPost=/usr/bin/cgdelete -g %s:%s", b.cgroupControllers(), b.cgroupPath()),
 	)
 	return b
 }
@@ -221,28 +673,103 @@ func (b *unitBuilder) buffer() (*bytes.Buffer, error) {
 	return bytes.NewBufferString(unit), b.err
 }
 
-func (b *unitBuilder) convertToSystemdRestartPolicy(restart string) (policy string, err error) {
+// rewriteRestartPolicy replaces restart directives of a rendered unit, the rest is kept as is
+// delays already in unit are kept, so overrides at create survive
+func (b *unitBuilder) rewriteRestartPolicy(unit string) (*bytes.Buffer, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	restartPolicy, maxRetry, err := b.convertToSystemdRestartPolicy(b.opts.RestartPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	restartSec := ""
+	if b.restartSec > 0 {
+		restartSec = fmt.Sprintf("RestartSec=%dms", b.restartSec.Milliseconds())
+	}
+	startLimitInterval := fmt.Sprintf("StartLimitIntervalSec=%dms", b.startLimitInterval.Milliseconds())
+	lines := []string{}
+	for _, line := range strings.Split(unit, "\n") {
+		directive := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(directive, "RestartSec="):
+			restartSec = directive
+		case strings.HasPrefix(directive, "StartLimitIntervalSec="):
+			startLimitInterval = directive
+		case strings.HasPrefix(directive, "Restart="), strings.HasPrefix(directive, "StartLimitBurst="):
+		default:
+			lines = append(lines, line)
+		}
+	}
+
+	restart := []string{fmt.Sprintf("Restart=%s", restartPolicy)}
+	if restartPolicy != "no" && restartSec != "" {
+		restart = append(restart, restartSec)
+	}
+	startLimit := []string{}
+	if maxRetry > 0 {
+		startLimit = append(startLimit, fmt.Sprintf("StartLimitBurst=%d", maxRetry), startLimitInterval)
+	}
+
+	rewritten := []string{}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "[Service]" {
+			rewritten = insertBeforeBlanks(rewritten, startLimit)
+		}
+		rewritten = append(rewritten, line)
+	}
+	rewritten = insertBeforeBlanks(rewritten, restart)
+	return bytes.NewBufferString(strings.Join(rewritten, "\n")), nil
+}
+
+// insertBeforeBlanks appends lines to the end of a section, which is followed by blank lines
+func insertBeforeBlanks(lines, inserted []string) []string {
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return append(append(append([]string{}, lines[:end]...), inserted...), lines[end:]...)
+}
+
+// convertToSystemdRestartPolicy also parses max retry from on-failure:N
+func (b *unitBuilder) convertToSystemdRestartPolicy(restart string) (policy string, maxRetry int, err error) {
 	switch {
 	case restart == "no":
 		policy = "no"
 	case restart == "always" || restart == "":
 		policy = "always"
-	case strings.HasPrefix(restart, "on-failure"):
+	case restart == "on-failure":
+		policy = "on-failure"
+	case strings.HasPrefix(restart, "on-failure:"):
 		policy = "on-failure"
+		if maxRetry, err = strconv.Atoi(strings.TrimPrefix(restart, "on-failure:")); err != nil || maxRetry < 0 {
+			err = types.NewDetailedErr(enginetypes.ErrUnsupportedRestartPolicy, restart)
+		}
 	default:
-		err = fmt.Errorf("restart policy not supported: %s", restart)
+		err = types.NewDetailedErr(enginetypes.ErrUnsupportedRestartPolicy, restart)
 	}
 	return
 }
 
-func (b *unitBuilder) convertToSystemdStdio(logType string) (stdioType string, err error) {
+// file type appends to LogConfig["path"], or a per-unit file under /var/log
+func (b *unitBuilder) convertToSystemdStdio(logType string, logConfig map[string]string) (stdioType string, err error) {
 	switch logType {
 	case "journald", "":
 		stdioType = "journal"
 	case "none":
 		stdioType = "null"
+	case "file":
+		path := logConfig["path"]
+		if path == "" {
+			path = getLogFilename(b.ID)
+		}
+		if !mountPathPattern.MatchString(path) || strings.HasSuffix(path, "/") || strings.Contains(path, "..") {
+			return "", types.NewDetailedErr(enginetypes.ErrInvalidLogPath, path)
+		}
+		stdioType = fmt.Sprintf("append:%s", path)
 	default:
-		err = fmt.Errorf("log type not supported: %s", logType)
+		err = types.NewDetailedErr(enginetypes.ErrUnsupportedLogType, logType)
 	}
 	return
 }
@@ -0,0 +1,592 @@
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	enginetypes "github.com/projecteru2/core/engine/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCreateOptions() *enginetypes.VirtualizationCreateOptions {
+	return &enginetypes.VirtualizationCreateOptions{
+		VirtualizationResource: enginetypes.VirtualizationResource{
+			CPU:    map[string]int64{"1": 100},
+			Quota:  1,
+			Memory: 1 << 30,
+		},
+		Name: "test",
+		Cmd:  []string{"/bin/sleep", "100"},
+	}
+}
+
+func TestUnitBuilderCgroupV1(t *testing.T) {
+	s := &SSHClient{}
+	buffer, err := s.newUnitBuilder("test", newTestCreateOptions()).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgcreate -g memory,cpuset:test")
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgset -r cpuset.cpus=1 test")
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgset -r memory.limit_in_bytes=1073741824 test")
+	assert.Contains(t, unit, "ExecStart=/usr/bin/cgexec -g memory,cpuset:test /bin/sleep 100")
+	assert.Contains(t, unit, "ExecStopPost=/usr/bin/cgdelete -g memory,cpuset:test")
+}
+
+func TestUnitBuilderCgroupV2(t *testing.T) {
+	s := &SSHClient{cgroupV2: true}
+	buffer, err := s.newUnitBuilder("test", newTestCreateOptions()).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.NotContains(t, unit, "/usr/bin/cg")
+	assert.Contains(t, unit, "CPUQuota=100.00%")
+	assert.Contains(t, unit, "AllowedCPUs=1")
+	assert.Contains(t, unit, "AllowedMemoryNodes=0")
+	assert.Contains(t, unit, "MemoryMax=1073741824")
+	assert.Contains(t, unit, "MemoryHigh=536870912")
+	assert.Contains(t, unit, "ExecStart=/bin/sleep 100")
+}
+
+func TestUnitBuilderExec(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	opts.Cmd = []string{"/bin/app", `--config={"name": "eru"}`}
+	opts.Env = []string{"A=hello world"}
+	opts.PreStartCmds = [][]string{{"/bin/mkdir", "-p", "/tmp/a b"}, {}, {"/bin/touch", "/tmp/a b/c"}}
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "ExecStartPre=/bin/mkdir -p \"/tmp/a b\"\nExecStartPre=/bin/touch \"/tmp/a b/c\"\nExecStart=")
+	assert.Contains(t, unit, `ExecStart=/usr/bin/cgexec -g memory,cpuset:test /bin/app "--config={\"name\": \"eru\"}"`)
+	assert.Contains(t, unit, `Environment="A=hello world"`)
+}
+
+func TestUnitBuilderRestartPolicy(t *testing.T) {
+	s := &SSHClient{restartSec: time.Second, startLimitInterval: time.Minute}
+	opts := newTestCreateOptions()
+	opts.RestartPolicy = "on-failure:5"
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "Restart=on-failure\nRestartSec=1000ms")
+	assert.Contains(t, unit, "StartLimitBurst=5\nStartLimitIntervalSec=60000ms\n\n[Service]")
+
+	// override by create options
+	opts.RestartDelay = 5 * time.Second
+	opts.RestartInterval = 10 * time.Minute
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "RestartSec=5000ms")
+	assert.Contains(t, unit, "StartLimitIntervalSec=600000ms")
+
+	// no limit
+	opts.RestartPolicy = "always"
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.NotContains(t, buffer.String(), "StartLimitBurst")
+
+	// no restart no delay
+	opts.RestartPolicy = "no"
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.NotContains(t, buffer.String(), "RestartSec")
+
+	// invalid
+	for _, policy := range []string{"on-failure:x", "on-failure:-1", "unless-stopped"} {
+		opts.RestartPolicy = policy
+		_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+		assert.True(t, errors.Is(err, enginetypes.ErrUnsupportedRestartPolicy))
+	}
+}
+
+func TestRewriteRestartPolicy(t *testing.T) {
+	s := &SSHClient{restartSec: time.Second, startLimitInterval: time.Minute}
+	opts := newTestCreateOptions()
+	opts.RestartPolicy = "on-failure:5"
+	opts.RestartDelay = 5 * time.Second
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	origin := buffer.String()
+
+	// stop restarting, delays are kept
+	buffer, err = s.newUnitBuilder("test", &enginetypes.VirtualizationCreateOptions{RestartPolicy: "no"}).rewriteRestartPolicy(origin)
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "Restart=no")
+	assert.NotContains(t, unit, "Restart=on-failure")
+	assert.NotContains(t, unit, "RestartSec")
+	assert.NotContains(t, unit, "StartLimitBurst")
+	assert.Equal(t, strings.Count(origin, "\n")-3, strings.Count(unit, "\n"))
+
+	// back to limited restarts
+	buffer, err = s.newUnitBuilder("test", &enginetypes.VirtualizationCreateOptions{RestartPolicy: "on-failure:3"}).rewriteRestartPolicy(origin)
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "StartLimitBurst=3\nStartLimitIntervalSec=60000ms\n\n[Service]")
+	assert.Contains(t, unit, "Restart=on-failure\nRestartSec=5000ms\n")
+	assert.Equal(t, 1, strings.Count(unit, "Restart="))
+
+	// invalid
+	_, err = s.newUnitBuilder("test", &enginetypes.VirtualizationCreateOptions{RestartPolicy: "unless-stopped"}).rewriteRestartPolicy(origin)
+	assert.True(t, errors.Is(err, enginetypes.ErrUnsupportedRestartPolicy))
+}
+
+func TestUnitBuilderErrors(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	opts.LogType = "syslog"
+	_, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrUnsupportedLogType))
+	assert.Contains(t, err.Error(), "syslog")
+
+	opts = newTestCreateOptions()
+	opts.Networks = map[string]string{"calico": ""}
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrUnsupportedNetwork))
+	assert.Contains(t, err.Error(), "calico")
+}
+
+func TestUnitBuilderCgroupPath(t *testing.T) {
+	s := &SSHClient{}
+	for _, ID := range []string{"", "test; rm -rf /", "test$(reboot)", "test`id`", "test 1", "../test", "test\nExecStart=/bin/sh"} {
+		_, err := s.newUnitBuilder(ID, newTestCreateOptions()).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+		assert.True(t, errors.Is(err, enginetypes.ErrInvalidCgroupPath), ID)
+	}
+
+	buffer, err := s.newUnitBuilder("SYSTEMD-abc123", newTestCreateOptions()).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "cgcreate -g memory,cpuset:SYSTEMD-abc123")
+}
+
+func TestUnitBuilderMemorySwapAndOOM(t *testing.T) {
+	opts := newTestCreateOptions()
+	opts.MemorySwap = opts.Memory
+	opts.OOMScoreAdjust = -500
+
+	s := &SSHClient{}
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "OOMScoreAdjust=-500")
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgset -r memory.soft_limit_in_bytes=536870912 test\nExecStartPre=/usr/bin/cgset -r memory.memsw.limit_in_bytes=1073741824 test")
+
+	s = &SSHClient{cgroupV2: true}
+	opts.MemorySwap = opts.Memory * 2
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "MemorySwapMax=1073741824")
+
+	// invalid
+	opts.MemorySwap = opts.Memory - 1
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidMemoryLimit))
+	opts.MemorySwap = 0
+	opts.OOMScoreAdjust = 1001
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidOOMScoreAdjust))
+}
+
+func TestUnitBuilderNUMACPUs(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	opts.CPU = nil
+	opts.NUMANode = "1"
+	b := s.newUnitBuilder("test", opts)
+	b.numaCPUs = []string{"4", "5", "6", "7"}
+	buffer, err := b.buildUnit().buildPreExec(8).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "cpuset.cpus=4,5,6,7 test")
+	assert.Contains(t, buffer.String(), "cpuset.mems=1 test")
+
+	opts.CPU = map[string]int64{"5": 100}
+	b = s.newUnitBuilder("test", opts)
+	b.numaCPUs = []string{"4", "5", "6", "7"}
+	buffer, err = b.buildUnit().buildPreExec(8).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "cpuset.cpus=5 test")
+
+	opts.CPU = map[string]int64{"1": 100, "5": 100}
+	b = s.newUnitBuilder("test", opts)
+	b.numaCPUs = []string{"4", "5", "6", "7"}
+	_, err = b.buildUnit().buildPreExec(8).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrCPUNotOnNUMANode))
+	assert.Contains(t, err.Error(), "cpu 1")
+}
+
+func TestUnitBuilderMultiNUMANodes(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	opts.CPU = nil
+	opts.NUMANode = "0-1,1"
+	b := s.newUnitBuilder("test", opts)
+	assert.Equal(t, []string{"0", "1"}, b.numaNodes)
+	b.numaCPUs = []string{"0", "1", "4", "5"}
+	buffer, err := b.buildUnit().buildPreExec(8).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "cpuset.cpus=0,1,4,5 test")
+	assert.Contains(t, buffer.String(), "cpuset.mems=0,1 test")
+
+	s.cgroupV2 = true
+	b = s.newUnitBuilder("test", opts)
+	b.numaCPUs = []string{"0", "1", "4", "5"}
+	buffer, err = b.buildUnit().buildPreExec(8).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "AllowedMemoryNodes=0,1")
+
+	// numa node is interpolated into shell commands
+	opts.NUMANode = "0;reboot"
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(8).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidNUMANode))
+}
+
+func TestUnitBuilderWatchdog(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	opts.HealthCheck = &enginetypes.HealthCheck{Interval: 30 * time.Second, Notify: true}
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "WatchdogSec=30000ms\nNotifyAccess=main")
+
+	// process not supporting notify
+	opts.HealthCheck.Notify = false
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.NotContains(t, buffer.String(), "WatchdogSec")
+	assert.NotContains(t, buffer.String(), "NotifyAccess")
+}
+
+func TestUnitBuilderSecurity(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.NotContains(t, buffer.String(), "ProtectSystem")
+	assert.NotContains(t, buffer.String(), "TemporaryFileSystem")
+
+	opts.ReadonlyRoot = true
+	opts.Tmpfs = []enginetypes.Tmpfs{{Path: "/tmp", Size: "64m"}, {Path: "/run/app"}}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "ProtectSystem=strict")
+	assert.Contains(t, buffer.String(), "TemporaryFileSystem=/tmp:mode=1777,size=67108864")
+	assert.Contains(t, buffer.String(), "TemporaryFileSystem=/run/app:mode=1777\n")
+
+	for _, tmpfs := range []enginetypes.Tmpfs{{Path: "tmp"}, {Path: "/tmp /var"}, {Path: "/tmp", Size: "64x"}, {Path: "/tmp", Size: "-1"}} {
+		opts.Tmpfs = []enginetypes.Tmpfs{tmpfs}
+		_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+		assert.True(t, errors.Is(err, enginetypes.ErrInvalidTmpfs), tmpfs)
+	}
+}
+
+func TestUnitBuilderMemorySoftLimit(t *testing.T) {
+	// both soft and hard
+	opts := newTestCreateOptions()
+	opts.MemorySoft = opts.Memory * 4 / 5
+	s := &SSHClient{cgroupV2: true}
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "MemoryMax=1073741824\nMemoryHigh=858993459")
+
+	s = &SSHClient{}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "memory.limit_in_bytes=1073741824 test\nExecStartPre=/usr/bin/cgset -r memory.soft_limit_in_bytes=858993459 test")
+
+	// soft only
+	opts.Memory = 0
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "memory.soft_limit_in_bytes=858993459 test")
+	assert.NotContains(t, buffer.String(), "memory.limit_in_bytes")
+
+	s = &SSHClient{cgroupV2: true}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "MemoryHigh=858993459")
+	assert.NotContains(t, buffer.String(), "MemoryMax")
+
+	// soft > hard
+	opts.Memory = 1 << 20 * 512
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidMemoryLimit))
+}
+
+func TestUnitDescription(t *testing.T) {
+	desc := &unitDesciption{Name: "test"}
+	assert.Equal(t, "test", desc.summary())
+	desc.Labels = map[string]string{"b": "2", "a": "1"}
+	assert.Equal(t, "test (a=1, b=2)", desc.summary())
+	desc.Labels = map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "meta": `{"publish": ["80"], "healthcheck": null}`}
+	assert.Equal(t, "test (a=1, b=2, c=3, +2 more)", desc.summary())
+
+	opts := newTestCreateOptions()
+	opts.Name = "100%"
+	opts.Labels = desc.Labels
+	s := &SSHClient{}
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "Description=100%% (a=1, b=2, c=3, +2 more)\n")
+
+	// round trip
+	meta, err := parseUnitMeta(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, &unitDesciption{Name: "100%", Labels: opts.Labels}, meta)
+
+	// units without meta
+	meta, err = parseUnitMeta(strings.NewReader("[Unit]\nDescription={}\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, meta)
+	_, err = parseUnitMeta(strings.NewReader("[Unit]\nX-Eru-Meta={\n"))
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidDescription))
+}
+
+func TestUnitBuilderIOLimit(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	opts.IOLimit = &enginetypes.IOLimit{Device: "/dev/sda", ReadBPS: 1048576, WriteBPS: 2097152}
+	b := s.newUnitBuilder("test", opts)
+	b.ioDevice = "8:0"
+	buffer, err := b.buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgcreate -g memory,cpuset,blkio:test")
+	assert.Contains(t, unit, `ExecStartPre=/usr/bin/cgset -r "blkio.throttle.read_bps_device=8:0 1048576" test`)
+	assert.Contains(t, unit, `ExecStartPre=/usr/bin/cgset -r "blkio.throttle.write_bps_device=8:0 2097152" test`)
+	assert.Contains(t, unit, "ExecStart=/usr/bin/cgexec -g memory,cpuset,blkio:test")
+	assert.Contains(t, unit, "ExecStopPost=/usr/bin/cgdelete -g memory,cpuset,blkio:test")
+
+	// device number is required by cgroup v1
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidIOLimit))
+
+	s.cgroupV2 = true
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "IOReadBandwidthMax=/dev/sda 1048576")
+	assert.Contains(t, unit, "IOWriteBandwidthMax=/dev/sda 2097152")
+
+	opts.IOLimit = &enginetypes.IOLimit{Device: "/dev/sda; reboot", ReadBPS: 1}
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidIOLimit))
+
+	opts.IOLimit = &enginetypes.IOLimit{Device: "/dev/sda", WriteBPS: -1}
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidIOLimit))
+}
+
+func TestUnitBuilderEnvFile(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	opts.Env = []string{"SECRET=42"}
+	opts.EnvFile = true
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "EnvironmentFile=/usr/local/lib/systemd/eru-env/test.env")
+	assert.NotContains(t, unit, "Environment=")
+	assert.NotContains(t, unit, "SECRET")
+	assert.Equal(t, []string{"SECRET=42"}, envFileEnv(opts))
+
+	// secrets go to env file while raw env stays in unit
+	opts.EnvFile = false
+	opts.Secrets = []string{"TOKEN=t"}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, `Environment="SECRET=42"`)
+	assert.Contains(t, unit, "EnvironmentFile=/usr/local/lib/systemd/eru-env/test.env")
+	assert.NotContains(t, unit, "TOKEN")
+	assert.Equal(t, []string{"TOKEN=t"}, envFileEnv(opts))
+
+	opts.EnvFile = true
+	assert.Equal(t, []string{"SECRET=42", "TOKEN=t"}, envFileEnv(opts))
+}
+
+func TestUnitBuilderCgroupControllers(t *testing.T) {
+	opts := newTestCreateOptions()
+	opts.Memory = 0
+	opts.PidsLimit = 512
+	s := &SSHClient{}
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgcreate -g cpuset,pids:test")
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgset -r pids.max=512 test")
+	assert.Contains(t, unit, "ExecStart=/usr/bin/cgexec -g cpuset,pids:test")
+	assert.Contains(t, unit, "ExecStopPost=/usr/bin/cgdelete -g cpuset,pids:test")
+	assert.NotContains(t, unit, "memory")
+
+	s = &SSHClient{cgroupV2: true}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "TasksMax=512")
+	assert.NotContains(t, unit, "pids.max")
+
+	opts.PidsLimit = -1
+	_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidPidsLimit))
+}
+
+func TestUnitBuilderCPUWeight(t *testing.T) {
+	opts := newTestCreateOptions()
+	opts.CPUWeight = 50
+	s := &SSHClient{}
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "CPUQuota=100.00%")
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgcreate -g memory,cpuset,cpu:test")
+	assert.Contains(t, unit, "ExecStartPre=/usr/bin/cgset -r cpu.shares=512 test")
+	assert.Contains(t, unit, "ExecStart=/usr/bin/cgexec -g memory,cpuset,cpu:test")
+
+	s = &SSHClient{cgroupV2: true}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "CPUWeight=50")
+	assert.NotContains(t, unit, "cpu.shares")
+
+	// burstable without quota
+	opts.Quota = 0
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "CPUWeight=50")
+	assert.NotContains(t, unit, "CPUQuota")
+
+	for _, weight := range []int64{-1, 10001} {
+		opts.CPUWeight = weight
+		_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+		assert.True(t, errors.Is(err, enginetypes.ErrInvalidCPUWeight))
+	}
+}
+
+func TestUnitBuilderLog(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "StandardOutput=journal")
+	assert.Contains(t, unit, "SyslogIdentifier=test")
+
+	opts.LogType = "file"
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "StandardOutput=append:/var/log/test.log\nStandardError=append:/var/log/test.log")
+	assert.NotContains(t, unit, "SyslogIdentifier")
+
+	opts.LogConfig = map[string]string{"path": "/data/logs/app.log"}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "StandardOutput=append:/data/logs/app.log")
+
+	for _, path := range []string{"relative.log", "/data/a b.log", "/data/logs/", "/data/../etc/passwd"} {
+		opts.LogConfig["path"] = path
+		_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+		assert.True(t, errors.Is(err, enginetypes.ErrInvalidLogPath), path)
+	}
+}
+
+func TestUnitBuilderDependencies(t *testing.T) {
+	s := &SSHClient{}
+	opts := newTestCreateOptions()
+	buffer, err := s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "After=network-online.target firewalld.service\nWants=network-online.target firewalld.service\n")
+	assert.NotContains(t, unit, "Requires=")
+
+	opts.Dependencies = &enginetypes.UnitDependencies{
+		After:    []string{"db.service"},
+		Requires: []string{"db.service"},
+		Wants:    []string{"cache.service", "sshd-keygen@rsa.service"},
+	}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "After=network-online.target firewalld.service db.service\n")
+	assert.Contains(t, unit, "Wants=network-online.target firewalld.service cache.service sshd-keygen@rsa.service\n")
+	assert.Contains(t, unit, "Requires=db.service\n")
+
+	// firewalld omitted
+	opts.Dependencies = &enginetypes.UnitDependencies{Omit: []string{"firewalld.service"}}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "After=network-online.target\nWants=network-online.target\n")
+	assert.NotContains(t, unit, "firewalld")
+
+	// targets by config, required ones go before wanted ones
+	configured := &SSHClient{requiredTargets: []string{"network-online.target"}, wantedTargets: []string{"firewalld.service"}}
+	opts.Dependencies = nil
+	buffer, err = configured.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "After=network-online.target firewalld.service\nWants=firewalld.service\nRequires=network-online.target\n")
+	// all removed
+	opts.Dependencies = &enginetypes.UnitDependencies{Omit: []string{"network-online.target", "firewalld.service"}}
+	buffer, err = configured.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.NotContains(t, unit, "After=")
+	assert.NotContains(t, unit, "Wants=")
+	assert.NotContains(t, unit, "Requires=")
+	configured.requiredTargets = []string{"bad target"}
+	_, err = configured.newUnitBuilder("test", &enginetypes.VirtualizationCreateOptions{}).buildUnit().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidUnitName))
+
+	for _, name := range []string{"db", "db.service other.service", "db.service\nExecStart=/bin/sh", ""} {
+		opts.Dependencies = &enginetypes.UnitDependencies{After: []string{name}}
+		_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+		assert.True(t, errors.Is(err, enginetypes.ErrInvalidUnitName), name)
+	}
+}
+
+func TestUnitBuilderBandwidth(t *testing.T) {
+	opts := newTestCreateOptions()
+	opts.Bandwidth = &enginetypes.Bandwidth{Device: "eth0", Egress: "10m"}
+	s := &SSHClient{}
+	b := s.newUnitBuilder("test", opts)
+	minor := uint32(0x1a2b)
+	b.tcClassMinor = minor
+	buffer, err := b.buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit := buffer.String()
+	assert.Contains(t, unit, "IPAccounting=yes")
+	assert.Contains(t, unit, "ExecStartPre=-/usr/sbin/tc qdisc add dev eth0 root handle 1: htb")
+	assert.Contains(t, unit, fmt.Sprintf("ExecStartPre=/usr/sbin/tc class add dev eth0 parent 1: classid 1:%x htb rate 10000000bps", minor))
+	assert.Contains(t, unit, fmt.Sprintf("ExecStartPre=/usr/bin/cgset -r net_cls.classid=0x1%04x test", minor))
+	assert.Contains(t, unit, fmt.Sprintf("ExecStopPost=-/usr/sbin/tc class del dev eth0 classid 1:%x", minor))
+	assert.Contains(t, unit, "ExecStopPost=/usr/bin/cgdelete -g memory,cpuset,net_cls:test")
+
+	// only accounted without egress
+	opts.Bandwidth = &enginetypes.Bandwidth{Device: "eth0"}
+	s = &SSHClient{cgroupV2: true}
+	buffer, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.NoError(t, err)
+	unit = buffer.String()
+	assert.Contains(t, unit, "IPAccounting=yes")
+	assert.NotContains(t, unit, "tc ")
+
+	for _, bandwidth := range []*enginetypes.Bandwidth{
+		{Device: "eth0", Egress: "10m"},
+		{Device: "eth0; rm -rf /", Egress: "10m"},
+		{Device: "eth0", Egress: "fast"},
+		{Device: "eth0", Ingress: "20m"},
+	} {
+		opts.Bandwidth = bandwidth
+		_, err = s.newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+		assert.True(t, errors.Is(err, enginetypes.ErrInvalidBandwidth), bandwidth)
+	}
+	// class must be allocated on host
+	opts.Bandwidth = &enginetypes.Bandwidth{Device: "eth0", Egress: "10m"}
+	_, err = (&SSHClient{}).newUnitBuilder("test", opts).buildUnit().buildPreExec(2).buildExec().buildPostExec().buffer()
+	assert.True(t, errors.Is(err, enginetypes.ErrInvalidBandwidth))
+}
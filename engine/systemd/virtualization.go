@@ -1,6 +1,7 @@
 package systemd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -8,6 +9,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	enginetypes "github.com/projecteru2/core/engine/types"
@@ -18,6 +20,7 @@ import (
 const (
 	cmdFileExist      = `/usr/bin/test -f '%s'`
 	cmdCopyFromStdin  = `/bin/cp -f /dev/stdin '%s'`
+	cmdPrivateCopy    = `umask 077 && /bin/cp -f /dev/stdin '%[1]s' && /bin/chmod 600 '%[1]s'`
 	cmdMkdir          = `/bin/mkdir -p %s`
 	cmdRemove         = `/bin/rm -f %s`
 	cmdSystemdReload  = `/bin/systemctl daemon-reload`
@@ -25,20 +28,51 @@ const (
 	cmdSystemdStop    = `/bin/systemctl stop %s`
 	cmdSystemdStatus  = `/bin/systemctl show %s --property SubState,ActiveState,Environment,Description --no-pager`
 	cmdCopyToStdout   = `/bin/cp -f '%s' /dev/stdout`
+	cmdListUnits      = `/bin/ls -1 %s`
 )
 
 // VirtualizationCreate creates systemd service
 func (s *SSHClient) VirtualizationCreate(ctx context.Context, opts *enginetypes.VirtualizationCreateOptions) (created *enginetypes.VirtualizationCreated, err error) {
-	ID := "SYSTEMD-" + strings.ToLower(utils.RandomString(46))
+	ID := eruUnitIDPrefix + strings.ToLower(utils.RandomString(46))
 
 	cpuAmount, err := s.cpuInfo(ctx)
 	if err != nil {
 		return
 	}
-	buffer, err := s.newUnitBuilder(ID, opts).buildUnit().buildPreExec(cpuAmount).buildExec().buildPostExec().buffer()
+	builder := s.newUnitBuilder(ID, opts)
+	if len(builder.numaNodes) > 0 {
+		if builder.numaCPUs, err = s.numaNodesCPUs(ctx, builder.numaNodes); err != nil {
+			return
+		}
+	}
+	if opts.IOLimit != nil && devicePathPattern.MatchString(opts.IOLimit.Device) {
+		if builder.ioDevice, err = s.blockDevice(ctx, opts.IOLimit.Device); err != nil {
+			return
+		}
+	}
+	if bandwidth := opts.Bandwidth; bandwidth != nil && bandwidth.Egress != "" && interfaceNamePattern.MatchString(bandwidth.Device) {
+		if builder.tcClassMinor, err = s.tcClassMinor(ctx, bandwidth.Device, ID); err != nil {
+			return
+		}
+	}
+	buffer, err := builder.buildUnit().buildPreExec(cpuAmount).buildExec().buildPostExec().buffer()
 	if err != nil {
 		return
 	}
+	if opts.DryRun {
+		return &enginetypes.VirtualizationCreated{
+			ID:       ID,
+			Name:     opts.Name,
+			Rendered: buffer,
+		}, nil
+	}
+
+	// env file must exist before the unit is loaded
+	if useEnvFile(opts) {
+		if err = s.copyPrivateFile(ctx, getEnvFilename(ID), renderEnvFile(envFileEnv(opts))); err != nil {
+			return
+		}
+	}
 
 	// cp - /usr/local/lib/systemd/system/
 	if err = s.VirtualizationCopyTo(ctx, "", getUnitFilename(ID), buffer, true, true); err != nil {
@@ -72,6 +106,16 @@ func (s *SSHClient) VirtualizationCopyTo(ctx context.Context, ID, target string,
 	return errors.Wrap(err, stderr.String())
 }
 
+// copyPrivateFile is like VirtualizationCopyTo but only root can read the file
+func (s *SSHClient) copyPrivateFile(ctx context.Context, target string, content io.Reader) error {
+	dirname, _ := filepath.Split(target)
+	if _, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdMkdir, dirname), nil); err != nil {
+		return errors.Wrap(err, stderr.String())
+	}
+	_, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdPrivateCopy, target), content)
+	return errors.Wrap(err, stderr.String())
+}
+
 // VirtualizationStart starts a systemd service
 func (s *SSHClient) VirtualizationStart(ctx context.Context, ID string) (err error) {
 	// systemctl restart $ID
@@ -93,7 +137,8 @@ func (s *SSHClient) VirtualizationRemove(ctx context.Context, ID string, volumes
 	}
 
 	// rm -f $FILE
-	if _, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdRemove, getUnitFilename(ID)), nil); err != nil {
+	// env file is removed with unit rather than on stop, as restarting still needs it
+	if _, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdRemove, getUnitFilename(ID)+" "+getEnvFilename(ID)), nil); err != nil {
 		return errors.Wrap(err, stderr.String())
 	}
 
@@ -116,7 +161,7 @@ func (s *SSHClient) VirtualizationInspect(ctx context.Context, ID string) (info
 		return
 	}
 
-	labels, err := serviceStatus.labels()
+	labels, err := s.unitLabels(ctx, ID, serviceStatus)
 	if err != nil {
 		return
 	}
@@ -131,9 +176,48 @@ func (s *SSHClient) VirtualizationInspect(ctx context.Context, ID string) (info
 	}, nil
 }
 
-// VirtualizationLogs fetches service logs
+// ListWorkloadIDs lists services by unit files eru created
+func (s *SSHClient) ListWorkloadIDs(ctx context.Context) (IDs []string, err error) {
+	stdout, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdListUnits, eruSystemdUnitPath), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, stderr.String())
+	}
+	return parseUnitIDs(stdout.String()), nil
+}
+
+// unitLabels reads labels from meta of unit file
+// falls back to description for units created before meta introduced
+func (s *SSHClient) unitLabels(ctx context.Context, ID string, serviceStatus *serviceStatus) (map[string]string, error) {
+	stdout, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdCopyToStdout, getUnitFilename(ID)), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, stderr.String())
+	}
+	desc, err := parseUnitMeta(stdout)
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil {
+		return serviceStatus.labels()
+	}
+	return desc.Labels, nil
+}
+
+// VirtualizationLogs streams service logs from journal
+// stdout and stderr are mixed in journal, all lines come from stdout
 func (s *SSHClient) VirtualizationLogs(ctx context.Context, opts *enginetypes.VirtualizationLogStreamOptions) (stdout io.ReadCloser, stderr io.ReadCloser, err error) {
-	err = types.ErrEngineNotImplemented
+	cmd, err := journalCmd(opts, time.Now())
+	if err != nil {
+		return
+	}
+	if stdout, err = s.runStreamCommand(ctx, cmd); err != nil {
+		return
+	}
+	return stdout, ioutil.NopCloser(&bytes.Buffer{}), nil
+}
+
+// VirtualizationStats gets live usage of a service
+func (s *SSHClient) VirtualizationStats(ctx context.Context, ID string) (stats *enginetypes.VirtualizationStats, err error) {
+	err = types.NewDetailedErr(types.ErrEngineUnsupported, enginetypes.CapVirtualizationStats)
 	return
 }
 
@@ -161,6 +245,32 @@ func (s *SSHClient) VirtualizationUpdateResource(ctx context.Context, ID string,
 	return
 }
 
+// VirtualizationUpdateRestartPolicy rewrites restart directives of the unit and reloads
+// the service keeps running, new policy takes effect on next exit
+func (s *SSHClient) VirtualizationUpdateRestartPolicy(ctx context.Context, ID, restartPolicy string) (err error) {
+	builder := s.newUnitBuilder(ID, &enginetypes.VirtualizationCreateOptions{RestartPolicy: restartPolicy})
+	if builder.err != nil {
+		return builder.err
+	}
+	// validated before touching the unit
+	if _, _, err = builder.convertToSystemdRestartPolicy(restartPolicy); err != nil {
+		return
+	}
+	stdout, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdCopyToStdout, getUnitFilename(ID)), nil)
+	if err != nil {
+		return errors.Wrap(err, stderr.String())
+	}
+	buffer, err := builder.rewriteRestartPolicy(stdout.String())
+	if err != nil {
+		return
+	}
+	if err = s.VirtualizationCopyTo(ctx, "", getUnitFilename(ID), buffer, true, true); err != nil {
+		return
+	}
+	_, stderr, err = s.runSingleCommand(ctx, cmdSystemdReload, nil)
+	return errors.Wrap(err, stderr.String())
+}
+
 // VirtualizationCopyFrom copy files from one service to another
 func (s *SSHClient) VirtualizationCopyFrom(ctx context.Context, ID, source string) (reader io.ReadCloser, filename string, err error) {
 	stdout, stderr, err := s.runSingleCommand(ctx, fmt.Sprintf(cmdCopyToStdout, source), nil)
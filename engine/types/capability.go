@@ -0,0 +1,22 @@
+package types
+
+// operations not every engine supports
+const (
+	CapNetworkConnect    = "NetworkConnect"
+	CapNetworkDisconnect = "NetworkDisconnect"
+	CapNetworkList       = "NetworkList"
+	CapNetworkInspect    = "NetworkInspect"
+	CapNetworkCreate     = "NetworkCreate"
+	CapNetworkRemove     = "NetworkRemove"
+
+	CapVirtualizationDryRun = "VirtualizationDryRun"
+	CapVirtualizationStats  = "VirtualizationStats"
+)
+
+// Capabilities is the set of operations supported by engine
+type Capabilities map[string]bool
+
+// Has .
+func (c Capabilities) Has(capability string) bool {
+	return c[capability]
+}
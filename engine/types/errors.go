@@ -0,0 +1,58 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errors for building virtualization
+var (
+	ErrUnsupportedLogType       = errors.New("log type not supported")
+	ErrUnsupportedRestartPolicy = errors.New("restart policy not supported")
+	ErrUnsupportedNetwork       = errors.New("network not supported")
+	ErrInvalidDescription       = errors.New("invalid description")
+	ErrCPUNotOnNUMANode         = errors.New("cpu not on numa node")
+	ErrInvalidNUMANode          = errors.New("invalid numa node")
+	ErrInvalidCPUList           = errors.New("invalid cpu list")
+	ErrInvalidCgroupPath        = errors.New("invalid cgroup path")
+	ErrInvalidIOLimit           = errors.New("invalid io limit")
+	ErrInvalidTmpfs             = errors.New("invalid tmpfs")
+	ErrInvalidLogPath           = errors.New("invalid log path")
+	ErrInvalidUnitName          = errors.New("invalid unit name")
+	ErrInvalidPidsLimit         = errors.New("invalid pids limit")
+	ErrInvalidBandwidth         = errors.New("invalid bandwidth")
+	ErrSSHPoolClosed            = errors.New("ssh pool closed")
+	ErrInvalidLogOptions        = errors.New("invalid log options")
+	ErrInvalidCPUWeight         = errors.New("invalid cpu weight")
+	ErrInvalidMemoryLimit       = errors.New("invalid memory limit")
+	ErrInvalidOOMScoreAdjust    = errors.New("invalid oom score adjust")
+)
+
+// errors for managing network
+var (
+	ErrNetworkNotExists = errors.New("network not exists")
+)
+
+// ResourceValidateError is the validation failure of one resource dimension
+type ResourceValidateError struct {
+	Resource string
+	Reason   string
+}
+
+// Error .
+func (e ResourceValidateError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Resource, e.Reason)
+}
+
+// ResourceValidateErrors aggregates failures of each resource dimension
+type ResourceValidateErrors []ResourceValidateError
+
+// Error .
+func (e ResourceValidateErrors) Error() string {
+	msgs := []string{}
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -1,9 +1,39 @@
 package types
 
+import (
+	"fmt"
+	"strconv"
+)
+
 // Info define info response
 type Info struct {
 	ID           string
 	NCPU         int
 	MemTotal     int64
 	StorageTotal int64
+	Type         string
+	Version      string
+}
+
+// ValidateResource validates each resource dimension against info independently
+func (i *Info) ValidateResource(cpu float64, cpumap map[string]int64, memory, storage int64) error {
+	errs := ResourceValidateErrors{}
+	if cpu > float64(i.NCPU) {
+		errs = append(errs, ResourceValidateError{Resource: "cpu", Reason: fmt.Sprintf("used %f exceeds %d cores", cpu, i.NCPU)})
+	}
+	for core := range cpumap {
+		if id, err := strconv.Atoi(core); err != nil || id < 0 || id >= i.NCPU {
+			errs = append(errs, ResourceValidateError{Resource: "cpu", Reason: fmt.Sprintf("core %s not exists", core)})
+		}
+	}
+	if memory > i.MemTotal {
+		errs = append(errs, ResourceValidateError{Resource: "memory", Reason: fmt.Sprintf("used %d exceeds total %d", memory, i.MemTotal)})
+	}
+	if i.StorageTotal > 0 && storage > i.StorageTotal {
+		errs = append(errs, ResourceValidateError{Resource: "storage", Reason: fmt.Sprintf("used %d exceeds total %d", storage, i.StorageTotal)})
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
 }
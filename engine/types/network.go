@@ -2,6 +2,35 @@ package types
 
 // Network is network info
 type Network struct {
-	Name    string   `json:"name"`
-	Subnets []string `json:"cidr"`
+	Name    string            `json:"name"`
+	Subnets []string          `json:"cidr"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	// nodes which have this network, filled by cluster
+	Nodes []string `json:"nodes,omitempty"`
+
+	// only filled by NetworkInspect
+	ID         string        `json:"id,omitempty"`
+	Driver     string        `json:"driver,omitempty"`
+	IPAM       []*IPAMConfig `json:"ipam,omitempty"`
+	Containers []string      `json:"containers,omitempty"`
+	// addresses of connected endpoints, without prefix length
+	Addresses []string `json:"addresses,omitempty"`
+	// addresses of each connected endpoint
+	Endpoints map[string][]string `json:"endpoints,omitempty"`
+}
+
+// NetworkCreateOptions is options for creating network
+type NetworkCreateOptions struct {
+	Name    string
+	Driver  string
+	Subnet  string
+	Gateway string
+	Labels  map[string]string
+}
+
+// IPAMConfig is ip address management config of a subnet
+type IPAMConfig struct {
+	Subnet  string `json:"subnet"`
+	IPRange string `json:"ip_range,omitempty"`
+	Gateway string `json:"gateway,omitempty"`
 }
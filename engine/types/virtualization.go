@@ -1,17 +1,61 @@
 package types
 
+import (
+	"bytes"
+	"time"
+)
+
 // VirtualizationResource define resources
 type VirtualizationResource struct {
 	CPU           map[string]int64 // for cpu binding
 	Quota         float64          // for cpu quota
+	CPUWeight     int64            // relative cpu share from 1 to 10000, independent of Quota, 0 means default, only supported by systemd engine
 	Memory        int64            // for memory binding
+	MemorySoft    int64            // soft limit, 0 means derived from Memory, only supported by systemd engine
+	MemorySwap    int64            // memory plus swap, same as Memory to disable swap, 0 means unlimited
 	Storage       int64
-	NUMANode      string // numa node
+	NUMANode      string // numa node, or nodes in cpulist format like "0,1"
 	Volumes       []string
 	VolumePlan    map[string]map[string]int64 // literal VolumePlan
 	VolumeChanged bool                        // indicate whether new volumes contained in realloc request
 }
 
+// HealthCheck define liveness probe of virtualization
+type HealthCheck struct {
+	Interval time.Duration // expected keep-alive interval, 0 means disabled
+	Notify   bool          // process sends keep-alive by sd_notify, only supported by systemd engine
+}
+
+// UnitDependencies define startup order against other units on the same host
+// e.g. unit of a sibling workload, only supported by systemd engine
+type UnitDependencies struct {
+	After    []string
+	Requires []string
+	Wants    []string
+	Omit     []string // default targets of engine left out, e.g. firewalld.service
+}
+
+// IOLimit define block io throttle on one device
+type IOLimit struct {
+	Device   string // block device path on host, like /dev/sda
+	ReadBPS  int64  // read bytes per second, 0 means unlimited
+	WriteBPS int64  // write bytes per second, 0 means unlimited
+}
+
+// Bandwidth define network rate limits of a workload on host network
+// ingress can't be told apart by workload on host network, limiting it is rejected, traffic is only accounted
+type Bandwidth struct {
+	Device  string // network interface on host, like eth0
+	Egress  string // human readable bytes per second like 10m, empty means unlimited
+	Ingress string // unsupported yet, must be empty
+}
+
+// Tmpfs define a tmpfs mount
+type Tmpfs struct {
+	Path string // mount point
+	Size string // human readable like 64m, empty means kernel default
+}
+
 // VirtualizationCreateOptions use for create virtualization target
 type VirtualizationCreateOptions struct {
 	VirtualizationResource
@@ -31,7 +75,29 @@ type VirtualizationCreateOptions struct {
 
 	Debug bool
 
-	RestartPolicy string
+	OOMScoreAdjust int // from -1000 to 1000, lower is less likely to be killed by OOM killer
+
+	RestartPolicy   string
+	RestartDelay    time.Duration // delay before restart, 0 means engine default
+	RestartInterval time.Duration // interval for counting restart limit, 0 means engine default
+
+	PreStartCmds [][]string // run in order before Cmd, only supported by systemd engine
+
+	HealthCheck *HealthCheck
+
+	IOLimit   *IOLimit   // only supported by systemd engine
+	PidsLimit int64      // max tasks in the workload, 0 means unlimited, only supported by systemd engine
+	Bandwidth *Bandwidth // only supported by systemd engine with cgroup v1
+
+	DryRun bool // render the config only and apply nothing, see CapVirtualizationDryRun
+
+	EnvFile bool     // keep Env in a file readable by root only, only supported by systemd engine
+	Secrets []string // resolved secret env, always kept in the env file, only supported by systemd engine
+
+	ReadonlyRoot bool    // only supported by systemd engine
+	Tmpfs        []Tmpfs // only supported by systemd engine
+
+	Dependencies *UnitDependencies
 
 	Networks map[string]string
 
@@ -48,8 +114,9 @@ type VirtualizationCreateOptions struct {
 
 // VirtualizationCreated use for store name and ID
 type VirtualizationCreated struct {
-	ID   string
-	Name string
+	ID       string
+	Name     string
+	Rendered *bytes.Buffer // rendered config if dry run
 }
 
 // VirtualizationInfo store virtualization info
@@ -64,6 +131,11 @@ type VirtualizationInfo struct {
 	// TODO other information like cpu memory
 }
 
+// VirtualizationStats is live usage of a virtualization
+type VirtualizationStats struct {
+	CPUUsage float64 // in cores, same unit as CPUQuotaRequest
+}
+
 // VirtualizationWaitResult store exit result
 type VirtualizationWaitResult struct {
 	Message string
@@ -56,6 +56,14 @@ func MakeClient(ctx context.Context, config coretypes.Config, nodename, endpoint
 	return &Virt{cli, config}, nil
 }
 
+// Capabilities only connecting and disconnecting network are supported
+func (v *Virt) Capabilities() enginetypes.Capabilities {
+	return enginetypes.Capabilities{
+		enginetypes.CapNetworkConnect:    true,
+		enginetypes.CapNetworkDisconnect: true,
+	}
+}
+
 // Info shows a connected node's information.
 func (v *Virt) Info(ctx context.Context) (*enginetypes.Info, error) {
 	resp, err := v.client.Info(ctx)
@@ -68,6 +76,7 @@ func (v *Virt) Info(ctx context.Context) (*enginetypes.Info, error) {
 		NCPU:         resp.CPU,
 		MemTotal:     resp.Mem,
 		StorageTotal: resp.Storage,
+		Type:         "virt",
 	}, nil
 }
 
@@ -128,11 +137,27 @@ func (v *Virt) NetworkDisconnect(ctx context.Context, network, target string, fo
 }
 
 // NetworkList lists all of networks.
-func (v *Virt) NetworkList(ctx context.Context, drivers []string) (nets []*enginetypes.Network, err error) {
+func (v *Virt) NetworkList(ctx context.Context, drivers []string, labels map[string]string) (nets []*enginetypes.Network, err error) {
 	log.Warnf("NetworkList does not implement")
 	return
 }
 
+// NetworkInspect inspects a network.
+func (v *Virt) NetworkInspect(ctx context.Context, network string) (n *enginetypes.Network, err error) {
+	log.Warnf("NetworkInspect does not implement")
+	return
+}
+
+// NetworkCreate creates a network.
+func (v *Virt) NetworkCreate(ctx context.Context, opts *enginetypes.NetworkCreateOptions) (string, error) {
+	return "", fmt.Errorf("NetworkCreate does not implement")
+}
+
+// NetworkRemove removes a network.
+func (v *Virt) NetworkRemove(ctx context.Context, network string) error {
+	return fmt.Errorf("NetworkRemove does not implement")
+}
+
 // BuildRefs builds references, it's not necessary for virt. presently.
 func (v *Virt) BuildRefs(ctx context.Context, name string, tags []string) (refs []string) {
 	log.Warnf("BuildRefs does not implement")
@@ -146,6 +171,9 @@ func (v *Virt) BuildContent(ctx context.Context, scm coresource.Source, opts *en
 
 // VirtualizationCreate creates a guest.
 func (v *Virt) VirtualizationCreate(ctx context.Context, opts *enginetypes.VirtualizationCreateOptions) (guest *enginetypes.VirtualizationCreated, err error) {
+	if opts.DryRun {
+		return nil, coretypes.NewDetailedErr(coretypes.ErrEngineUnsupported, enginetypes.CapVirtualizationDryRun)
+	}
 	vols, err := v.parseVolumes(opts.Volumes)
 	if err != nil {
 		return nil, err
@@ -217,6 +245,11 @@ func (v *Virt) VirtualizationInspect(ctx context.Context, ID string) (*enginetyp
 	}, nil
 }
 
+// VirtualizationStats is not supported
+func (v *Virt) VirtualizationStats(ctx context.Context, ID string) (*enginetypes.VirtualizationStats, error) {
+	return nil, coretypes.NewDetailedErr(coretypes.ErrEngineUnsupported, enginetypes.CapVirtualizationStats)
+}
+
 // VirtualizationLogs streams a specific guest's log.
 func (v *Virt) VirtualizationLogs(ctx context.Context, opts *enginetypes.VirtualizationLogStreamOptions) (stdout io.ReadCloser, stderr io.ReadCloser, err error) {
 	return nil, nil, fmt.Errorf("VirtualizationLogs does not implement")
@@ -237,6 +270,11 @@ func (v *Virt) VirtualizationWait(ctx context.Context, ID, state string) (*engin
 	return nil, fmt.Errorf("VirtualizationWait does not implement")
 }
 
+// VirtualizationUpdateRestartPolicy updates restart policy.
+func (v *Virt) VirtualizationUpdateRestartPolicy(ctx context.Context, ID, restartPolicy string) error {
+	return fmt.Errorf("VirtualizationUpdateRestartPolicy does not implement")
+}
+
 // VirtualizationUpdateResource updates resource.
 func (v *Virt) VirtualizationUpdateResource(ctx context.Context, ID string, opts *enginetypes.VirtualizationResource) error {
 	vols, err := v.parseVolumes(opts.Volumes)
@@ -269,8 +307,13 @@ func (v *Virt) VirtualizationExecute(ctx context.Context, ID string, commands, e
 	return nil, nil, fmt.Errorf("VirtualizationExecute not implemented")
 }
 
+// ListWorkloadIDs lists guests, yavirtd can't list them yet
+func (v *Virt) ListWorkloadIDs(ctx context.Context) ([]string, error) {
+	return nil, coretypes.ErrEngineNotImplemented
+}
+
 // ResourceValidate validate resource usage
-func (v *Virt) ResourceValidate(ctx context.Context, cpu float64, cpumap map[string]int64, memory, storage int64) error {
+func (v *Virt) ResourceValidate(ctx context.Context, info *enginetypes.Info, cpu float64, cpumap map[string]int64, memory, storage int64) error {
 	// TODO list all workloads, calcuate resource
-	return nil
+	return coretypes.ErrEngineNotImplemented
 }
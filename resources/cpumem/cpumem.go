@@ -5,6 +5,7 @@ import (
 	resourcetypes "github.com/projecteru2/core/resources/types"
 	"github.com/projecteru2/core/scheduler"
 	"github.com/projecteru2/core/types"
+	"github.com/projecteru2/core/utils"
 )
 
 type cpuMemRequest struct {
@@ -15,6 +16,7 @@ type cpuMemRequest struct {
 
 	memoryRequest int64
 	memoryLimit   int64
+	numaNode      string
 }
 
 // MakeRequest .
@@ -26,6 +28,7 @@ func MakeRequest(opts types.ResourceOptions) (resourcetypes.ResourceRequest, err
 		memoryRequest:   opts.MemoryRequest,
 		memoryLimit:     opts.MemoryLimit,
 		CPU:             opts.CPU,
+		numaNode:        opts.NUMANode,
 	}
 	return cmr, cmr.Validate()
 }
@@ -80,6 +83,12 @@ func (cm cpuMemRequest) MakeScheduler() resourcetypes.SchedulerV2 {
 			return
 		}
 
+		if cm.numaNode != "" {
+			if scheduleInfos = numaScheduleInfos(scheduleInfos, cm.numaNode); len(scheduleInfos) == 0 {
+				return nil, 0, errors.Wrapf(types.ErrInsufficientMEM, "no node has numa node %s", cm.numaNode)
+			}
+		}
+
 		var CPUPlans map[string][]types.CPUMap
 		switch {
 		case !cm.CPUBind || cm.CPUQuotaRequest == 0:
@@ -95,11 +104,37 @@ func (cm cpuMemRequest) MakeScheduler() resourcetypes.SchedulerV2 {
 			CPUQuotaRequest: cm.CPUQuotaRequest,
 			CPUQuotaLimit:   cm.CPUQuotaLimit,
 			CPUPlans:        CPUPlans,
+			numaNode:        cm.numaNode,
 			capacity:        resourcetypes.GetCapacity(scheduleInfos),
 		}, total, err
 	}
 }
 
+// numaScheduleInfos narrows nodes down to the numa node, nodes without it are dropped
+// memory of other numa nodes is not usable, so is aggregate memory beyond the numa node
+func numaScheduleInfos(scheduleInfos []resourcetypes.ScheduleInfo, numaNode string) []resourcetypes.ScheduleInfo {
+	numaInfos := []resourcetypes.ScheduleInfo{}
+	for _, scheduleInfo := range scheduleInfos {
+		memory, ok := scheduleInfo.NUMAMemory[numaNode]
+		if !ok {
+			continue
+		}
+		numaInfo := scheduleInfo
+		numaInfo.MemCap = utils.Min64(scheduleInfo.MemCap, memory)
+		numaInfo.NUMAMemory = types.NUMAMemory{numaNode: memory}
+		numaInfo.CPU = types.CPUMap{}
+		numaInfo.NUMA = types.NUMA{}
+		for cpuID, pieces := range scheduleInfo.CPU {
+			if scheduleInfo.NUMA[cpuID] == numaNode {
+				numaInfo.CPU[cpuID] = pieces
+				numaInfo.NUMA[cpuID] = numaNode
+			}
+		}
+		numaInfos = append(numaInfos, numaInfo)
+	}
+	return numaInfos
+}
+
 // Rate for global strategy
 func (cm cpuMemRequest) Rate(node types.Node) float64 {
 	if cm.CPUBind {
@@ -116,6 +151,7 @@ type ResourcePlans struct {
 	CPUQuotaRequest float64
 	CPUQuotaLimit   float64
 	CPUPlans        map[string][]types.CPUMap
+	numaNode        string
 
 	capacity map[string]int
 }
@@ -142,6 +178,9 @@ func (rp ResourcePlans) ApplyChangesOnNode(node *types.Node, indices ...int) {
 		}
 	}
 	node.MemCap -= rp.memoryRequest * int64(len(indices))
+	if rp.numaNode != "" {
+		node.DecrNUMANodeMemory(rp.numaNode, rp.memoryRequest*int64(len(indices)))
+	}
 	node.SetCPUUsed(rp.CPUQuotaRequest*float64(len(indices)), types.IncrUsage)
 }
 
@@ -153,6 +192,9 @@ func (rp ResourcePlans) RollbackChangesOnNode(node *types.Node, indices ...int)
 		}
 	}
 	node.MemCap += rp.memoryRequest * int64(len(indices))
+	if rp.numaNode != "" {
+		node.IncrNUMANodeMemory(rp.numaNode, rp.memoryRequest*int64(len(indices)))
+	}
 	node.SetCPUUsed(rp.CPUQuotaRequest*float64(len(indices)), types.DecrUsage)
 }
 
@@ -165,6 +207,9 @@ func (rp ResourcePlans) Dispense(opts resourcetypes.DispenseOptions, r *types.Re
 	r.CPUQuotaRequest = rp.CPUQuotaRequest
 	r.MemoryLimit = rp.memoryLimit
 	r.MemoryRequest = rp.memoryRequest
+	if rp.numaNode != "" {
+		r.NUMANode = rp.numaNode
+	}
 
 	if len(rp.CPUPlans) > 0 {
 		if p, ok := rp.CPUPlans[opts.Node.Name]; !ok || len(p) <= opts.Index {
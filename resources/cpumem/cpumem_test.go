@@ -5,6 +5,7 @@ import (
 
 	resourcetypes "github.com/projecteru2/core/resources/types"
 	"github.com/projecteru2/core/scheduler"
+	complexscheduler "github.com/projecteru2/core/scheduler/complex"
 	schedulerMocks "github.com/projecteru2/core/scheduler/mocks"
 	"github.com/projecteru2/core/types"
 
@@ -306,3 +307,68 @@ func (test *requestMemNodeTest) assertAfterChanges(t *testing.T) {
 func (test *requestMemNodeTest) assertAfterRollback(t *testing.T) {
 	assert.Equal(t, test.node.CPU["0"], int64(10000))
 }
+
+func TestRequestNUMANode(t *testing.T) {
+	potassium, _ := complexscheduler.New(types.Config{Scheduler: types.SchedConfig{MaxShare: -1, ShareBase: 100}})
+	prevSche, _ := scheduler.GetSchedulerV1()
+	scheduler.InitSchedulerV1(potassium)
+	defer scheduler.InitSchedulerV1(prevSche)
+
+	newScheduleInfos := func() []resourcetypes.ScheduleInfo {
+		return []resourcetypes.ScheduleInfo{
+			{NodeMeta: types.NodeMeta{
+				Name:       "n1",
+				CPU:        types.CPUMap{"0": 100, "1": 100, "2": 100, "3": 100},
+				NUMA:       types.NUMA{"0": "0", "1": "0", "2": "1", "3": "1"},
+				NUMAMemory: types.NUMAMemory{"0": 100, "1": 1000},
+				MemCap:     1100,
+			}},
+			{NodeMeta: types.NodeMeta{
+				Name:   "n2",
+				CPU:    types.CPUMap{"0": 100},
+				MemCap: 1100,
+			}},
+		}
+	}
+
+	// aggregate memory if not pinned
+	req, err := MakeRequest(types.ResourceOptions{MemoryRequest: 100})
+	assert.NoError(t, err)
+	plans, total, err := req.MakeScheduler()(newScheduleInfos())
+	assert.NoError(t, err)
+	assert.Equal(t, 22, total)
+
+	// only memory of numa node 0, nodes without it are dropped
+	req, err = MakeRequest(types.ResourceOptions{MemoryRequest: 100, NUMANode: "0"})
+	assert.NoError(t, err)
+	plans, total, err = req.MakeScheduler()(newScheduleInfos())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, map[string]int{"n1": 1}, plans.Capacity())
+	node := &types.Node{NodeMeta: newScheduleInfos()[0].NodeMeta}
+	plans.ApplyChangesOnNode(node, 0)
+	assert.EqualValues(t, 0, node.NUMAMemory["0"])
+	assert.EqualValues(t, 1000, node.MemCap)
+	r, err := plans.Dispense(resourcetypes.DispenseOptions{Node: node}, &types.ResourceMeta{})
+	assert.NoError(t, err)
+	assert.Equal(t, "0", r.NUMANode)
+	plans.RollbackChangesOnNode(node, 0)
+	assert.EqualValues(t, 100, node.NUMAMemory["0"])
+
+	// bound cpus are taken from numa node 1
+	req, err = MakeRequest(types.ResourceOptions{CPUQuotaRequest: 1, CPUBind: true, MemoryRequest: 100, NUMANode: "1"})
+	assert.NoError(t, err)
+	plans, total, err = req.MakeScheduler()(newScheduleInfos())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	for i := 0; i < total; i++ {
+		r, err = plans.Dispense(resourcetypes.DispenseOptions{Node: node, Index: i}, &types.ResourceMeta{})
+		assert.NoError(t, err)
+		assert.Equal(t, "1", r.NUMANode)
+	}
+
+	req, err = MakeRequest(types.ResourceOptions{MemoryRequest: 100, NUMANode: "2"})
+	assert.NoError(t, err)
+	_, _, err = req.MakeScheduler()(newScheduleInfos())
+	assert.True(t, errors.Is(err, types.ErrInsufficientMEM))
+}
@@ -10,6 +10,7 @@ import (
 type storageRequest struct {
 	request int64
 	limit   int64
+	pool    string
 }
 
 // MakeRequest .
@@ -17,6 +18,7 @@ func MakeRequest(opts types.ResourceOptions) (resourcetypes.ResourceRequest, err
 	sr := &storageRequest{
 		request: opts.StorageRequest,
 		limit:   opts.StorageLimit,
+		pool:    opts.StoragePool,
 	}
 	return sr, sr.Validate()
 }
@@ -48,15 +50,37 @@ func (s storageRequest) MakeScheduler() resourcetypes.SchedulerV2 {
 			return
 		}
 
+		if s.pool != "" {
+			if scheduleInfos = s.selectPoolNodes(scheduleInfos); len(scheduleInfos) == 0 {
+				return nil, 0, errors.Wrapf(types.ErrInsufficientStorage, "no node has storage pool %s", s.pool)
+			}
+		}
 		scheduleInfos, total, err = schedulerV1.SelectStorageNodes(scheduleInfos, s.request)
 		return ResourcePlans{
 			request:  s.request,
 			limit:    s.limit,
+			pool:     s.pool,
 			capacity: resourcetypes.GetCapacity(scheduleInfos),
 		}, total, err
 	}
 }
 
+// selectPoolNodes keeps nodes having the pool, bounded by what's left in it
+func (s storageRequest) selectPoolNodes(scheduleInfos []resourcetypes.ScheduleInfo) []resourcetypes.ScheduleInfo {
+	selected := []resourcetypes.ScheduleInfo{}
+	for _, scheduleInfo := range scheduleInfos {
+		free, ok := scheduleInfo.StoragePools[s.pool]
+		if !ok {
+			continue
+		}
+		if free < scheduleInfo.StorageCap {
+			scheduleInfo.StorageCap = free
+		}
+		selected = append(selected, scheduleInfo)
+	}
+	return selected
+}
+
 // Rate .
 func (s storageRequest) Rate(node types.Node) float64 {
 	return float64(s.request) / float64(node.InitStorageCap)
@@ -66,6 +90,7 @@ func (s storageRequest) Rate(node types.Node) float64 {
 type ResourcePlans struct {
 	request  int64
 	limit    int64
+	pool     string
 	capacity map[string]int
 }
 
@@ -82,11 +107,17 @@ func (rp ResourcePlans) Capacity() map[string]int {
 // ApplyChangesOnNode .
 func (rp ResourcePlans) ApplyChangesOnNode(node *types.Node, indices ...int) {
 	node.StorageCap -= int64(len(indices)) * rp.request
+	if rp.pool != "" {
+		node.DecrStoragePool(rp.pool, int64(len(indices))*rp.request)
+	}
 }
 
 // RollbackChangesOnNode .
 func (rp ResourcePlans) RollbackChangesOnNode(node *types.Node, indices ...int) {
 	node.StorageCap += int64(len(indices)) * rp.request
+	if rp.pool != "" {
+		node.IncrStoragePool(rp.pool, int64(len(indices))*rp.request)
+	}
 }
 
 // Dispense .
@@ -96,5 +127,6 @@ func (rp ResourcePlans) Dispense(opts resourcetypes.DispenseOptions, r *types.Re
 	}
 	r.StorageLimit = rp.limit
 	r.StorageRequest = rp.request
+	r.StoragePool = rp.pool
 	return r, nil
 }
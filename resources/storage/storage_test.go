@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"testing"
 
 	resourcetypes "github.com/projecteru2/core/resources/types"
@@ -133,3 +134,42 @@ func TestStorage(t *testing.T) {
 	_, err = plans.Dispense(opts, r)
 	assert.EqualError(t, err, "cannot alloc a each node plan, not enough capacity")
 }
+
+func TestStoragePool(t *testing.T) {
+	mockScheduler := &schedulerMocks.Scheduler{}
+	prevSche, _ := scheduler.GetSchedulerV1()
+	scheduler.InitSchedulerV1(mockScheduler)
+	defer func() {
+		scheduler.InitSchedulerV1(prevSche)
+	}()
+	scheduleInfos := []resourcetypes.ScheduleInfo{
+		{NodeMeta: types.NodeMeta{Name: "n1", StorageCap: 10240, StoragePools: types.StoragePools{"ssd": 2048}}, Capacity: 2},
+		{NodeMeta: types.NodeMeta{Name: "n2", StorageCap: 10240}},
+	}
+	// only n1 is left, bounded by its pool
+	mockScheduler.On("SelectStorageNodes", mock.MatchedBy(func(infos []resourcetypes.ScheduleInfo) bool {
+		return len(infos) == 1 && infos[0].Name == "n1" && infos[0].StorageCap == 2048
+	}), int64(1024)).Return(scheduleInfos[:1], 2, nil)
+
+	resourceRequest, err := MakeRequest(types.ResourceOptions{StorageRequest: 1024, StoragePool: "ssd"})
+	assert.NoError(t, err)
+	plans, total, err := resourceRequest.MakeScheduler()(scheduleInfos)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, int64(10240), scheduleInfos[0].StorageCap)
+
+	node := types.Node{NodeMeta: types.NodeMeta{Name: "n1", StorageCap: 10240, StoragePools: types.StoragePools{"ssd": 2048}}}
+	plans.ApplyChangesOnNode(&node, 0, 1)
+	assert.Equal(t, int64(8192), node.StorageCap)
+	assert.Equal(t, int64(0), node.StoragePools["ssd"])
+	plans.RollbackChangesOnNode(&node, 0)
+	assert.Equal(t, int64(1024), node.StoragePools["ssd"])
+	r, err := plans.Dispense(resourcetypes.DispenseOptions{Node: &node}, &types.ResourceMeta{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ssd", r.StoragePool)
+
+	resourceRequest, err = MakeRequest(types.ResourceOptions{StorageRequest: 1024, StoragePool: "nvme"})
+	assert.NoError(t, err)
+	_, _, err = resourceRequest.MakeScheduler()(scheduleInfos)
+	assert.True(t, errors.Is(err, types.ErrInsufficientStorage))
+}
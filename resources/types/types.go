@@ -81,4 +81,6 @@ type ScheduleInfo struct {
 	CPUPlan     []types.CPUMap
 	VolumePlans []types.VolumePlan // {{"AUTO:/data:rw:1024": "/mnt0:/data:rw:1024"}}
 	Capacity    int                // 可以部署几个
+	// which resource limits the capacity
+	LimitingFactor string
 }
@@ -1,10 +1,43 @@
 package types
 
+import "math"
+
+// limiting factors of capacity
+const (
+	LimitedByCPU     = "cpu"
+	LimitedByMemory  = "memory"
+	LimitedByStorage = "storage"
+	LimitedByVolume  = "volume"
+)
+
+// CapacityDetail .
+type CapacityDetail struct {
+	Total          int
+	Capacity       map[string]int
+	LimitingFactor map[string]string
+}
+
 // GetCapacity .
 func GetCapacity(scheduleInfos []ScheduleInfo) map[string]int {
-	capacity := make(map[string]int)
+	return GetCapacityDetail(scheduleInfos).Capacity
+}
+
+// GetCapacityDetail returns capacity of each node with the total
+// and which resource limits the capacity
+func GetCapacityDetail(scheduleInfos []ScheduleInfo) *CapacityDetail {
+	detail := &CapacityDetail{
+		Capacity:       make(map[string]int),
+		LimitingFactor: make(map[string]string),
+	}
 	for _, scheduleInfo := range scheduleInfos {
-		capacity[scheduleInfo.Name] = scheduleInfo.Capacity
+		detail.Capacity[scheduleInfo.Name] = scheduleInfo.Capacity
+		detail.LimitingFactor[scheduleInfo.Name] = scheduleInfo.LimitingFactor
+		// unlimited capacity is MaxInt64
+		if detail.Total > math.MaxInt64-scheduleInfo.Capacity {
+			detail.Total = math.MaxInt64
+			continue
+		}
+		detail.Total += scheduleInfo.Capacity
 	}
-	return capacity
+	return detail
 }
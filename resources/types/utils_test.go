@@ -1,6 +1,7 @@
 package types
 
 import (
+	"math"
 	"testing"
 
 	"github.com/projecteru2/core/types"
@@ -16,3 +17,19 @@ func TestGetCapacity(t *testing.T) {
 	assert.Equal(t, r["1"], 1)
 	assert.Equal(t, r["2"], 1)
 }
+
+func TestGetCapacityDetail(t *testing.T) {
+	nodesInfo := []ScheduleInfo{
+		{NodeMeta: types.NodeMeta{Name: "1"}, Capacity: 1, LimitingFactor: LimitedByCPU},
+		{NodeMeta: types.NodeMeta{Name: "2"}, Capacity: 2, LimitingFactor: LimitedByMemory},
+	}
+	r := GetCapacityDetail(nodesInfo)
+	assert.Equal(t, r.Total, 3)
+	assert.Equal(t, r.Capacity, map[string]int{"1": 1, "2": 2})
+	assert.Equal(t, r.LimitingFactor, map[string]string{"1": LimitedByCPU, "2": LimitedByMemory})
+
+	// unlimited
+	nodesInfo = append(nodesInfo, ScheduleInfo{NodeMeta: types.NodeMeta{Name: "3"}, Capacity: math.MaxInt64})
+	r = GetCapacityDetail(nodesInfo)
+	assert.Equal(t, r.Total, math.MaxInt64)
+}
@@ -58,7 +58,7 @@ func (v *Vibranium) WatchServiceStatus(_ *pb.Empty, stream pb.CoreRPC_WatchServi
 
 // ListNetworks list networks for pod
 func (v *Vibranium) ListNetworks(ctx context.Context, opts *pb.ListNetworkOptions) (*pb.Networks, error) {
-	networks, err := v.cluster.ListNetworks(ctx, opts.Podname, opts.Driver)
+	networks, _, err := v.cluster.ListNetworks(ctx, &types.ListNetworksOptions{Podname: opts.Podname, Driver: opts.Driver})
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +81,7 @@ func (v *Vibranium) ConnectNetwork(ctx context.Context, opts *pb.ConnectNetworkO
 
 // DisconnectNetwork disconnect network
 func (v *Vibranium) DisconnectNetwork(ctx context.Context, opts *pb.DisconnectNetworkOptions) (*pb.Empty, error) {
-	return &pb.Empty{}, v.cluster.DisconnectNetwork(ctx, opts.Network, opts.Target, opts.Force)
+	return &pb.Empty{}, v.cluster.DisconnectNetwork(ctx, opts.Network, opts.Target, opts.Force, 0)
 }
 
 // AddPod saves a pod, and returns it to client
@@ -126,7 +126,7 @@ func (v *Vibranium) ListPods(ctx context.Context, _ *pb.Empty) (*pb.Pods, error)
 
 // GetPodResource get pod nodes resource usage
 func (v *Vibranium) GetPodResource(ctx context.Context, opts *pb.GetPodOptions) (*pb.PodResource, error) {
-	r, err := v.cluster.PodResource(ctx, opts.Name)
+	r, err := v.cluster.PodResource(ctx, opts.Name, false)
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +226,7 @@ func (v *Vibranium) NodeStatusStream(_ *pb.Empty, stream pb.CoreRPC_NodeStatusSt
 
 // GetNodeResource check node resource
 func (v *Vibranium) GetNodeResource(ctx context.Context, opts *pb.GetNodeResourceOptions) (*pb.NodeResource, error) {
-	nr, err := v.cluster.NodeResource(ctx, opts.GetOpts().Nodename, opts.Fix)
+	nr, err := v.cluster.NodeResource(ctx, opts.GetOpts().Nodename, &types.NodeResourceOptions{Fix: opts.Fix})
 	if err != nil {
 		return nil, err
 	}
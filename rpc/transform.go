@@ -81,13 +81,18 @@ func toRPCNode(ctx context.Context, n *types.Node) *pb.Node {
 }
 
 func toRPCNodeResource(nr *types.NodeResource) *pb.NodeResource {
+	// no field for residual diffs in pb, marked in diffs instead
+	diffs := append([]string{}, nr.Diffs...)
+	for _, diff := range nr.ResidualDiffs {
+		diffs = append(diffs, "residual after fix: "+diff)
+	}
 	return &pb.NodeResource{
 		Name:           nr.Name,
 		CpuPercent:     nr.CPUPercent,
 		MemoryPercent:  nr.MemoryPercent,
 		StoragePercent: nr.StoragePercent,
 		VolumePercent:  nr.VolumePercent,
-		Diffs:          nr.Diffs,
+		Diffs:          diffs,
 	}
 }
 
@@ -25,6 +25,7 @@ func cpuPriorPlan(cpu float64, memory int64, scheduleInfos []resourcetypes.Sched
 	volTotal := 0
 
 	for p, scheduleInfo := range scheduleInfos {
+		nodeShare := scheduleInfo.GetShareBase(coreShare)
 		// 统计全局 CPU，为非 numa 或者跨 numa 计算
 		globalCPUMap := scheduleInfo.CPU
 		// 统计全局 Memory
@@ -47,12 +48,12 @@ func cpuPriorPlan(cpu float64, memory int64, scheduleInfos []resourcetypes.Sched
 			if !ok {
 				continue
 			}
-			cap, plan := calculateCPUPlan(nodeCPUMap, nodeMemCap, cpu, memory, maxShareCore, coreShare)
+			cap, plan, limitedByMemory := calculateCPUPlan(nodeCPUMap, nodeMemCap, cpu, memory, maxShareCore, nodeShare)
 			if cap > 0 {
 				if _, ok := nodeWorkload[scheduleInfo.Name]; !ok {
 					nodeWorkload[scheduleInfo.Name] = []types.CPUMap{}
 				}
-				volTotal += updateScheduleInfoCapacity(&scheduleInfos[p], cap)
+				volTotal += updateScheduleInfoCapacity(&scheduleInfos[p], cap, limitingFactor(limitedByMemory))
 				globalMemCap -= int64(cap) * memory
 				for _, cpuPlan := range plan {
 					globalCPUMap.Sub(cpuPlan)
@@ -63,12 +64,13 @@ func cpuPriorPlan(cpu float64, memory int64, scheduleInfos []resourcetypes.Sched
 		}
 		// 非 numa
 		// 或者是扣掉 numa 分配后剩下的资源里面
-		cap, plan := calculateCPUPlan(globalCPUMap, globalMemCap, cpu, memory, maxShareCore, coreShare)
+		cap, plan, limitedByMemory := calculateCPUPlan(globalCPUMap, globalMemCap, cpu, memory, maxShareCore, nodeShare)
 		if cap > 0 {
 			if _, ok := nodeWorkload[scheduleInfo.Name]; !ok {
 				nodeWorkload[scheduleInfo.Name] = []types.CPUMap{}
 			}
 			scheduleInfos[p].Capacity += cap
+			scheduleInfos[p].LimitingFactor = limitingFactor(limitedByMemory)
 			volTotal += cap
 			nodeWorkload[scheduleInfo.Name] = append(nodeWorkload[scheduleInfo.Name], plan...)
 		}
@@ -85,7 +87,8 @@ func cpuPriorPlan(cpu float64, memory int64, scheduleInfos []resourcetypes.Sched
 	return scheduleInfos[p:], nodeWorkload, volTotal, nil
 }
 
-func calculateCPUPlan(CPUMap types.CPUMap, MemCap int64, cpu float64, memory int64, maxShareCore, coreShare int) (int, []types.CPUMap) {
+// calculateCPUPlan also reports whether the plan is cut by memory instead of cpu
+func calculateCPUPlan(CPUMap types.CPUMap, MemCap int64, cpu float64, memory int64, maxShareCore, coreShare int) (int, []types.CPUMap, bool) {
 	host := newHost(CPUMap, coreShare)
 	plan := host.distributeOneRation(cpu, maxShareCore)
 	memLimit := math.MaxInt64
@@ -93,12 +96,21 @@ func calculateCPUPlan(CPUMap types.CPUMap, MemCap int64, cpu float64, memory int
 		memLimit = int(MemCap / memory)
 	}
 	cap := len(plan) // 每个node可以放的容器数
+	limitedByMemory := false
 	if cap > memLimit {
 		plan = plan[:memLimit]
 		cap = memLimit
+		limitedByMemory = true
 	}
 	if cap <= 0 {
 		plan = nil
 	}
-	return cap, plan
+	return cap, plan, limitedByMemory
+}
+
+func limitingFactor(limitedByMemory bool) string {
+	if limitedByMemory {
+		return resourcetypes.LimitedByMemory
+	}
+	return resourcetypes.LimitedByCPU
 }
@@ -40,6 +40,50 @@ func TestCPUPriorPlan(t *testing.T) {
 	assert.Equal(t, total, 3)
 }
 
+func TestCPUPriorPlanLimitingFactor(t *testing.T) {
+	// 4 cores fit 4 workloads, memory only 2
+	scheduleInfos := []resourcetypes.ScheduleInfo{{
+		NodeMeta: types.NodeMeta{Name: "n1", CPU: types.CPUMap{"0": 100, "1": 100, "2": 100, "3": 100}, MemCap: 2 * int64(units.GiB)},
+	}}
+	scheduleInfos, _, total, err := cpuPriorPlan(1, int64(units.GiB), scheduleInfos, -1, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, resourcetypes.LimitedByMemory, scheduleInfos[0].LimitingFactor)
+
+	// memory fits 4 workloads, cores only 2
+	scheduleInfos = []resourcetypes.ScheduleInfo{{
+		NodeMeta: types.NodeMeta{Name: "n1", CPU: types.CPUMap{"0": 100, "1": 100}, MemCap: 4 * int64(units.GiB)},
+	}}
+	scheduleInfos, _, total, err = cpuPriorPlan(1, int64(units.GiB), scheduleInfos, -1, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, resourcetypes.LimitedByCPU, scheduleInfos[0].LimitingFactor)
+
+	// numa node cut by its own memory
+	scheduleInfos = resetscheduleInfos()
+	scheduleInfos[0].NUMAMemory["node0"] = int64(units.MiB)
+	scheduleInfos[0].NUMAMemory["node1"] = int64(units.MiB)
+	scheduleInfos[0].MemCap = 2 * int64(units.MiB)
+	_, _, _, err = cpuPriorPlan(1, int64(units.MiB), scheduleInfos, -1, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, resourcetypes.LimitedByMemory, scheduleInfos[0].LimitingFactor)
+}
+
+func TestCPUPriorPlanNodeShareBase(t *testing.T) {
+	// a core of node is 10 pieces instead of 100
+	scheduleInfos := []resourcetypes.ScheduleInfo{{
+		NodeMeta: types.NodeMeta{Name: "n1", CPU: types.CPUMap{"0": 10, "1": 10}, MemCap: int64(units.GiB), ShareBase: 10},
+	}}
+	_, resultCPUPlan, total, err := cpuPriorPlan(1, int64(units.MiB), scheduleInfos, -1, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	// a whole core, either one
+	assert.Len(t, resultCPUPlan["n1"][0], 1)
+	for _, pieces := range resultCPUPlan["n1"][0] {
+		assert.Equal(t, int64(10), pieces)
+	}
+}
+
 func resetscheduleInfos() []resourcetypes.ScheduleInfo {
 	return []resourcetypes.ScheduleInfo{
 		{
@@ -64,7 +64,7 @@ func (m *Potassium) SelectStorageNodes(scheduleInfos []resourcetypes.ScheduleInf
 	total := 0
 	for i := range scheduleInfos {
 		storCap := int(scheduleInfos[i].StorageCap / storage)
-		total += updateScheduleInfoCapacity(&scheduleInfos[i], storCap)
+		total += updateScheduleInfoCapacity(&scheduleInfos[i], storCap, resourcetypes.LimitedByStorage)
 	}
 
 	return scheduleInfos, total, nil
@@ -104,6 +104,7 @@ func (m *Potassium) SelectMemoryNodes(scheduleInfos []resourcetypes.ScheduleInfo
 		}
 		volTotal += capacity
 		scheduleInfos[i].Capacity = capacity
+		scheduleInfos[i].LimitingFactor = resourcetypes.LimitedByMemory
 	}
 	return scheduleInfos, volTotal, nil
 }
@@ -125,13 +126,14 @@ func (m *Potassium) SelectCPUNodes(scheduleInfos []resourcetypes.ScheduleInfo, q
 func (m *Potassium) ReselectCPUNodes(scheduleInfo resourcetypes.ScheduleInfo, CPU types.CPUMap, quota float64, memory int64) (resourcetypes.ScheduleInfo, map[string][]types.CPUMap, int, error) {
 	var affinityPlan types.CPUMap
 	// remaining quota that's impossible to achieve affinity
-	if scheduleInfo, quota, affinityPlan = cpuReallocPlan(scheduleInfo, quota, CPU, int64(m.sharebase)); quota == 0 {
+	if scheduleInfo, quota, affinityPlan = cpuReallocPlan(scheduleInfo, quota, CPU, int64(scheduleInfo.GetShareBase(m.sharebase))); quota == 0 {
 		cpuPlans := map[string][]types.CPUMap{
 			scheduleInfo.Name: {
 				affinityPlan,
 			},
 		}
 		scheduleInfo.Capacity = 1
+		scheduleInfo.LimitingFactor = resourcetypes.LimitedByCPU
 		return scheduleInfo, cpuPlans, 1, nil
 	}
 
@@ -247,7 +249,7 @@ func (m *Potassium) SelectVolumeNodes(scheduleInfos []resourcetypes.ScheduleInfo
 	volumePlans := map[string][]types.VolumePlan{}
 	for idx, scheduleInfo := range scheduleInfos {
 		if len(scheduleInfo.Volume) == 0 {
-			volTotal += updateScheduleInfoCapacity(&scheduleInfos[idx], 0)
+			volTotal += updateScheduleInfoCapacity(&scheduleInfos[idx], 0, resourcetypes.LimitedByVolume)
 			continue
 		}
 
@@ -259,7 +261,7 @@ func (m *Potassium) SelectVolumeNodes(scheduleInfos []resourcetypes.ScheduleInfo
 		capNorm, plansNorm := calculateVolumePlan(usedVolumeMap, reqsNorm)
 		capMono, plansMono := calculateMonopolyVolumePlan(scheduleInfo.InitVolume, unusedVolumeMap, reqsMono)
 
-		volTotal += updateScheduleInfoCapacity(&scheduleInfos[idx], utils.Min(capNorm, capMono))
+		volTotal += updateScheduleInfoCapacity(&scheduleInfos[idx], utils.Min(capNorm, capMono), resourcetypes.LimitedByVolume)
 		cap := scheduleInfos[idx].Capacity
 
 		volumePlans[scheduleInfo.Name] = make([]types.VolumePlan, cap)
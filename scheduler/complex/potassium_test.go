@@ -1196,6 +1196,25 @@ func TestSelectStorageNodesCapacityLessThanMemory(t *testing.T) {
 	assert.Equal(t, 0, res[1].Capacity)
 }
 
+func TestSelectStorageNodesLimitingFactor(t *testing.T) {
+	k, _ := newPotassium()
+	scheduleInfos := generateNodes(2, 2, 4*int64(units.GiB), int64(units.GiB), 10)
+	scheduleInfos, _, err := k.SelectMemoryNodes(scheduleInfos, 1.0, int64(units.GiB))
+	assert.NoError(t, err)
+	assert.Equal(t, resourcetypes.LimitedByMemory, scheduleInfos[0].LimitingFactor)
+
+	scheduleInfos, _, err = k.SelectStorageNodes(scheduleInfos, int64(units.GiB))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scheduleInfos[0].Capacity)
+	assert.Equal(t, resourcetypes.LimitedByStorage, scheduleInfos[0].LimitingFactor)
+
+	// not limited by larger capacity
+	scheduleInfos, _, err = k.SelectStorageNodes(scheduleInfos, int64(units.MiB))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scheduleInfos[0].Capacity)
+	assert.Equal(t, resourcetypes.LimitedByStorage, scheduleInfos[0].LimitingFactor)
+}
+
 func TestSelectStorageNodesNotEnough(t *testing.T) {
 	k, _ := newPotassium()
 	scheduleInfos := generateNodes(1, 2, 4*int64(units.GiB), int64(units.MiB), 10)
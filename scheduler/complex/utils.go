@@ -6,7 +6,10 @@ import (
 	"github.com/projecteru2/core/utils"
 )
 
-func updateScheduleInfoCapacity(scheduleInfo *resourcetypes.ScheduleInfo, capacity int) int {
+func updateScheduleInfoCapacity(scheduleInfo *resourcetypes.ScheduleInfo, capacity int, factor string) int {
+	if scheduleInfo.Capacity == 0 || capacity < scheduleInfo.Capacity {
+		scheduleInfo.LimitingFactor = factor
+	}
 	if scheduleInfo.Capacity == 0 {
 		scheduleInfo.Capacity = capacity
 	} else {
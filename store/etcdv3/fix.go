@@ -0,0 +1,71 @@
+package etcdv3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/types"
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+// AddResourceFixRecord saves the fixed node with an audit record of fixing in one txn
+// so a record never exists for a fix not written
+func (m *Mercury) AddResourceFixRecord(ctx context.Context, record *types.ResourceFixRecord, node *types.Node) error {
+	bytes, err := json.Marshal(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	data, err := nodesData([]*types.Node{node})
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(nodeFixesKey, record.Nodename, strconv.FormatInt(record.Time.UnixNano(), 10))
+	_, err = m.BatchCreateAndUpdate(ctx, map[string]string{key: string(bytes)}, data)
+	return errors.WithStack(err)
+}
+
+// ListResourceFixRecords lists audit records of a node in time order
+func (m *Mercury) ListResourceFixRecords(ctx context.Context, nodename string) ([]*types.ResourceFixRecord, error) {
+	records := []*types.ResourceFixRecord{}
+	resp, err := m.Get(ctx, fmt.Sprintf(nodeFixesKey, nodename, ""), clientv3.WithPrefix())
+	if err != nil {
+		return records, errors.WithStack(err)
+	}
+
+	for _, ev := range resp.Kvs {
+		record := &types.ResourceFixRecord{}
+		if err := json.Unmarshal(ev.Value, record); err != nil {
+			return records, errors.WithStack(err)
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+// AddNodeResourceSnapshot saves a snapshot of node resource
+func (m *Mercury) AddNodeResourceSnapshot(ctx context.Context, snapshot *types.NodeResourceSnapshot) error {
+	bytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = m.Create(ctx, fmt.Sprintf(nodeSnapshotKey, snapshot.Nodename, snapshot.ID), string(bytes))
+	return errors.WithStack(err)
+}
+
+// GetNodeResourceSnapshot gets a snapshot of node resource
+func (m *Mercury) GetNodeResourceSnapshot(ctx context.Context, nodename, snapshotID string) (*types.NodeResourceSnapshot, error) {
+	resp, err := m.Get(ctx, fmt.Sprintf(nodeSnapshotKey, nodename, snapshotID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(resp.Kvs) != 1 {
+		return nil, types.NewDetailedErr(types.ErrSnapshotNotExists, fmt.Sprintf("%s of node %s", snapshotID, nodename))
+	}
+	snapshot := &types.NodeResourceSnapshot{}
+	return snapshot, errors.WithStack(json.Unmarshal(resp.Kvs[0].Value, snapshot))
+}
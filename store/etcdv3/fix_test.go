@@ -0,0 +1,70 @@
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceFixRecord(t *testing.T) {
+	m := NewMercury(t)
+	defer m.TerminateEmbededStorage()
+	ctx := context.Background()
+
+	records, err := m.ListResourceFixRecords(ctx, "node")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	node, err := m.doAddNode(ctx, "node", "mock://", "pod", "", "", "", 1, 100, 1000, 1000, nil, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	node2, err := m.doAddNode(ctx, "node2", "mock://", "pod", "", "", "", 1, 100, 1000, 1000, nil, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	node.MemCap = 900
+	assert.NoError(t, m.AddResourceFixRecord(ctx, &types.ResourceFixRecord{Nodename: "node", Time: now, OldCPUUsed: 2, NewCPUUsed: 1}, node))
+	assert.NoError(t, m.AddResourceFixRecord(ctx, &types.ResourceFixRecord{Nodename: "node", Time: now.Add(-time.Hour), MemCap: 100}, node))
+	assert.NoError(t, m.AddResourceFixRecord(ctx, &types.ResourceFixRecord{Nodename: "node2", Time: now}, node2))
+	n, err := m.GetNode(ctx, "node")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 900, n.MemCap)
+	// nothing written if record exists or node doesn't
+	node.MemCap = 800
+	assert.True(t, errors.Is(m.AddResourceFixRecord(ctx, &types.ResourceFixRecord{Nodename: "node", Time: now}, node), types.ErrKeyExists))
+	missing := &types.Node{NodeMeta: types.NodeMeta{Name: "missing", Podname: "pod"}}
+	assert.True(t, errors.Is(m.AddResourceFixRecord(ctx, &types.ResourceFixRecord{Nodename: "missing", Time: now}, missing), types.ErrKeyNotExists))
+	n, err = m.GetNode(ctx, "node")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 900, n.MemCap)
+	records, err = m.ListResourceFixRecords(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	records, err = m.ListResourceFixRecords(ctx, "node")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, int64(100), records[0].MemCap)
+	assert.Equal(t, float64(2), records[1].OldCPUUsed)
+	assert.Equal(t, float64(1), records[1].NewCPUUsed)
+}
+
+func TestNodeResourceSnapshot(t *testing.T) {
+	m := NewMercury(t)
+	defer m.TerminateEmbededStorage()
+	ctx := context.Background()
+
+	snapshot := types.NewNodeResourceSnapshot(&types.NodeResource{Name: "node", CPUPercent: 0.5, Workloads: []*types.Workload{{ID: "w1"}}}, time.Now())
+	assert.NoError(t, m.AddNodeResourceSnapshot(ctx, snapshot))
+	assert.Error(t, m.AddNodeResourceSnapshot(ctx, snapshot))
+
+	saved, err := m.GetNodeResourceSnapshot(ctx, "node", snapshot.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, saved.CPUPercent)
+	assert.Equal(t, []string{"w1"}, saved.WorkloadIDs)
+	_, err = m.GetNodeResourceSnapshot(ctx, "node2", snapshot.ID)
+	assert.True(t, errors.Is(err, types.ErrSnapshotNotExists))
+}
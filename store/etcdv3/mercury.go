@@ -19,11 +19,16 @@ const (
 	nodeKeyKey       = "/node/%s:key"          // /node/{nodename}:key
 	nodeStatusPrefix = "/status:node/"         // /status:node/{nodename} -> node status key
 	nodeWorkloadsKey = "/node/%s:workloads/%s" // /node/{nodename}:workloads/{workloadID}
+	nodeFixesKey     = "/node/%s:fixes/%s"     // /node/{nodename}:fixes/{timestamp}
+	nodeSnapshotKey  = "/node/%s:snapshots/%s" // /node/{nodename}:snapshots/{snapshotID}
 
 	workloadInfoKey          = "/workloads/%s" // /workloads/{workloadID}
 	workloadDeployPrefix     = "/deploy"       // /deploy/{appname}/{entrypoint}/{nodename}/{workloadID}
 	workloadStatusPrefix     = "/status"       // /status/{appname}/{entrypoint}/{nodename}/{workloadID} value -> something by agent
 	workloadProcessingPrefix = "/processing"   // /processing/{appname}/{entrypoint}/{nodename}/{opsIdent} value -> count
+
+	reservationKey = "/reservations/%s" // /reservations/{token}
+	secretKey      = "/secrets/%s"      // /secrets/{key}
 )
 
 // Mercury means store with etcdv3
@@ -228,6 +228,58 @@ func (e *ETCD) BatchUpdate(ctx context.Context, data map[string]string, opts ...
 	return e.batchUpdate(ctx, data, opts...)
 }
 
+// BatchCreateAndUpdate creates keys not exist and updates keys exist in one txn
+// nothing is written if any of creates exists or any of updates doesn't
+func (e *ETCD) BatchCreateAndUpdate(ctx context.Context, creates, updates map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error) {
+	ops := []clientv3.Op{}
+	failOps := []clientv3.Op{}
+	conds := []clientv3.Cmp{}
+	for key, val := range creates {
+		ops = append(ops, clientv3.OpPut(key, val, opts...))
+		failOps = append(failOps, clientv3.OpGet(key))
+		conds = append(conds, clientv3.Compare(clientv3.Version(key), "=", 0))
+	}
+	for key, val := range updates {
+		ops = append(ops, clientv3.OpPut(key, val, opts...))
+		conds = append(conds, clientv3.Compare(clientv3.Version(key), "!=", 0))
+	}
+	resp, err := e.doBatchOp(ctx, conds, ops, failOps)
+	if err != nil {
+		return resp, err
+	}
+	if !resp.Succeeded {
+		for _, failResp := range resp.Responses {
+			if len(failResp.GetResponseRange().Kvs) != 0 {
+				return resp, types.ErrKeyExists
+			}
+		}
+		return resp, types.ErrKeyNotExists
+	}
+	return resp, nil
+}
+
+// BatchDeleteAndUpdate .
+func (e *ETCD) BatchDeleteAndUpdate(ctx context.Context, deletes []string, updates map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error) {
+	ops := []clientv3.Op{}
+	conds := []clientv3.Cmp{}
+	for _, key := range deletes {
+		ops = append(ops, clientv3.OpDelete(key))
+		conds = append(conds, clientv3.Compare(clientv3.Version(key), "!=", 0))
+	}
+	for key, val := range updates {
+		ops = append(ops, clientv3.OpPut(key, val, opts...))
+		conds = append(conds, clientv3.Compare(clientv3.Version(key), "!=", 0))
+	}
+	resp, err := e.doBatchOp(ctx, conds, ops, []clientv3.Op{})
+	if err != nil {
+		return resp, err
+	}
+	if !resp.Succeeded {
+		return resp, types.ErrKeyNotExists
+	}
+	return resp, nil
+}
+
 // KeepAliveOnce keeps on a lease alive.
 func (e *ETCD) BindStatus(ctx context.Context, entityKey, statusKey, statusValue string, ttl int64) error {
 	updateStatus := []clientv3.Op{clientv3.OpPut(statusKey, statusValue)}
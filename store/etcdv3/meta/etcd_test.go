@@ -2,6 +2,7 @@ package meta
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -347,6 +348,33 @@ func TestETCD(t *testing.T) {
 	r, err = m.BatchUpdate(ctx, data)
 	require.Error(t, err)
 	require.False(t, r.Succeeded)
+	// BatchCreateAndUpdate
+	r, err = m.BatchCreateAndUpdate(ctx, map[string]string{"k4": "a4"}, map[string]string{"k1": "d1"})
+	require.NoError(t, err)
+	require.True(t, r.Succeeded)
+	// BatchCreateAndUpdateFail
+	_, err = m.BatchCreateAndUpdate(ctx, map[string]string{"k4": "b4"}, map[string]string{"k1": "e1"})
+	require.True(t, errors.Is(err, types.ErrKeyExists))
+	_, err = m.BatchCreateAndUpdate(ctx, map[string]string{"k5": "a5"}, map[string]string{"k6": "a6"})
+	require.True(t, errors.Is(err, types.ErrKeyNotExists))
+	ev, err = m.GetOne(ctx, "k1")
+	require.NoError(t, err)
+	require.Equal(t, "d1", string(ev.Value))
+	resp, err = m.Get(ctx, "k5")
+	require.NoError(t, err)
+	require.EqualValues(t, 0, resp.Count)
+	// BatchDeleteAndUpdate
+	r, err = m.BatchDeleteAndUpdate(ctx, []string{"k4"}, map[string]string{"k1": "f1"})
+	require.NoError(t, err)
+	require.True(t, r.Succeeded)
+	// BatchDeleteAndUpdateFail
+	_, err = m.BatchDeleteAndUpdate(ctx, []string{"k4"}, map[string]string{"k1": "g1"})
+	require.True(t, errors.Is(err, types.ErrKeyNotExists))
+	_, err = m.BatchDeleteAndUpdate(ctx, []string{"k1"}, map[string]string{"k6": "b6"})
+	require.True(t, errors.Is(err, types.ErrKeyNotExists))
+	ev, err = m.GetOne(ctx, "k1")
+	require.NoError(t, err)
+	require.Equal(t, "f1", string(ev.Value))
 	// Watch
 	ctx2, cancel := context.WithCancel(ctx)
 	ch := m.watch(ctx2, "watchkey", clientv3.WithPrefix())
@@ -28,6 +28,8 @@ type KV interface {
 	BatchCreate(ctx context.Context, data map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error)
 	BatchUpdate(ctx context.Context, data map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error)
 	BatchDelete(ctx context.Context, keys []string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error)
+	BatchCreateAndUpdate(ctx context.Context, creates, updates map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error)
+	BatchDeleteAndUpdate(ctx context.Context, deletes []string, updates map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error)
 
 	StartEphemeral(ctx context.Context, path string, heartbeat time.Duration) (<-chan struct{}, func(), error)
 	CreateLock(key string, ttl time.Duration) (lock.DistributedLock, error)
@@ -51,6 +51,36 @@ func (_m *KV) BatchCreate(ctx context.Context, data map[string]string, opts ...c
 	return r0, r1
 }
 
+// BatchCreateAndUpdate provides a mock function with given fields: ctx, creates, updates, opts
+func (_m *KV) BatchCreateAndUpdate(ctx context.Context, creates map[string]string, updates map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, creates, updates)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *clientv3.TxnResponse
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]string, map[string]string, ...clientv3.OpOption) *clientv3.TxnResponse); ok {
+		r0 = rf(ctx, creates, updates, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*clientv3.TxnResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, map[string]string, map[string]string, ...clientv3.OpOption) error); ok {
+		r1 = rf(ctx, creates, updates, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // BatchDelete provides a mock function with given fields: ctx, keys, opts
 func (_m *KV) BatchDelete(ctx context.Context, keys []string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error) {
 	_va := make([]interface{}, len(opts))
@@ -81,6 +111,36 @@ func (_m *KV) BatchDelete(ctx context.Context, keys []string, opts ...clientv3.O
 	return r0, r1
 }
 
+// BatchDeleteAndUpdate provides a mock function with given fields: ctx, deletes, updates, opts
+func (_m *KV) BatchDeleteAndUpdate(ctx context.Context, deletes []string, updates map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, deletes, updates)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *clientv3.TxnResponse
+	if rf, ok := ret.Get(0).(func(context.Context, []string, map[string]string, ...clientv3.OpOption) *clientv3.TxnResponse); ok {
+		r0 = rf(ctx, deletes, updates, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*clientv3.TxnResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string, map[string]string, ...clientv3.OpOption) error); ok {
+		r1 = rf(ctx, deletes, updates, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // BatchUpdate provides a mock function with given fields: ctx, data, opts
 func (_m *KV) BatchUpdate(ctx context.Context, data map[string]string, opts ...clientv3.OpOption) (*clientv3.TxnResponse, error) {
 	_va := make([]interface{}, len(opts))
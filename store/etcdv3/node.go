@@ -78,7 +78,7 @@ func (m *Mercury) AddNode(ctx context.Context, opts *types.AddNodeOptions) (*typ
 		}
 	}
 
-	return m.doAddNode(ctx, opts.Nodename, opts.Endpoint, opts.Podname, opts.Ca, opts.Cert, opts.Key, opts.CPU, opts.Share, opts.Memory, opts.Storage, opts.Labels, opts.Numa, opts.NumaMemory, opts.Volume)
+	return m.doAddNode(ctx, opts.Nodename, opts.Endpoint, opts.Podname, opts.Ca, opts.Cert, opts.Key, opts.CPU, opts.Share, opts.Memory, opts.Storage, opts.Labels, opts.Numa, opts.NumaMemory, opts.Volume, opts.StoragePools)
 }
 
 // RemoveNode delete a node
@@ -144,19 +144,27 @@ func (m *Mercury) GetNodesByPod(ctx context.Context, podname string, labels map[
 
 // UpdateNodes .
 func (m *Mercury) UpdateNodes(ctx context.Context, nodes ...*types.Node) error {
+	data, err := nodesData(nodes)
+	if err != nil {
+		return err
+	}
+	_, err = m.BatchUpdate(ctx, data)
+	return errors.WithStack(err)
+}
+
+// nodesData makes both keys of nodes
+func nodesData(nodes []*types.Node) (map[string]string, error) {
 	data := map[string]string{}
 	for _, node := range nodes {
 		bytes, err := json.Marshal(node)
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 		d := string(bytes)
 		data[fmt.Sprintf(nodeInfoKey, node.Name)] = d
 		data[fmt.Sprintf(nodePodKey, node.Podname, node.Name)] = d
 	}
-
-	_, err := m.BatchUpdate(ctx, data)
-	return errors.WithStack(err)
+	return data, nil
 }
 
 // UpdateNodeResource update cpu and memory on a node, either add or subtract
@@ -198,7 +206,7 @@ func (m *Mercury) makeClient(ctx context.Context, node *types.Node, force bool)
 	return client, nil
 }
 
-func (m *Mercury) doAddNode(ctx context.Context, name, endpoint, podname, ca, cert, key string, cpu, share int, memory, storage int64, labels map[string]string, numa types.NUMA, numaMemory types.NUMAMemory, volumemap types.VolumeMap) (*types.Node, error) {
+func (m *Mercury) doAddNode(ctx context.Context, name, endpoint, podname, ca, cert, key string, cpu, share int, memory, storage int64, labels map[string]string, numa types.NUMA, numaMemory types.NUMAMemory, volumemap types.VolumeMap, storagePools types.StoragePools) (*types.Node, error) {
 	data := map[string]string{}
 	// 如果有tls的证书需要保存就保存一下
 	if ca != "" {
@@ -233,6 +241,10 @@ func (m *Mercury) doAddNode(ctx context.Context, name, endpoint, podname, ca, ce
 			Labels:         labels,
 			NUMA:           numa,
 			NUMAMemory:     numaMemory,
+			ShareBase:      share,
+
+			StoragePools:     storagePools,
+			InitStoragePools: storagePools,
 		},
 		Available: true,
 	}
@@ -59,6 +59,20 @@ func (m *Mercury) GetPod(ctx context.Context, name string) (*types.Pod, error) {
 	return pod, err
 }
 
+// UpdatePod overwrites an existing pod
+func (m *Mercury) UpdatePod(ctx context.Context, pod *types.Pod) error {
+	key := fmt.Sprintf(podInfoKey, pod.Name)
+	if _, err := m.GetOne(ctx, key); err != nil {
+		return err
+	}
+	bytes, err := json.Marshal(pod)
+	if err != nil {
+		return err
+	}
+	_, err = m.Put(ctx, key, string(bytes))
+	return err
+}
+
 // GetAllPods get all pods in etcd
 // any error will break and return error immediately
 // storage path in etcd is `/pod`
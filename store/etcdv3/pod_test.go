@@ -27,6 +27,13 @@ func TestPod(t *testing.T) {
 	assert.Equal(t, len(pods), 1)
 	assert.Equal(t, pods[0].Name, podname)
 
+	pod2.DefaultStrategy = "FILL"
+	assert.NoError(t, m.UpdatePod(ctx, pod2))
+	pod3, err := m.GetPod(ctx, podname)
+	assert.NoError(t, err)
+	assert.Equal(t, "FILL", pod3.DefaultStrategy)
+	assert.Error(t, m.UpdatePod(ctx, &types.Pod{Name: "missing"}))
+
 	_, err = m.AddNode(ctx, &types.AddNodeOptions{Nodename: "test", Endpoint: "mock://", Podname: podname, CPU: 10, Share: 100, Memory: 1000, Storage: 1000})
 	assert.NoError(t, err)
 	err = m.RemovePod(ctx, podname)
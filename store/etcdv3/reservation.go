@@ -0,0 +1,69 @@
+package etcdv3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/types"
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+// AddReservation saves a reservation and nodes with resources taken by it in one txn, token must be unique
+func (m *Mercury) AddReservation(ctx context.Context, reservation *types.Reservation, nodes []*types.Node) error {
+	bytes, err := json.Marshal(reservation)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	data, err := nodesData(nodes)
+	if err != nil {
+		return err
+	}
+	_, err = m.BatchCreateAndUpdate(ctx, map[string]string{fmt.Sprintf(reservationKey, reservation.Token): string(bytes)}, data)
+	return errors.WithStack(err)
+}
+
+// GetReservation gets a reservation by token
+func (m *Mercury) GetReservation(ctx context.Context, token string) (*types.Reservation, error) {
+	ev, err := m.GetOne(ctx, fmt.Sprintf(reservationKey, token))
+	if err != nil {
+		return nil, types.NewDetailedErr(types.ErrInvalidReservation, fmt.Sprintf("token %s: %v", token, err))
+	}
+	reservation := &types.Reservation{}
+	return reservation, errors.WithStack(json.Unmarshal(ev.Value, reservation))
+}
+
+// ListReservations lists reservations of all pods
+func (m *Mercury) ListReservations(ctx context.Context) ([]*types.Reservation, error) {
+	reservations := []*types.Reservation{}
+	resp, err := m.Get(ctx, fmt.Sprintf(reservationKey, ""), clientv3.WithPrefix())
+	if err != nil {
+		return reservations, errors.WithStack(err)
+	}
+
+	for _, ev := range resp.Kvs {
+		reservation := &types.Reservation{}
+		if err := json.Unmarshal(ev.Value, reservation); err != nil {
+			return reservations, errors.WithStack(err)
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations, nil
+}
+
+// RemoveReservation deletes a reservation and saves nodes with resources given back by it in one txn
+// fails if not exists, so only one of concurrent removers wins
+func (m *Mercury) RemoveReservation(ctx context.Context, token string, nodes []*types.Node) error {
+	data, err := nodesData(nodes)
+	if err != nil {
+		return err
+	}
+	if _, err = m.BatchDeleteAndUpdate(ctx, []string{fmt.Sprintf(reservationKey, token)}, data); err != nil {
+		if errors.Is(err, types.ErrKeyNotExists) {
+			return types.NewDetailedErr(types.ErrInvalidReservation, fmt.Sprintf("token %s or its nodes not exists", token))
+		}
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservation(t *testing.T) {
+	m := NewMercury(t)
+	defer m.TerminateEmbededStorage()
+	ctx := context.Background()
+
+	reservations, err := m.ListReservations(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, reservations)
+
+	reservation := &types.Reservation{
+		Token:     "token",
+		Podname:   "pod",
+		Resources: map[string][]*types.ResourceMeta{"node": {{MemoryRequest: 100}}},
+		ExpireAt:  time.Now().Add(time.Minute),
+	}
+	node, err := m.doAddNode(ctx, "node", "mock://", "pod", "", "", "", 1, 100, 1000, 1000, nil, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	node.MemCap -= 100
+	assert.NoError(t, m.AddReservation(ctx, reservation, []*types.Node{node}))
+	n, err := m.GetNode(ctx, "node")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 900, n.MemCap)
+	// nothing written if reservation exists or node doesn't
+	node.MemCap -= 100
+	assert.True(t, errors.Is(m.AddReservation(ctx, reservation, []*types.Node{node}), types.ErrKeyExists))
+	missing := &types.Node{NodeMeta: types.NodeMeta{Name: "missing", Podname: "pod"}}
+	assert.True(t, errors.Is(m.AddReservation(ctx, &types.Reservation{Token: "token3"}, []*types.Node{node, missing}), types.ErrKeyNotExists))
+	n, err = m.GetNode(ctx, "node")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 900, n.MemCap)
+	assert.NoError(t, m.AddReservation(ctx, &types.Reservation{Token: "token2"}, nil))
+
+	r, err := m.GetReservation(ctx, "token")
+	assert.NoError(t, err)
+	assert.Equal(t, "pod", r.Podname)
+	assert.Equal(t, int64(100), r.Resources["node"][0].MemoryRequest)
+	_, err = m.GetReservation(ctx, "token3")
+	assert.True(t, errors.Is(err, types.ErrInvalidReservation))
+
+	reservations, err = m.ListReservations(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, reservations, 2)
+
+	// nothing written if node doesn't exist
+	node.MemCap = 1000
+	assert.True(t, errors.Is(m.RemoveReservation(ctx, "token", []*types.Node{node, missing}), types.ErrInvalidReservation))
+	_, err = m.GetReservation(ctx, "token")
+	assert.NoError(t, err)
+	assert.NoError(t, m.RemoveReservation(ctx, "token", []*types.Node{node}))
+	n, err = m.GetNode(ctx, "node")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1000, n.MemCap)
+	assert.True(t, errors.Is(m.RemoveReservation(ctx, "token", []*types.Node{node}), types.ErrInvalidReservation))
+	reservations, err = m.ListReservations(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, reservations, 1)
+}
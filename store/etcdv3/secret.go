@@ -0,0 +1,43 @@
+package etcdv3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/types"
+)
+
+// SetSecret saves a secret, existing one is overwritten
+func (m *Mercury) SetSecret(ctx context.Context, key, value string) error {
+	_, err := m.Put(ctx, fmt.Sprintf(secretKey, key), value)
+	return errors.WithStack(err)
+}
+
+// GetSecrets gets secrets by keys, all of them must exist
+func (m *Mercury) GetSecrets(ctx context.Context, keys []string) (map[string]string, error) {
+	secrets := map[string]string{}
+	for _, key := range keys {
+		resp, err := m.Get(ctx, fmt.Sprintf(secretKey, key))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(resp.Kvs) != 1 {
+			return nil, types.NewDetailedErr(types.ErrSecretNotExists, key)
+		}
+		secrets[key] = string(resp.Kvs[0].Value)
+	}
+	return secrets, nil
+}
+
+// RemoveSecret removes a secret
+func (m *Mercury) RemoveSecret(ctx context.Context, key string) error {
+	resp, err := m.Delete(ctx, fmt.Sprintf(secretKey, key))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if resp.Deleted == 0 {
+		return types.NewDetailedErr(types.ErrSecretNotExists, key)
+	}
+	return nil
+}
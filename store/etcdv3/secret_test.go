@@ -0,0 +1,32 @@
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret(t *testing.T) {
+	m := NewMercury(t)
+	defer m.TerminateEmbededStorage()
+	ctx := context.Background()
+
+	assert.NoError(t, m.SetSecret(ctx, "db", "pass"))
+	assert.NoError(t, m.SetSecret(ctx, "token", "t1"))
+	assert.NoError(t, m.SetSecret(ctx, "token", "t2"))
+
+	secrets, err := m.GetSecrets(ctx, []string{"db", "token"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"db": "pass", "token": "t2"}, secrets)
+	_, err = m.GetSecrets(ctx, []string{"db", "missing"})
+	assert.True(t, errors.Is(err, types.ErrSecretNotExists))
+	assert.Contains(t, err.Error(), "missing")
+
+	assert.NoError(t, m.RemoveSecret(ctx, "db"))
+	assert.True(t, errors.Is(m.RemoveSecret(ctx, "db"), types.ErrSecretNotExists))
+	_, err = m.GetSecrets(ctx, []string{"db"})
+	assert.True(t, errors.Is(err, types.ErrSecretNotExists))
+}
@@ -43,6 +43,20 @@ func (_m *Store) AddNode(_a0 context.Context, _a1 *types.AddNodeOptions) (*types
 	return r0, r1
 }
 
+// AddNodeResourceSnapshot provides a mock function with given fields: ctx, snapshot
+func (_m *Store) AddNodeResourceSnapshot(ctx context.Context, snapshot *types.NodeResourceSnapshot) error {
+	ret := _m.Called(ctx, snapshot)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *types.NodeResourceSnapshot) error); ok {
+		r0 = rf(ctx, snapshot)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // AddPod provides a mock function with given fields: ctx, name, desc
 func (_m *Store) AddPod(ctx context.Context, name string, desc string) (*types.Pod, error) {
 	ret := _m.Called(ctx, name, desc)
@@ -66,6 +80,34 @@ func (_m *Store) AddPod(ctx context.Context, name string, desc string) (*types.P
 	return r0, r1
 }
 
+// AddReservation provides a mock function with given fields: ctx, reservation, nodes
+func (_m *Store) AddReservation(ctx context.Context, reservation *types.Reservation, nodes []*types.Node) error {
+	ret := _m.Called(ctx, reservation, nodes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *types.Reservation, []*types.Node) error); ok {
+		r0 = rf(ctx, reservation, nodes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddResourceFixRecord provides a mock function with given fields: ctx, record, node
+func (_m *Store) AddResourceFixRecord(ctx context.Context, record *types.ResourceFixRecord, node *types.Node) error {
+	ret := _m.Called(ctx, record, node)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ResourceFixRecord, *types.Node) error); ok {
+		r0 = rf(ctx, record, node)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // AddWorkload provides a mock function with given fields: ctx, workload
 func (_m *Store) AddWorkload(ctx context.Context, workload *types.Workload) error {
 	ret := _m.Called(ctx, workload)
@@ -163,6 +205,29 @@ func (_m *Store) GetNode(ctx context.Context, nodename string) (*types.Node, err
 	return r0, r1
 }
 
+// GetNodeResourceSnapshot provides a mock function with given fields: ctx, nodename, snapshotID
+func (_m *Store) GetNodeResourceSnapshot(ctx context.Context, nodename string, snapshotID string) (*types.NodeResourceSnapshot, error) {
+	ret := _m.Called(ctx, nodename, snapshotID)
+
+	var r0 *types.NodeResourceSnapshot
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *types.NodeResourceSnapshot); ok {
+		r0 = rf(ctx, nodename, snapshotID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.NodeResourceSnapshot)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, nodename, snapshotID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetNodes provides a mock function with given fields: ctx, nodenames
 func (_m *Store) GetNodes(ctx context.Context, nodenames []string) ([]*types.Node, error) {
 	ret := _m.Called(ctx, nodenames)
@@ -232,6 +297,52 @@ func (_m *Store) GetPod(ctx context.Context, podname string) (*types.Pod, error)
 	return r0, r1
 }
 
+// GetReservation provides a mock function with given fields: ctx, token
+func (_m *Store) GetReservation(ctx context.Context, token string) (*types.Reservation, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 *types.Reservation
+	if rf, ok := ret.Get(0).(func(context.Context, string) *types.Reservation); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Reservation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSecrets provides a mock function with given fields: ctx, keys
+func (_m *Store) GetSecrets(ctx context.Context, keys []string) (map[string]string, error) {
+	ret := _m.Called(ctx, keys)
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]string); ok {
+		r0 = rf(ctx, keys)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, keys)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetWorkload provides a mock function with given fields: ctx, id
 func (_m *Store) GetWorkload(ctx context.Context, id string) (*types.Workload, error) {
 	ret := _m.Called(ctx, id)
@@ -324,6 +435,52 @@ func (_m *Store) ListNodeWorkloads(ctx context.Context, nodename string, labels
 	return r0, r1
 }
 
+// ListReservations provides a mock function with given fields: ctx
+func (_m *Store) ListReservations(ctx context.Context) ([]*types.Reservation, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*types.Reservation
+	if rf, ok := ret.Get(0).(func(context.Context) []*types.Reservation); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*types.Reservation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListResourceFixRecords provides a mock function with given fields: ctx, nodename
+func (_m *Store) ListResourceFixRecords(ctx context.Context, nodename string) ([]*types.ResourceFixRecord, error) {
+	ret := _m.Called(ctx, nodename)
+
+	var r0 []*types.ResourceFixRecord
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*types.ResourceFixRecord); ok {
+		r0 = rf(ctx, nodename)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*types.ResourceFixRecord)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, nodename)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListWorkloads provides a mock function with given fields: ctx, appname, entrypoint, nodename, limit, labels
 func (_m *Store) ListWorkloads(ctx context.Context, appname string, entrypoint string, nodename string, limit int64, labels map[string]string) ([]*types.Workload, error) {
 	ret := _m.Called(ctx, appname, entrypoint, nodename, limit, labels)
@@ -437,6 +594,34 @@ func (_m *Store) RemovePod(ctx context.Context, podname string) error {
 	return r0
 }
 
+// RemoveReservation provides a mock function with given fields: ctx, token, nodes
+func (_m *Store) RemoveReservation(ctx context.Context, token string, nodes []*types.Node) error {
+	ret := _m.Called(ctx, token, nodes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []*types.Node) error); ok {
+		r0 = rf(ctx, token, nodes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveSecret provides a mock function with given fields: ctx, key
+func (_m *Store) RemoveSecret(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // RemoveWorkload provides a mock function with given fields: ctx, workload
 func (_m *Store) RemoveWorkload(ctx context.Context, workload *types.Workload) error {
 	ret := _m.Called(ctx, workload)
@@ -502,6 +687,20 @@ func (_m *Store) SetNodeStatus(ctx context.Context, node *types.Node, ttl int64)
 	return r0
 }
 
+// SetSecret provides a mock function with given fields: ctx, key, value
+func (_m *Store) SetSecret(ctx context.Context, key string, value string) error {
+	ret := _m.Called(ctx, key, value)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, key, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetWorkloadStatus provides a mock function with given fields: ctx, workload, ttl
 func (_m *Store) SetWorkloadStatus(ctx context.Context, workload *types.Workload, ttl int64) error {
 	ret := _m.Called(ctx, workload, ttl)
@@ -588,6 +787,20 @@ func (_m *Store) UpdateNodes(_a0 context.Context, _a1 ...*types.Node) error {
 	return r0
 }
 
+// UpdatePod provides a mock function with given fields: ctx, pod
+func (_m *Store) UpdatePod(ctx context.Context, pod *types.Pod) error {
+	ret := _m.Called(ctx, pod)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *types.Pod) error); ok {
+		r0 = rf(ctx, pod)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateProcessing provides a mock function with given fields: ctx, opts, nodename, count
 func (_m *Store) UpdateProcessing(ctx context.Context, opts *types.DeployOptions, nodename string, count int) error {
 	ret := _m.Called(ctx, opts, nodename, count)
@@ -28,6 +28,7 @@ type Store interface {
 	// pod
 	AddPod(ctx context.Context, name, desc string) (*types.Pod, error)
 	GetPod(ctx context.Context, podname string) (*types.Pod, error)
+	UpdatePod(ctx context.Context, pod *types.Pod) error
 	RemovePod(ctx context.Context, podname string) error
 	GetAllPods(ctx context.Context) ([]*types.Pod, error)
 
@@ -41,6 +42,10 @@ type Store interface {
 	UpdateNodeResource(ctx context.Context, node *types.Node, resource *types.ResourceMeta, action string) error
 	SetNodeStatus(ctx context.Context, node *types.Node, ttl int64) error
 	NodeStatusStream(ctx context.Context) chan *types.NodeStatus
+	AddResourceFixRecord(ctx context.Context, record *types.ResourceFixRecord, node *types.Node) error
+	ListResourceFixRecords(ctx context.Context, nodename string) ([]*types.ResourceFixRecord, error)
+	AddNodeResourceSnapshot(ctx context.Context, snapshot *types.NodeResourceSnapshot) error
+	GetNodeResourceSnapshot(ctx context.Context, nodename, snapshotID string) (*types.NodeResourceSnapshot, error)
 
 	// workload
 	AddWorkload(ctx context.Context, workload *types.Workload) error
@@ -57,6 +62,17 @@ type Store interface {
 	// deploy status
 	MakeDeployStatus(ctx context.Context, opts *types.DeployOptions, strategyInfo []strategy.Info) error
 
+	// reservation
+	AddReservation(ctx context.Context, reservation *types.Reservation, nodes []*types.Node) error
+	GetReservation(ctx context.Context, token string) (*types.Reservation, error)
+	ListReservations(ctx context.Context) ([]*types.Reservation, error)
+	RemoveReservation(ctx context.Context, token string, nodes []*types.Node) error
+
+	// secret
+	SetSecret(ctx context.Context, key, value string) error
+	GetSecrets(ctx context.Context, keys []string) (map[string]string, error)
+	RemoveSecret(ctx context.Context, key string) error
+
 	// processing status
 	SaveProcessing(ctx context.Context, opts *types.DeployOptions, nodename string, count int) error
 	UpdateProcessing(ctx context.Context, opts *types.DeployOptions, nodename string, count int) error
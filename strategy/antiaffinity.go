@@ -0,0 +1,71 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/log"
+	"github.com/projecteru2/core/types"
+)
+
+// AntiAffinityPlan spreads workloads by node label
+// 按 key 对应的标签值分组, 每组都有实例之后才会在同组的节点上部署第二个
+// 标签值不够的时候就轮流往各组里放, 没有这个标签的节点视为同一组
+// limit 在这里不生效
+func AntiAffinityPlan(key string) startegyFunc {
+	return func(infos []Info, need, total, _ int) (map[string]int, error) {
+		log.Debugf("[AntiAffinityPlan] key %s need %d total %d infos %+v", key, need, total, infos)
+		if total < need {
+			return nil, errors.WithStack(types.NewDetailedErr(types.ErrInsufficientRes,
+				fmt.Sprintf("need: %d, vol: %d", need, total)))
+		}
+
+		groups := map[string][]*Info{}
+		loads := map[string]int{}
+		for i := range infos {
+			value := infos[i].Labels[key]
+			groups[value] = append(groups[value], &infos[i])
+			loads[value] += infos[i].Count
+		}
+		values := []string{}
+		for value := range groups {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+
+		deployMap := map[string]int{}
+		for ; need > 0; need-- {
+			var picked *Info
+			pickedValue := ""
+			for _, value := range values {
+				if picked != nil && loads[value] >= loads[pickedValue] {
+					continue
+				}
+				if info := pickNode(groups[value], deployMap); info != nil {
+					picked, pickedValue = info, value
+				}
+			}
+			if picked == nil {
+				return nil, errors.WithStack(types.NewDetailedErr(types.ErrInsufficientRes,
+					fmt.Sprintf("insufficient nodes to spread by %s, %d more needed", key, need)))
+			}
+			deployMap[picked.Nodename]++
+			loads[pickedValue]++
+		}
+		return deployMap, nil
+	}
+}
+
+// pickNode picks the least loaded node which still has capacity
+func pickNode(infos []*Info, deployMap map[string]int) (picked *Info) {
+	for _, info := range infos {
+		if info.Capacity <= deployMap[info.Nodename] {
+			continue
+		}
+		if picked == nil || info.Count+deployMap[info.Nodename] < picked.Count+deployMap[picked.Nodename] {
+			picked = info
+		}
+	}
+	return
+}
@@ -0,0 +1,47 @@
+package strategy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func zonedNodes() []Info {
+	return []Info{
+		{Nodename: "n1", Labels: map[string]string{"zone": "a"}, Capacity: 10},
+		{Nodename: "n2", Labels: map[string]string{"zone": "a"}, Capacity: 10},
+		{Nodename: "n3", Labels: map[string]string{"zone": "b"}, Capacity: 10},
+		{Nodename: "n4", Labels: map[string]string{"zone": "c"}, Capacity: 1},
+	}
+}
+
+func TestAntiAffinityPlan(t *testing.T) {
+	// one per zone
+	r, err := AntiAffinityPlan("zone")(zonedNodes(), 3, 21, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n1": 1, "n3": 1, "n4": 1}, r)
+
+	// not enough zones, spread in turn
+	r, err = AntiAffinityPlan("zone")(zonedNodes(), 6, 21, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n1": 2, "n2": 1, "n3": 2, "n4": 1}, r)
+
+	// existing workloads count
+	nodes := zonedNodes()
+	nodes[2].Count = 2
+	r, err = AntiAffinityPlan("zone")(nodes, 2, 21, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n1": 1, "n4": 1}, r)
+
+	// nodes without label are in one group
+	nodes = append(zonedNodes(), Info{Nodename: "n5", Capacity: 10})
+	r, err = AntiAffinityPlan("zone")(nodes, 4, 31, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n1": 1, "n3": 1, "n4": 1, "n5": 1}, r)
+
+	// insufficient
+	_, err = AntiAffinityPlan("zone")(zonedNodes(), 22, 21, 0)
+	assert.True(t, errors.Is(err, types.ErrInsufficientRes))
+}
@@ -38,3 +38,78 @@ func AveragePlan(infos []Info, need, total, limit int) (map[string]int, error) {
 
 	return deployMap, nil
 }
+
+// ScoredAveragePlan layers node scores on AveragePlan
+// 节点分数为 1 加上命中的标签权重, 权重必须是 label=value 的形式, 负数忽略
+// 选出分数最高的 limit 台共部署 need*limit 个, 每台先部署一个, 剩下的按分数比例分配并受容量限制
+// 没有权重的时候就是 AveragePlan
+func ScoredAveragePlan(weights map[string]float64) startegyFunc {
+	return func(infos []Info, need, total, limit int) (map[string]int, error) {
+		if len(weights) == 0 {
+			return AveragePlan(infos, need, total, limit)
+		}
+		log.Debugf("[ScoredAveragePlan] weights %v need %d limit %d infos %v", weights, need, limit, infos)
+		if limit == 0 {
+			limit = len(infos)
+		}
+		if len(infos) < limit {
+			return nil, errors.WithStack(types.NewDetailedErr(types.ErrInsufficientRes,
+				fmt.Sprintf("node len %d < limit, cannot alloc an average node plan", len(infos))))
+		}
+
+		scores := map[string]float64{}
+		for _, info := range infos {
+			scores[info.Nodename] = nodeScore(info.Labels, weights)
+		}
+		sort.Slice(infos, func(i, j int) bool {
+			si, sj := scores[infos[i].Nodename], scores[infos[j].Nodename]
+			if si != sj {
+				return si > sj
+			}
+			if infos[i].Capacity != infos[j].Capacity {
+				return infos[i].Capacity > infos[j].Capacity
+			}
+			return infos[i].Nodename < infos[j].Nodename
+		})
+		infos = infos[:limit]
+
+		deployMap := map[string]int{}
+		for _, info := range infos {
+			if info.Capacity > 0 {
+				deployMap[info.Nodename] = 1
+			}
+		}
+		if len(deployMap) < limit {
+			return nil, types.NewDetailedErr(types.ErrInsufficientRes, fmt.Sprintf("insufficient nodes, %d more needed", limit-len(deployMap)))
+		}
+		// the node with least deployed over score goes next, higher scored first on ties
+		for left := need*limit - limit; left > 0; left-- {
+			var picked *Info
+			for i := range infos {
+				info := &infos[i]
+				if deployMap[info.Nodename] >= info.Capacity {
+					continue
+				}
+				if picked == nil || float64(deployMap[info.Nodename]+1)/scores[info.Nodename] < float64(deployMap[picked.Nodename]+1)/scores[picked.Nodename] {
+					picked = info
+				}
+			}
+			if picked == nil {
+				return nil, errors.WithStack(types.NewDetailedErr(types.ErrInsufficientCap,
+					fmt.Sprintf("%d more needed", left)))
+			}
+			deployMap[picked.Nodename]++
+		}
+		return deployMap, nil
+	}
+}
+
+func nodeScore(labels map[string]string, weights map[string]float64) float64 {
+	score := 1.0
+	for key, value := range labels {
+		if weight := weights[key+"="+value]; weight > 0 {
+			score += weight
+		}
+	}
+	return score
+}
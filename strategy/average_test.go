@@ -41,3 +41,51 @@ func TestAveragePlan(t *testing.T) {
 	_, err = AveragePlan(nodes, 2, 100, 0)
 	assert.EqualError(t, err, "not enough resource: insufficient nodes, 1 more needed")
 }
+
+func TestScoredAveragePlan(t *testing.T) {
+	labeled := func(caps []int, disks []string) []Info {
+		nodes := genNodesByCapCount(caps, make([]int, len(caps)))
+		for i := range nodes {
+			nodes[i].Labels = map[string]string{"disk": disks[i]}
+		}
+		return nodes
+	}
+
+	// no weights is plain average
+	nodes := labeled([]int{10, 10, 10}, []string{"ssd", "hdd", "hdd"})
+	r, err := ScoredAveragePlan(nil)(nodes, 2, 30, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 2, "1": 2, "2": 2}, r)
+
+	// shifted in proportion to score, every node still gets one
+	nodes = labeled([]int{10, 10, 10}, []string{"ssd", "hdd", "hdd"})
+	r, err = ScoredAveragePlan(map[string]float64{"disk=ssd": 2})(nodes, 5, 30, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 9, "1": 3, "2": 3}, r)
+
+	// doubled weight doubles the share
+	nodes = labeled([]int{20, 20}, []string{"ssd", "hdd"})
+	r, err = ScoredAveragePlan(map[string]float64{"disk=ssd": 1})(nodes, 6, 40, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 8, "1": 4}, r)
+
+	// bounded by capacity, negative weights ignored
+	nodes = labeled([]int{4, 10}, []string{"ssd", "hdd"})
+	r, err = ScoredAveragePlan(map[string]float64{"disk=ssd": 9, "disk=hdd": -1})(nodes, 5, 14, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 4, "1": 6}, r)
+
+	// limit takes higher scored nodes
+	nodes = labeled([]int{10, 10, 10}, []string{"hdd", "ssd", "hdd"})
+	r, err = ScoredAveragePlan(map[string]float64{"disk=ssd": 1})(nodes, 3, 30, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"1": 3}, r)
+
+	// insufficient
+	nodes = labeled([]int{1, 2}, []string{"ssd", "hdd"})
+	_, err = ScoredAveragePlan(map[string]float64{"disk=ssd": 1})(nodes, 2, 3, 0)
+	assert.Error(t, err)
+	nodes = labeled([]int{1, 2}, []string{"ssd", "hdd"})
+	_, err = ScoredAveragePlan(map[string]float64{"disk=ssd": 1})(nodes, 2, 3, 3)
+	assert.Error(t, err)
+}
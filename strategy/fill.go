@@ -16,6 +16,27 @@ import (
 // limit = 0 代表对所有节点进行填充
 func FillPlan(infos []Info, need, _, limit int) (_ map[string]int, err error) {
 	log.Debugf("[FillPlan] need %d limit %d infos %+v", need, limit, infos)
+	return doFillPlan(infos, need, limit, func(i, j int) bool {
+		if infos[i].Count == infos[j].Count {
+			return infos[i].Capacity > infos[j].Capacity
+		}
+		return infos[i].Count > infos[j].Count
+	})
+}
+
+// FillByStoragePlan works like FillPlan
+// but fills nodes with the most free storage first
+func FillByStoragePlan(infos []Info, need, _, limit int) (_ map[string]int, err error) {
+	log.Debugf("[FillByStoragePlan] need %d limit %d infos %+v", need, limit, infos)
+	return doFillPlan(infos, need, limit, func(i, j int) bool {
+		if infos[i].Storage == infos[j].Storage {
+			return infos[i].Capacity > infos[j].Capacity
+		}
+		return infos[i].Storage > infos[j].Storage
+	})
+}
+
+func doFillPlan(infos []Info, need, limit int, less func(i, j int) bool) (_ map[string]int, err error) {
 	scheduleInfosLength := len(infos)
 	if limit == 0 {
 		limit = scheduleInfosLength
@@ -24,12 +45,7 @@ func FillPlan(infos []Info, need, _, limit int) (_ map[string]int, err error) {
 		return nil, errors.WithStack(types.NewDetailedErr(types.ErrInsufficientRes,
 			fmt.Sprintf("node len %d cannot alloc a fill node plan", scheduleInfosLength)))
 	}
-	sort.Slice(infos, func(i, j int) bool {
-		if infos[i].Count == infos[j].Count {
-			return infos[i].Capacity > infos[j].Capacity
-		}
-		return infos[i].Count > infos[j].Count
-	})
+	sort.Slice(infos, less)
 	deployMap, toDeploy := make(map[string]int), 0
 	for _, info := range infos {
 		if info.Count+info.Capacity >= need {
@@ -47,3 +63,17 @@ func FillPlan(infos []Info, need, _, limit int) (_ map[string]int, err error) {
 	return nil, errors.WithStack(types.NewDetailedErr(types.ErrInsufficientRes,
 		fmt.Sprintf("insufficient nodes to fill %d, %d more nodes needed", need, limit)))
 }
+
+// FillReservePlan works like FillPlan
+// but leaves reserve slots free on each node for later realloc
+func FillReservePlan(reserve int) startegyFunc {
+	return func(infos []Info, need, total, limit int) (map[string]int, error) {
+		log.Debugf("[FillReservePlan] reserve %d", reserve)
+		reservedInfos := make([]Info, len(infos))
+		for i, info := range infos {
+			info.Capacity = utils.Max(info.Capacity-reserve, 0)
+			reservedInfos[i] = info
+		}
+		return FillPlan(reservedInfos, need, total, limit)
+	}
+}
@@ -83,3 +83,47 @@ func TestFillPlan(t *testing.T) {
 	_, err = FillPlan(nodes, 5, 1000, 0)
 	assert.EqualError(t, err, "not enough resource: insufficient nodes to fill 5, 1 more nodes needed")
 }
+
+func TestFillReservePlan(t *testing.T) {
+	// capacity 10 with 3 reserved
+	nodes := deployedNodes()
+	r, err := FillReservePlan(3)(nodes, 9, 0, 0)
+	assert.NoError(t, err)
+	finalCounts := []int{}
+	for _, node := range nodes {
+		finalCounts = append(finalCounts, node.Count+r[node.Nodename])
+	}
+	sort.Ints(finalCounts)
+	assert.ElementsMatch(t, []int{9, 9, 9, 9}, finalCounts)
+	// infos not changed
+	assert.Equal(t, 10, nodes[0].Capacity)
+
+	// no room after reserved
+	_, err = FillReservePlan(3)(deployedNodes(), 10, 0, 0)
+	assert.True(t, errors.Is(err, types.ErrInsufficientRes))
+
+	// reserve all
+	_, err = FillReservePlan(20)(deployedNodes(), 8, 0, 1)
+	assert.True(t, errors.Is(err, types.ErrInsufficientRes))
+}
+
+func TestFillByStoragePlan(t *testing.T) {
+	// cpu rich but storage poor nodes got small capacity from storage plan
+	infos := []Info{
+		{Nodename: "n1", Capacity: 2, Storage: 20},
+		{Nodename: "n2", Capacity: 10, Storage: 100},
+		{Nodename: "n3", Capacity: 1, Storage: 10},
+		{Nodename: "n4", Capacity: 5, Storage: 50},
+	}
+	r, err := FillByStoragePlan(infos, 3, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n2": 3, "n4": 3}, r)
+
+	// storage poor nodes can't be filled
+	_, err = FillByStoragePlan(infos, 3, 0, 3)
+	assert.True(t, errors.Is(err, types.ErrInsufficientRes))
+
+	r, err = FillByStoragePlan(infos, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n1": 1, "n2": 1, "n3": 1, "n4": 1}, r)
+}
@@ -0,0 +1,55 @@
+package strategy
+
+import (
+	"github.com/projecteru2/core/log"
+)
+
+// PreferPlan layers soft affinity on plan
+// each preferred node with capacity takes one workload in order, the rest are left to plan
+// preferred nodes full or missing are skipped, preference never fails a deploy
+func PreferPlan(preferred []string, plan startegyFunc) startegyFunc {
+	return func(infos []Info, need, total, limit int) (map[string]int, error) {
+		log.Debugf("[PreferPlan] preferred %v need %d total %d", preferred, need, total)
+		rest := make([]Info, len(infos))
+		copy(rest, infos)
+		index := map[string]int{}
+		for i := range rest {
+			index[rest[i].Nodename] = i
+		}
+
+		deployMap := map[string]int{}
+		for _, nodename := range preferred {
+			i, ok := index[nodename]
+			if need == 0 {
+				break
+			}
+			if !ok || rest[i].Capacity <= 0 || deployMap[nodename] > 0 {
+				continue
+			}
+			deployMap[nodename]++
+			rest[i].Capacity--
+			rest[i].Count++
+			need--
+			total--
+		}
+		if need == 0 {
+			return deployMap, nil
+		}
+
+		// plans count on nodes with capacity only
+		available := []Info{}
+		for _, info := range rest {
+			if info.Capacity > 0 {
+				available = append(available, info)
+			}
+		}
+		restMap, err := plan(available, need, total, limit)
+		if err != nil {
+			return nil, err
+		}
+		for nodename, count := range restMap {
+			deployMap[nodename] += count
+		}
+		return deployMap, nil
+	}
+}
@@ -0,0 +1,47 @@
+package strategy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreferPlan(t *testing.T) {
+	// preferred ones first, the rest by plan counting them in
+	r, err := PreferPlan([]string{"n4", "n3"}, CommunismPlan)(deployedNodes(), 4, 40, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n1": 1, "n2": 1, "n3": 1, "n4": 1}, r)
+
+	// need met by preferred ones
+	r, err = PreferPlan([]string{"n4", "n3"}, CommunismPlan)(deployedNodes(), 1, 40, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"n4": 1}, r)
+
+	// full or missing preferred nodes fall back to plan
+	nodes := deployedNodes()
+	nodes[3].Capacity = 0
+	r, err = PreferPlan([]string{"n4", "n5"}, CommunismPlan)(nodes, 2, 30, 0)
+	assert.NoError(t, err)
+	assert.NotContains(t, r, "n4")
+	assert.NotContains(t, r, "n5")
+	assert.Equal(t, 2, sumDeployMap(r))
+
+	// infos of caller are not touched
+	nodes = deployedNodes()
+	_, err = PreferPlan([]string{"n1"}, CommunismPlan)(nodes, 2, 40, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, deployedNodes(), nodes)
+
+	// insufficient by plan
+	_, err = PreferPlan([]string{"n1"}, CommunismPlan)(deployedNodes(), 41, 40, 0)
+	assert.True(t, errors.Is(err, types.ErrInsufficientRes))
+}
+
+func sumDeployMap(deployMap map[string]int) (sum int) {
+	for _, count := range deployMap {
+		sum += count
+	}
+	return
+}
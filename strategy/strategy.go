@@ -18,15 +18,46 @@ const (
 	Each = "EACH"
 	// Global .
 	Global = "GLOBAL"
+	// FillReserve .
+	FillReserve = "FILL_RESERVE"
+	// AntiAffinity .
+	AntiAffinity = "ANTI_AFFINITY"
+	// FillByStorage .
+	FillByStorage = "FILL_BY_STORAGE"
+	// WeightedAverage .
+	WeightedAverage = "WEIGHTED_EACH"
 	// Dummy for calculate capacity
 	Dummy = "DUMMY"
 )
 
 var Plans = map[string]startegyFunc{
-	Auto:   CommunismPlan,
-	Fill:   FillPlan,
-	Each:   AveragePlan,
-	Global: GlobalPlan,
+	Auto:            CommunismPlan,
+	Fill:            FillPlan,
+	Each:            AveragePlan,
+	Global:          GlobalPlan,
+	FillByStorage:   FillByStoragePlan,
+	WeightedAverage: WeightedAveragePlan,
+}
+
+// OptionPlans are plans depending on deploy options
+var OptionPlans = map[string]func(*types.DeployOptions) startegyFunc{
+	FillReserve:  func(opts *types.DeployOptions) startegyFunc { return FillReservePlan(opts.ReserveCount) },
+	AntiAffinity: func(opts *types.DeployOptions) startegyFunc { return AntiAffinityPlan(opts.SpreadKey) },
+}
+
+// perNodeNeed are strategies taking need as count on each node, preference makes no sense for them
+var perNodeNeed = map[string]bool{
+	Each:          true,
+	Fill:          true,
+	FillByStorage: true,
+	FillReserve:   true,
+}
+
+// Known tells whether strategy can be used for deploying
+func Known(strategy string) bool {
+	_, ok := Plans[strategy]
+	_, optionOK := OptionPlans[strategy]
+	return ok || optionOK
 }
 
 type startegyFunc = func(_ []Info, need, total, limit int) (map[string]int, error)
@@ -35,7 +66,17 @@ type startegyFunc = func(_ []Info, need, total, limit int) (map[string]int, erro
 func Deploy(opts *types.DeployOptions, strategyInfos []Info, total int) (map[string]int, error) {
 	deployMethod, ok := Plans[opts.DeployStrategy]
 	if !ok {
-		return nil, errors.WithStack(types.ErrBadDeployStrategy)
+		makePlan, ok := OptionPlans[opts.DeployStrategy]
+		if !ok {
+			return nil, errors.WithStack(types.ErrBadDeployStrategy)
+		}
+		deployMethod = makePlan(opts)
+	}
+	if opts.DeployStrategy == Each && len(opts.NodeWeights) > 0 {
+		deployMethod = ScoredAveragePlan(opts.NodeWeights)
+	}
+	if len(opts.PreferredNodes) > 0 && !perNodeNeed[opts.DeployStrategy] {
+		deployMethod = PreferPlan(opts.PreferredNodes, deployMethod)
 	}
 
 	return deployMethod(strategyInfos, opts.Count, total, opts.NodesLimit)
@@ -44,12 +85,15 @@ func Deploy(opts *types.DeployOptions, strategyInfos []Info, total int) (map[str
 // Info .
 type Info struct {
 	Nodename string
+	Labels   map[string]string
 
 	Usage float64
 	Rate  float64
 
 	Capacity int
 	Count    int
+	// free storage of the node
+	Storage int64
 }
 
 // NewInfos .
@@ -66,9 +110,11 @@ func NewInfos(resourceRequests resourcetypes.ResourceRequests, nodeMap map[strin
 
 		strategyInfos = append(strategyInfos, Info{
 			Nodename: nodename,
+			Labels:   node.Labels,
 			Rate:     resourceRequests.MainRateOnNode(*node),
 			Usage:    resourceRequests.MainUsageOnNode(*node),
 			Capacity: capacity,
+			Storage:  node.AvailableStorage(),
 		})
 	}
 	return
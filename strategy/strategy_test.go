@@ -1,6 +1,7 @@
 package strategy
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/projecteru2/core/resources"
@@ -48,6 +49,45 @@ func TestDeploy(t *testing.T) {
 	}
 	_, err = Deploy(opts, nil, 2)
 	assert.Error(t, err)
+
+	// weights route EACH to scored average
+	opts.DeployStrategy = Each
+	opts.Count = 2
+	opts.NodeWeights = map[string]float64{"disk=ssd": 1}
+	nodes := deployedNodes()
+	nodes[0].Labels = map[string]string{"disk": "ssd"}
+	r, err := Deploy(opts, nodes, 40)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, r["n1"])
+	opts.NodeWeights = nil
+
+	opts.DeployStrategy = FillReserve
+	opts.ReserveCount = 2
+	opts.Count = 9
+	opts.NodesLimit = 0
+	r, err = Deploy(opts, deployedNodes(), 40)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, r["n2"])
+	assert.Equal(t, 2, r["n4"])
+
+	opts.DeployStrategy = WeightedAverage
+	opts.Count = 3
+	r, err = Deploy(opts, genNodesByCapCount([]int{1, 2, 4}, []int{0, 0, 0}), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"1": 1, "2": 2}, r)
+
+	// preferred nodes layered, skipped by per node strategies
+	opts.PreferredNodes = []string{"1"}
+	r, err = Deploy(opts, genNodesByCapCount([]int{1, 2, 4}, []int{0, 0, 0}), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r["1"])
+	assert.Equal(t, 3, sumDeployMap(r))
+	opts.DeployStrategy = FillReserve
+	opts.Count = 9
+	r, err = Deploy(opts, deployedNodes(), 40)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, r["n2"])
+	assert.Equal(t, 2, r["n4"])
 }
 
 func TestNewInfos(t *testing.T) {
@@ -61,4 +101,20 @@ func TestNewInfos(t *testing.T) {
 	mockPlan.On("Capacity").Return(map[string]int{"node1": 1})
 	plans := []resourcetypes.ResourcePlans{mockPlan}
 	NewInfos(rrs, nodeMap, plans)
+
+	// capacity limited by storage plan and free storage recorded
+	nodeMap = map[string]*types.Node{
+		"node1": {NodeMeta: types.NodeMeta{StorageCap: 10, InitStorageCap: 100}},
+		"node2": {NodeMeta: types.NodeMeta{StorageCap: 100, InitStorageCap: 100}},
+	}
+	cpuPlan := &resourcetypesmocks.ResourcePlans{}
+	cpuPlan.On("Capacity").Return(map[string]int{"node1": 100, "node2": 100})
+	storagePlan := &resourcetypesmocks.ResourcePlans{}
+	storagePlan.On("Capacity").Return(map[string]int{"node1": 1, "node2": 10})
+	infos := NewInfos(rrs, nodeMap, []resourcetypes.ResourcePlans{cpuPlan, storagePlan})
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Nodename < infos[j].Nodename })
+	assert.Equal(t, 1, infos[0].Capacity)
+	assert.Equal(t, int64(10), infos[0].Storage)
+	assert.Equal(t, 10, infos[1].Capacity)
+	assert.Equal(t, int64(100), infos[1].Storage)
 }
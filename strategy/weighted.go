@@ -0,0 +1,59 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/log"
+	"github.com/projecteru2/core/types"
+)
+
+// WeightedAveragePlan deploy workloads in proportion to node capacity
+// 按每台机器剩余可部署容量的比例分配 need 个实例, 大机器多放小机器少放
+// 按比例取整后剩下的实例按余数从大到小补齐, 余数相同的容量大者优先, 再按节点名
+// limit 为 0 时对所有节点分配, 否则只在容量最大的 limit 台上分配
+func WeightedAveragePlan(infos []Info, need, total, limit int) (map[string]int, error) {
+	log.Debugf("[WeightedAveragePlan] need %d total %d limit %d infos %+v", need, total, limit, infos)
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Capacity == infos[j].Capacity {
+			return infos[i].Nodename < infos[j].Nodename
+		}
+		return infos[i].Capacity > infos[j].Capacity
+	})
+	if limit > 0 && limit < len(infos) {
+		infos = infos[:limit]
+	}
+
+	volume := 0
+	for _, info := range infos {
+		volume += info.Capacity
+	}
+	if volume < need {
+		return nil, errors.WithStack(types.NewDetailedErr(types.ErrInsufficientRes,
+			fmt.Sprintf("need: %d, vol: %d", need, volume)))
+	}
+
+	deployMap := map[string]int{}
+	remainders := make([]int, len(infos))
+	left := need
+	for i, info := range infos {
+		share := need * info.Capacity / volume
+		remainders[i] = need * info.Capacity % volume
+		if share > 0 {
+			deployMap[info.Nodename] = share
+		}
+		left -= share
+	}
+
+	// infos are sorted by capacity and nodename, stable sort keeps that order on equal remainders
+	order := make([]int, len(infos))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return remainders[order[i]] > remainders[order[j]] })
+	for _, i := range order[:left] {
+		deployMap[infos[i].Nodename]++
+	}
+	return deployMap, nil
+}
@@ -0,0 +1,48 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedAveragePlan(t *testing.T) {
+	// proportional to capacity
+	nodes := genNodesByCapCount([]int{10, 30, 60}, []int{0, 0, 0})
+	r, err := WeightedAveragePlan(nodes, 10, 100, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 1, "1": 3, "2": 6}, r)
+
+	// remainder goes to the largest fraction
+	nodes = genNodesByCapCount([]int{1, 2, 4}, []int{0, 0, 0})
+	r, err = WeightedAveragePlan(nodes, 3, 7, 0)
+	assert.NoError(t, err)
+	// shares are 3/7, 6/7, 12/7
+	assert.Equal(t, map[string]int{"1": 1, "2": 2}, r)
+
+	// equal remainders go by nodename
+	nodes = genNodesByCapCount([]int{1, 1, 2}, []int{0, 0, 0})
+	r, err = WeightedAveragePlan(nodes, 2, 4, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 1, "2": 1}, r)
+
+	// use up all capacity
+	nodes = genNodesByCapCount([]int{1, 2, 4}, []int{0, 0, 0})
+	r, err = WeightedAveragePlan(nodes, 7, 7, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 1, "1": 2, "2": 4}, r)
+
+	// limit
+	nodes = genNodesByCapCount([]int{1, 2, 4}, []int{0, 0, 0})
+	r, err = WeightedAveragePlan(nodes, 6, 7, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"1": 2, "2": 4}, r)
+
+	// insufficient
+	nodes = genNodesByCapCount([]int{1, 2, 4}, []int{0, 0, 0})
+	_, err = WeightedAveragePlan(nodes, 8, 7, 0)
+	assert.Error(t, err)
+	nodes = genNodesByCapCount([]int{1, 2, 4}, []int{0, 0, 0})
+	_, err = WeightedAveragePlan(nodes, 7, 7, 2)
+	assert.Error(t, err)
+}
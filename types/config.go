@@ -19,6 +19,17 @@ type Config struct {
 	WALFile        string        `yaml:"wal_file" required:"true" default:"core.wal"`   // WAL file path
 	WALOpenTimeout time.Duration `yaml:"wal_open_timeout" required:"true" default:"8s"` // timeout for opening a WAL file
 
+	MaxConcurrency int           `yaml:"max_concurrency" default:"20"`  // how many nodes can be operated concurrently, 0 means unlimited
+	InspectTimeout time.Duration `yaml:"inspect_timeout" default:"10s"` // timeout for inspecting a workload, 0 means no timeout
+
+	NodeResourceCacheTTL time.Duration `yaml:"node_resource_cache_ttl"`       // ttl of cached node resource, 0 means no cache
+	ReservationTTL       time.Duration `yaml:"reservation_ttl" default:"10m"` // default ttl of reserved resources
+
+	ResourceWarnThreshold     float64 `yaml:"resource_warn_threshold" default:"0.8"`      // node resource percent to be near capacity, 0 means disabled
+	ResourceCriticalThreshold float64 `yaml:"resource_critical_threshold" default:"0.95"` // node resource percent to be at capacity, 0 means disabled
+	CPUDriftRatio             float64 `yaml:"cpu_drift_ratio"`                            // advise if live cpu usage drifts from request over this ratio, 0 means disabled
+	CPUUsedEpsilon            float64 `yaml:"cpu_used_epsilon" default:"0.001"`           // cpu used within this of the sum of requests is float noise instead of a diff, 0 means exact
+
 	Git       GitConfig     `yaml:"git"`
 	Etcd      EtcdConfig    `yaml:"etcd"`
 	Docker    DockerConfig  `yaml:"docker"`
@@ -66,7 +77,13 @@ type VirtConfig struct {
 
 // SystemdConfig is systemd config
 type SystemdConfig struct {
-	Username string `yaml:"username" default:"root"`
+	Username           string        `yaml:"username" default:"root"`
+	RestartSec         time.Duration `yaml:"restart_sec" default:"1s"`           // delay before restarting a unit
+	StartLimitInterval time.Duration `yaml:"start_limit_interval" default:"60s"` // interval to count restarts limited by on-failure:N
+	MaxConnections     int           `yaml:"max_connections" default:"4"`        // max ssh connections kept to each node
+	MaxSessions        int           `yaml:"max_sessions" default:"8"`           // max sessions multiplexed on each connection, keep it under MaxSessions of sshd
+	RequiredTargets    []string      `yaml:"required_targets"`                   // units started after and required by, failure of them fails the unit
+	WantedTargets      []string      `yaml:"wanted_targets"`                     // units started after and wanted by, missing ones are skipped, network-online.target and firewalld.service if both unset
 }
 
 // LogConfig define log type
@@ -79,6 +96,15 @@ type LogConfig struct {
 type SchedConfig struct {
 	MaxShare  int `yaml:"maxshare" required:"true" default:"-1"`   // comlpex scheduler use maxshare
 	ShareBase int `yaml:"sharebase" required:"true" default:"100"` // how many pieces for one core
+
+	MinFree FreeFloor `yaml:"min_free"` // resource kept free on every node, placements never go below
+}
+
+// FreeFloor is the least free resource of a node, 0 means no floor
+type FreeFloor struct {
+	CPU     float64 `yaml:"cpu"`     // cores
+	Memory  int64   `yaml:"memory"`  // bytes
+	Storage int64   `yaml:"storage"` // bytes
 }
 
 // AuthConfig contains authorization information for connecting to a Registry
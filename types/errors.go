@@ -3,6 +3,8 @@ package types
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 // errors
@@ -35,16 +37,28 @@ var (
 	ErrBadWorkloadID     = errors.New("workload ID must be length of 64")
 	ErrBadDeployStrategy = errors.New("deploy method not support yet")
 	ErrBadIPAddress      = errors.New("bad IP address")
+	ErrInvalidIP         = errors.New("invalid IP")
 	ErrBadSCMType        = errors.New("unknown SCM type")
 	ErrBadMemory         = errors.New("bad `Memory` value")
 	ErrBadCPU            = errors.New("bad `CPU` value")
 	ErrBadStorage        = errors.New("bad `Storage` value")
 	ErrBadVolume         = errors.New("bad `Volume` value")
 	ErrBadCount          = errors.New("bad `Count` value")
+	ErrBadLimit          = errors.New("bad `Limit` value")
+	ErrBadOffset         = errors.New("bad `Offset` value")
+	ErrBadRestartPolicy  = errors.New("bad `RestartPolicy` value")
+
+	ErrInvalidReservation = errors.New("invalid reservation")
+
+	ErrSecretNotExists = errors.New("secret not exists")
+
+	ErrSnapshotNotExists = errors.New("snapshot not exists")
 
 	ErrPodHasNodes = errors.New("pod has nodes")
 	ErrPodNoNodes  = errors.New("pod has no nodes")
 
+	ErrPodNoNetworkCapableNodes = errors.New("pod has no nodes capable of network")
+
 	ErrCannotGetEngine = errors.New("cannot get engine")
 	ErrNilEngine       = errors.New("engine is nil")
 
@@ -78,6 +92,8 @@ var (
 	ErrInvalidWorkloadName = errors.New("invalid workload name")
 
 	ErrEngineNotImplemented = errors.New("not implemented")
+	ErrEngineUnsupported    = errors.New("operation not supported by engine")
+	ErrEngineUnreachable    = errors.New("engine unreachable")
 
 	ErrNodeNotExists     = errors.New("node not exists")
 	ErrWorkloadNotExists = errors.New("workload not exists")
@@ -92,6 +108,49 @@ func NewDetailedErr(err error, details interface{}) error {
 	return fmt.Errorf("%w: %v", err, details)
 }
 
+// CapacityShortfall tells why nodes can't satisfy a deploy
+// Rejections are keyed by nodename
+type CapacityShortfall struct {
+	Cause      error
+	Rejections map[string]string
+}
+
+// Error .
+func (c *CapacityShortfall) Error() string {
+	nodenames := []string{}
+	for nodename := range c.Rejections {
+		nodenames = append(nodenames, nodename)
+	}
+	sort.Strings(nodenames)
+	msgs := []string{}
+	for _, nodename := range nodenames {
+		msgs = append(msgs, fmt.Sprintf("node %s rejected: %s", nodename, c.Rejections[nodename]))
+	}
+	return fmt.Sprintf("%v: %s", c.Cause, strings.Join(msgs, "; "))
+}
+
+// Unwrap .
+func (c *CapacityShortfall) Unwrap() error {
+	return c.Cause
+}
+
+// LockError tells acquiring a lock failed, e.g. timed out by contention
+// message is kept as the cause
+type LockError struct {
+	Name  string
+	Cause error
+}
+
+// Error .
+func (l *LockError) Error() string {
+	return l.Cause.Error()
+}
+
+// Unwrap .
+func (l *LockError) Unwrap() error {
+	return l.Cause
+}
+
 // validation errors
 var (
 	ErrEmptyNodeName     = errors.New("node name is empty")
@@ -101,10 +160,14 @@ var (
 	ErrEmptyImage        = errors.New("image is empty")
 	ErrEmptyCount        = errors.New("count is 0")
 	ErrEmptyWorkloadID   = errors.New("workload id is empty")
+	ErrEmptyNetworkName  = errors.New("network name is empty")
+	ErrEndpointRemains   = errors.New("network endpoint remains after disconnect")
 
 	ErrEmptyEntrypointName       = errors.New("entrypoint name is empty")
 	ErrUnderlineInEntrypointName = errors.New("entrypoint name has '_' character")
 
 	ErrNoFilesToSend = errors.New("no files to send")
 	ErrNoFilesToCopy = errors.New("no files to copy")
+
+	ErrForceFix = errors.New("can't fix with resource read without lock")
 )
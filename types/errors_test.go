@@ -2,6 +2,7 @@ package types
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -17,3 +18,19 @@ func TestDetailedErr(t *testing.T) {
 	assert.True(t, errors.Is(dt, err))
 	assert.True(t, strings.Contains(dt.Error(), detail))
 }
+
+func TestCapacityShortfall(t *testing.T) {
+	var err error = &CapacityShortfall{
+		Cause: NewDetailedErr(ErrInsufficientRes, "need 3"),
+		Rejections: map[string]string{
+			"n2": "insufficient memory",
+			"n1": "insufficient cpu",
+		},
+	}
+	assert.True(t, errors.Is(err, ErrInsufficientRes))
+	assert.Equal(t, "not enough resource: need 3: node n1 rejected: insufficient cpu; node n2 rejected: insufficient memory", err.Error())
+
+	var shortfall *CapacityShortfall
+	assert.True(t, errors.As(fmt.Errorf("wrapped: %w", err), &shortfall))
+	assert.Len(t, shortfall.Rejections, 2)
+}
@@ -130,6 +130,46 @@ type LogStreamMessage struct {
 type CapacityMessage struct {
 	Total          int
 	NodeCapacities map[string]int
+	Strategy       string
+}
+
+// BatchCapacity for CalculateBatchCapacity API output
+// items are kept in order of request, Shortfalls are keyed by index of item
+type BatchCapacity struct {
+	Placements []map[string]int // nodename to count of each item, nil if the item can't be placed
+	Shortfalls map[int]string   // why the item can't be placed
+}
+
+// Feasible means every item is placed
+func (b *BatchCapacity) Feasible() bool {
+	return len(b.Shortfalls) == 0
+}
+
+// NetworkUsage for NetworkUsage API output
+// one record for each subnet of a network
+type NetworkUsage struct {
+	Network string
+	Subnet  string
+	Total   uint64
+	Used    uint64
+	Free    uint64
+}
+
+// NetworkWorkloads for NetworkWorkloads API output
+type NetworkWorkloads struct {
+	Network   string
+	Workloads []*NetworkEndpoint
+	// endpoints not matching any workload in store
+	Orphans []*NetworkEndpoint
+}
+
+// NetworkEndpoint is an endpoint connected to a network
+// Workload is nil for orphans
+type NetworkEndpoint struct {
+	ID        string
+	Nodename  string
+	Addresses []string
+	Workload  *Workload
 }
 
 type errorDetail struct {
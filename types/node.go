@@ -2,8 +2,10 @@ package types
 
 import (
 	"context"
-
 	"math"
+	"sort"
+	"strconv"
+	"time"
 
 	engine "github.com/projecteru2/core/engine"
 	enginetypes "github.com/projecteru2/core/engine/types"
@@ -22,6 +24,9 @@ type NUMA map[string]string
 // NUMAMemory fine NUMA memory NODE
 type NUMAMemory map[string]int64
 
+// StoragePools is storage of each pool on node, by pool name
+type StoragePools map[string]int64
+
 // NodeMeta .
 type NodeMeta struct {
 	Name     string            `json:"name"`
@@ -40,6 +45,13 @@ type NodeMeta struct {
 	InitStorageCap int64      `json:"init_storage_cap"`
 	InitNUMAMemory NUMAMemory `json:"init_numa_memory"`
 	InitVolume     VolumeMap  `json:"init_volume"`
+
+	// pieces of one core on this node, 0 means the global share base
+	ShareBase int `json:"share_base,omitempty"`
+
+	// pools are part of StorageCap, workloads bound to a pool take from both
+	StoragePools     StoragePools `json:"storage_pools,omitempty"`
+	InitStoragePools StoragePools `json:"init_storage_pools,omitempty"`
 }
 
 // Node store node info
@@ -50,6 +62,7 @@ type Node struct {
 	VolumeUsed int64   `json:"volumeused"`
 
 	Available bool       `json:"available"`
+	Cordoned  bool       `json:"cordoned,omitempty"` // excluded from new placements, workloads keep running
 	Engine    engine.API `json:"-"`
 }
 
@@ -122,6 +135,28 @@ func (n *Node) DecrNUMANodeMemory(nodeID string, memory int64) {
 	}
 }
 
+// GetShareBase returns share base of node, global one if not set
+func (n NodeMeta) GetShareBase(global int) int {
+	if n.ShareBase > 0 {
+		return n.ShareBase
+	}
+	return global
+}
+
+// IncrStoragePool set storage pool capacity
+func (n *Node) IncrStoragePool(pool string, storage int64) {
+	if _, ok := n.StoragePools[pool]; ok {
+		n.StoragePools[pool] += storage
+	}
+}
+
+// DecrStoragePool set storage pool capacity
+func (n *Node) DecrStoragePool(pool string, storage int64) {
+	if _, ok := n.StoragePools[pool]; ok {
+		n.StoragePools[pool] -= storage
+	}
+}
+
 // StorageUsage calculates node's storage usage ratio.
 func (n *Node) StorageUsage() float64 {
 	switch {
@@ -152,6 +187,17 @@ func (n *Node) AvailableStorage() int64 {
 	}
 }
 
+// CPUFragmentation counts cores partially used
+// which can't host a full-core workload
+func (n *Node) CPUFragmentation() (count int) {
+	for core, share := range n.CPU {
+		if share > 0 && share < n.InitCPU[core] {
+			count++
+		}
+	}
+	return
+}
+
 // ResourceUsages .
 func (n *Node) ResourceUsages() map[ResourceType]float64 {
 	return map[ResourceType]float64{
@@ -173,6 +219,9 @@ func (n *Node) RecycleResources(resource *ResourceMeta) {
 	if resource.NUMANode != "" {
 		n.IncrNUMANodeMemory(resource.NUMANode, resource.MemoryRequest)
 	}
+	if resource.StoragePool != "" {
+		n.IncrStoragePool(resource.StoragePool, resource.StorageRequest)
+	}
 }
 
 // PreserveResources .
@@ -186,21 +235,245 @@ func (n *Node) PreserveResources(resource *ResourceMeta) {
 	if resource.NUMANode != "" {
 		n.DecrNUMANodeMemory(resource.NUMANode, resource.MemoryRequest)
 	}
+	if resource.StoragePool != "" {
+		n.DecrStoragePool(resource.StoragePool, resource.StorageRequest)
+	}
 }
 
 // NodeResource for node check
 type NodeResource struct {
-	Name              string
-	CPU               CPUMap
-	MemCap            int64
-	StorageCap        int64
-	CPUPercent        float64
-	MemoryPercent     float64
-	StoragePercent    float64
-	NUMAMemoryPercent map[string]float64
-	VolumePercent     float64
-	Diffs             []string
-	Workloads         []*Workload
+	Name               string
+	CPU                CPUMap
+	MemCap             int64
+	StorageCap         int64
+	CPUPercent         float64
+	MemoryPercent      float64
+	StoragePercent     float64
+	NUMAMemoryPercent  map[string]float64
+	StoragePoolPercent map[string]float64
+	MemoryOvercommit   float64 // sum of memory limits over init memory, burstable workloads risk OOM beyond 1
+	VolumePercent      float64
+	CPUFragmentation   int
+	EngineType         string
+	EngineVersion      string
+	EngineReachable    bool
+	LockFree           bool // read without node lock, may be inconsistent
+	NearCapacity       bool // any percent reaches warn threshold
+	AtCapacity         bool // any percent reaches critical threshold
+	Overcommitted      bool // any percent exceeds 1, accounting is broken
+	Diffs              []string
+	StructuredDiffs    []ResourceDiff // same diffs as Diffs, for automation
+	ResidualDiffs      []string       // diffs still found after fixing, empty means the fix converged
+	Advisories         []string       // not accounting errors, e.g. cpu usage drifts from request
+	Workloads          []*Workload
+	WorkloadsResource  map[string]*WorkloadResource
+	FixPlan            *ResourceFixPlan
+}
+
+// ResourceDiff is a diff of node resource in structured form
+// Expected is recomputed from workloads, Actual is recorded on node, Delta is Expected - Actual
+type ResourceDiff struct {
+	Dimension  string // cpu, cpu:<core>, memory, numa_memory:<numa node>, storage, volume, workload, init_*, or resource reported by engine
+	Expected   float64
+	Actual     float64
+	Delta      float64
+	WorkloadID string // only for diffs caused by one workload
+}
+
+// AddDiff records diff in both forms, text is kept for backward compatibility
+func (nr *NodeResource) AddDiff(diff ResourceDiff, text string) {
+	diff.Delta = diff.Expected - diff.Actual
+	nr.Diffs = append(nr.Diffs, text)
+	nr.StructuredDiffs = append(nr.StructuredDiffs, diff)
+}
+
+// DrainPlan tells whether workloads on a node can be rescheduled to the rest of its pod
+// CPU, Memory, Storage and Volume are total requests of the workloads
+// Targets and Shortfalls are keyed by workload ID
+type DrainPlan struct {
+	Nodename   string
+	CPU        float64
+	Memory     int64
+	Storage    int64
+	Volume     int64
+	Targets    map[string]string // nodename the workload would move to
+	Shortfalls map[string]string // why the workload can't be placed
+}
+
+// Feasible means every workload has a target
+func (p *DrainPlan) Feasible() bool {
+	return len(p.Shortfalls) == 0
+}
+
+// MarkCapacity sets capacity flags by percents, threshold 0 means disabled
+func (nr *NodeResource) MarkCapacity(warn, critical float64) {
+	percents := []float64{nr.CPUPercent, nr.MemoryPercent, nr.StoragePercent, nr.VolumePercent}
+	// NUMAMemoryPercent is of free memory
+	for _, percent := range nr.NUMAMemoryPercent {
+		percents = append(percents, 1-percent)
+	}
+	for _, percent := range percents {
+		nr.NearCapacity = nr.NearCapacity || warn > 0 && percent >= warn
+		nr.AtCapacity = nr.AtCapacity || critical > 0 && percent >= critical
+		nr.Overcommitted = nr.Overcommitted || percent > 1
+	}
+}
+
+// WorkloadResource for workload resource usage on node
+type WorkloadResource struct {
+	ID              string
+	CPUQuotaRequest float64
+	MemoryRequest   int64
+	MemoryLimit     int64
+	StorageRequest  int64
+	VolumeRequest   int64
+}
+
+// ResourceFixPlan records what fixing a node's resource will change
+// CPU, NUMAMemory, MemCap, StorageCap and StoragePools are deltas, CPUUsed and VolumeUsed are final values
+type ResourceFixPlan struct {
+	Nodename     string
+	CPUUsed      float64
+	VolumeUsed   int64
+	CPU          CPUMap
+	NUMAMemory   NUMAMemory
+	MemCap       int64
+	StorageCap   int64
+	StoragePools StoragePools
+}
+
+// Apply applies plan on node
+func (p *ResourceFixPlan) Apply(node *Node) {
+	node.CPUUsed = p.CPUUsed
+	node.VolumeUsed = p.VolumeUsed
+	for i, v := range p.CPU {
+		node.CPU[i] += v
+	}
+	if node.NUMAMemory == nil && len(p.NUMAMemory) > 0 {
+		node.NUMAMemory = NUMAMemory{}
+	}
+	for nodeID, v := range p.NUMAMemory {
+		node.NUMAMemory[nodeID] += v
+	}
+	node.MemCap += p.MemCap
+	node.StorageCap += p.StorageCap
+	for pool, v := range p.StoragePools {
+		node.IncrStoragePool(pool, v)
+	}
+}
+
+// ResourceFixRecord is the audit record of fixing a node's resource
+// MemCap and StorageCap are deltas
+type ResourceFixRecord struct {
+	Nodename   string    `json:"nodename"`
+	Time       time.Time `json:"time"`
+	Operator   string    `json:"operator"`
+	OldCPUUsed float64   `json:"old_cpu_used"`
+	NewCPUUsed float64   `json:"new_cpu_used"`
+	MemCap     int64     `json:"memcap"`
+	StorageCap int64     `json:"storage_cap"`
+}
+
+// NodeResourceSnapshot is node resource saved at some time, to be diffed with later
+type NodeResourceSnapshot struct {
+	ID                 string             `json:"id"`
+	Nodename           string             `json:"nodename"`
+	Time               time.Time          `json:"time"`
+	CPUPercent         float64            `json:"cpu_percent"`
+	MemoryPercent      float64            `json:"memory_percent"`
+	StoragePercent     float64            `json:"storage_percent"`
+	VolumePercent      float64            `json:"volume_percent"`
+	NUMAMemoryPercent  map[string]float64 `json:"numa_memory_percent,omitempty"`
+	StoragePoolPercent map[string]float64 `json:"storage_pool_percent,omitempty"`
+	WorkloadIDs        []string           `json:"workload_ids"`
+	Diffs              []string           `json:"diffs"`
+}
+
+// NewNodeResourceSnapshot snapshots node resource, ID is made of time
+func NewNodeResourceSnapshot(nr *NodeResource, t time.Time) *NodeResourceSnapshot {
+	snapshot := &NodeResourceSnapshot{
+		ID:                 strconv.FormatInt(t.UnixNano(), 10),
+		Nodename:           nr.Name,
+		Time:               t,
+		CPUPercent:         nr.CPUPercent,
+		MemoryPercent:      nr.MemoryPercent,
+		StoragePercent:     nr.StoragePercent,
+		VolumePercent:      nr.VolumePercent,
+		NUMAMemoryPercent:  nr.NUMAMemoryPercent,
+		StoragePoolPercent: nr.StoragePoolPercent,
+		WorkloadIDs:        []string{},
+		Diffs:              nr.Diffs,
+	}
+	for _, workload := range nr.Workloads {
+		snapshot.WorkloadIDs = append(snapshot.WorkloadIDs, workload.ID)
+	}
+	sort.Strings(snapshot.WorkloadIDs)
+	return snapshot
+}
+
+// NodeResourceDelta is the change of node resource since a snapshot
+// percents are current ones minus those of snapshot
+type NodeResourceDelta struct {
+	Nodename           string
+	SnapshotID         string
+	Since              time.Time
+	AddedWorkloads     []string
+	RemovedWorkloads   []string
+	CPUPercent         float64
+	MemoryPercent      float64
+	StoragePercent     float64
+	VolumePercent      float64
+	NUMAMemoryPercent  map[string]float64
+	StoragePoolPercent map[string]float64
+	NewDiffs           []string // diffs not found at snapshot
+}
+
+// Diff computes delta from snapshot to current
+func (s *NodeResourceSnapshot) Diff(current *NodeResourceSnapshot) *NodeResourceDelta {
+	delta := &NodeResourceDelta{
+		Nodename:           s.Nodename,
+		SnapshotID:         s.ID,
+		Since:              s.Time,
+		AddedWorkloads:     missingStrings(current.WorkloadIDs, s.WorkloadIDs),
+		RemovedWorkloads:   missingStrings(s.WorkloadIDs, current.WorkloadIDs),
+		CPUPercent:         current.CPUPercent - s.CPUPercent,
+		MemoryPercent:      current.MemoryPercent - s.MemoryPercent,
+		StoragePercent:     current.StoragePercent - s.StoragePercent,
+		VolumePercent:      current.VolumePercent - s.VolumePercent,
+		NUMAMemoryPercent:  percentsDelta(s.NUMAMemoryPercent, current.NUMAMemoryPercent),
+		StoragePoolPercent: percentsDelta(s.StoragePoolPercent, current.StoragePoolPercent),
+		NewDiffs:           missingStrings(current.Diffs, s.Diffs),
+	}
+	return delta
+}
+
+// missingStrings returns those of a not in b, in order of a
+func missingStrings(a, b []string) []string {
+	in := map[string]bool{}
+	for _, s := range b {
+		in[s] = true
+	}
+	result := []string{}
+	for _, s := range a {
+		if !in[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// percentsDelta treats keys missing on either side as 0
+func percentsDelta(before, after map[string]float64) map[string]float64 {
+	delta := map[string]float64{}
+	for key, value := range after {
+		delta[key] = value - before[key]
+	}
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			delta[key] = -value
+		}
+	}
+	return delta
 }
 
 // NodeStatus wraps node status
@@ -5,6 +5,7 @@ import (
 	"math"
 	"reflect"
 	"testing"
+	"time"
 
 	enginemocks "github.com/projecteru2/core/engine/mocks"
 	enginetypes "github.com/projecteru2/core/engine/types"
@@ -150,3 +151,101 @@ func TestNodeWithResource(t *testing.T) {
 	assert.EqualValues(t, 0, n.StorageCap)
 	assert.EqualValues(t, 0, n.VolumeUsed)
 }
+
+func TestResourceFixPlanApply(t *testing.T) {
+	n := &Node{
+		NodeMeta: NodeMeta{
+			CPU:        CPUMap{"0": 0, "1": 10},
+			MemCap:     2,
+			StorageCap: 3,
+			NUMAMemory: NUMAMemory{"0": 1},
+		},
+		CPUUsed:    1,
+		VolumeUsed: 10,
+	}
+	plan := &ResourceFixPlan{
+		CPUUsed:    1.8,
+		VolumeUsed: 5,
+		CPU:        CPUMap{"1": 10},
+		NUMAMemory: NUMAMemory{"0": 2},
+		MemCap:     1,
+		StorageCap: -1,
+	}
+	plan.Apply(n)
+	assert.EqualValues(t, 1.8, n.CPUUsed)
+	assert.EqualValues(t, 5, n.VolumeUsed)
+	assert.EqualValues(t, 3, n.NUMAMemory["0"])
+	assert.True(t, reflect.DeepEqual(n.CPU, CPUMap{"0": 0, "1": 20}))
+	assert.EqualValues(t, 3, n.MemCap)
+	assert.EqualValues(t, 2, n.StorageCap)
+}
+
+func TestCPUFragmentation(t *testing.T) {
+	node := &Node{
+		NodeMeta: NodeMeta{
+			CPU:     CPUMap{"0": 100, "1": 30, "2": 0, "3": 99},
+			InitCPU: CPUMap{"0": 100, "1": 100, "2": 100, "3": 100},
+		},
+	}
+	assert.Equal(t, 2, node.CPUFragmentation())
+}
+
+func TestNodeResourceMarkCapacity(t *testing.T) {
+	nr := &NodeResource{CPUPercent: 0.5, MemoryPercent: 0.3, NUMAMemoryPercent: map[string]float64{"0": 1}}
+	nr.MarkCapacity(0.8, 0.95)
+	assert.False(t, nr.NearCapacity)
+	assert.False(t, nr.AtCapacity)
+	assert.False(t, nr.Overcommitted)
+
+	nr = &NodeResource{CPUPercent: 0.5, NUMAMemoryPercent: map[string]float64{"0": 0.15}}
+	nr.MarkCapacity(0.8, 0.95)
+	assert.True(t, nr.NearCapacity)
+	assert.False(t, nr.AtCapacity)
+	assert.False(t, nr.Overcommitted)
+
+	nr = &NodeResource{StoragePercent: 1.2}
+	nr.MarkCapacity(0.8, 0.95)
+	assert.True(t, nr.NearCapacity)
+	assert.True(t, nr.AtCapacity)
+	assert.True(t, nr.Overcommitted)
+
+	// disabled thresholds
+	nr = &NodeResource{VolumePercent: 1}
+	nr.MarkCapacity(0, 0)
+	assert.False(t, nr.NearCapacity)
+	assert.False(t, nr.AtCapacity)
+	assert.False(t, nr.Overcommitted)
+}
+
+func TestNodeResourceSnapshotDiff(t *testing.T) {
+	now := time.Now()
+	before := NewNodeResourceSnapshot(&NodeResource{
+		Name:              "n1",
+		CPUPercent:        0.5,
+		MemoryPercent:     0.2,
+		NUMAMemoryPercent: map[string]float64{"0": 0.1, "1": 0.3},
+		Workloads:         []*Workload{{ID: "w2"}, {ID: "w1"}},
+		Diffs:             []string{"cpus used: 1"},
+	}, now.Add(-time.Hour))
+	assert.Equal(t, []string{"w1", "w2"}, before.WorkloadIDs)
+	after := NewNodeResourceSnapshot(&NodeResource{
+		Name:               "n1",
+		CPUPercent:         0.75,
+		MemoryPercent:      0.2,
+		NUMAMemoryPercent:  map[string]float64{"0": 0.2},
+		StoragePoolPercent: map[string]float64{"ssd": 0.5},
+		Workloads:          []*Workload{{ID: "w2"}, {ID: "w3"}},
+		Diffs:              []string{"cpus used: 1", "memory used: 2"},
+	}, now)
+
+	delta := before.Diff(after)
+	assert.Equal(t, before.ID, delta.SnapshotID)
+	assert.Equal(t, []string{"w3"}, delta.AddedWorkloads)
+	assert.Equal(t, []string{"w1"}, delta.RemovedWorkloads)
+	assert.Equal(t, 0.25, delta.CPUPercent)
+	assert.Equal(t, float64(0), delta.MemoryPercent)
+	assert.InDelta(t, 0.1, delta.NUMAMemoryPercent["0"], 1e-9)
+	assert.Equal(t, -0.3, delta.NUMAMemoryPercent["1"])
+	assert.Equal(t, 0.5, delta.StoragePoolPercent["ssd"])
+	assert.Equal(t, []string{"memory used: 2"}, delta.NewDiffs)
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // TODO should validate options
 
 // DeployOptions is options for deploying
@@ -13,6 +15,7 @@ type DeployOptions struct {
 	ExtraArgs      string                   // Extra arguments to append to command
 	Count          int                      // How many workloads needed, e.g. 4
 	Env            []string                 // Env for workload
+	SecretEnv      map[string]string        // Env names and referenced secret keys, resolved from store when deploying
 	DNS            []string                 // DNS for workload
 	ExtraHosts     []string                 // Extra hosts for workload
 	Networks       map[string]string        // Network names and specified IPs
@@ -29,6 +32,11 @@ type DeployOptions struct {
 	AfterCreate    []string                 // AfterCreate support run cmds after create
 	RawArgs        []byte                   // RawArgs for raw args processing
 	Lambda         bool                     // indicate is lambda workload or not
+	ReserveCount   int                      // Reserved slots on each node, for FILL_RESERVE strategy
+	SpreadKey      string                   // Node label key to spread workloads by, for ANTI_AFFINITY strategy
+	Reservation    string                   // Token of reservation to deploy with, see ReserveOptions
+	PreferredNodes []string                 // Nodes tried first if they have capacity, ignored by EACH and FILL strategies
+	NodeWeights    map[string]float64       // Weights of node labels as label=value, biases EACH strategy to higher scored nodes
 }
 
 // Validate checks options
@@ -87,6 +95,27 @@ type ListWorkloadsOptions struct {
 	Labels     map[string]string
 }
 
+// ListNetworksOptions for list networks
+// Limit 0 means no limit
+type ListNetworksOptions struct {
+	Podname string
+	Driver  string
+	Labels  map[string]string
+	Limit   int
+	Offset  int
+}
+
+// Validate checks options
+func (o *ListNetworksOptions) Validate() error {
+	if o.Limit < 0 {
+		return NewDetailedErr(ErrBadLimit, o.Limit)
+	}
+	if o.Offset < 0 {
+		return NewDetailedErr(ErrBadOffset, o.Offset)
+	}
+	return nil
+}
+
 // ReplaceOptions for replace workload
 type ReplaceOptions struct {
 	DeployOptions
@@ -113,6 +142,27 @@ func (o *ReplaceOptions) Normalize() {
 	}
 }
 
+// ReserveOptions for reserving resources of workloads without creating them
+// TTL 0 means ReservationTTL in config
+type ReserveOptions struct {
+	DeployOptions
+	TTL time.Duration
+}
+
+// Validate doesn't check image, nothing will be pulled
+func (o *ReserveOptions) Validate() error {
+	if o.Name == "" {
+		return ErrEmptyAppName
+	}
+	if o.Podname == "" {
+		return ErrEmptyPodName
+	}
+	if o.Count <= 0 {
+		return NewDetailedErr(ErrBadCount, o.Count)
+	}
+	return o.Entrypoint.Validate()
+}
+
 // AddNodeOptions for adding node
 type AddNodeOptions struct {
 	Nodename   string
@@ -129,6 +179,8 @@ type AddNodeOptions struct {
 	Numa       NUMA
 	NumaMemory NUMAMemory
 	Volume     VolumeMap
+	// pools are part of Storage
+	StoragePools StoragePools
 }
 
 // Validate checks options
@@ -162,6 +214,7 @@ type SetNodeOptions struct {
 	DeltaVolume     VolumeMap
 	NUMA            map[string]string
 	Labels          map[string]string
+	ShareBase       int // 0 keeps the current one, cores with shares bound can't change it
 }
 
 // Validate checks options
@@ -169,9 +222,18 @@ func (o *SetNodeOptions) Validate() error {
 	if o.Nodename == "" {
 		return ErrEmptyNodeName
 	}
+	if o.ShareBase < 0 {
+		return NewDetailedErr(ErrBadCPU, "share base less than 0")
+	}
 	return nil
 }
 
+// ChangesCapacity tells whether init capacities or numa topology of node are changed
+func (o *SetNodeOptions) ChangesCapacity() bool {
+	return len(o.DeltaCPU) > 0 || o.DeltaMemory != 0 || o.DeltaStorage != 0 ||
+		len(o.DeltaNUMAMemory) > 0 || len(o.DeltaVolume) > 0 || len(o.NUMA) > 0 || o.ShareBase > 0
+}
+
 // Normalize keeps options consistent
 func (o *SetNodeOptions) Normalize(node *Node) {
 	o.DeltaStorage += o.DeltaVolume.Total()
@@ -182,6 +244,25 @@ func (o *SetNodeOptions) Normalize(node *Node) {
 	}
 }
 
+// NodeResourceOptions for getting node resource
+type NodeResourceOptions struct {
+	WithWorkloads bool // carry per-workload resource usage
+	Fix           bool // fix diffs found
+	DryRun        bool // only make the fix plan without applying it
+	SkipInspect   bool // skip inspecting workloads, only accounting diffs are returned
+	Refresh       bool // skip the cached one
+	Force         bool // read without node lock, e.g. node lock held by a stuck one
+}
+
+// Validate checks options
+// lock free read may be inconsistent, plans made on it are not trustworthy
+func (o *NodeResourceOptions) Validate() error {
+	if o.Force && (o.Fix || o.DryRun) {
+		return ErrForceFix
+	}
+	return nil
+}
+
 // ImageOptions wraps options for images
 // Prune is only used when remove image
 type ImageOptions struct {
@@ -217,6 +298,13 @@ type ExecuteWorkloadOptions struct {
 	ReplCmd    []byte
 }
 
+// NetworkAttachment is a network with optional IPs to connect
+type NetworkAttachment struct {
+	Network string
+	IPv4    string
+	IPv6    string
+}
+
 // ReallocOptions .
 type ReallocOptions struct {
 	ID           string
@@ -224,6 +312,23 @@ type ReallocOptions struct {
 	ResourceOpts ResourceOptions
 }
 
+// restart policies a workload can be updated to
+const (
+	RestartNo            = "no"
+	RestartAlways        = "always"
+	RestartOnFailure     = "on-failure"
+	RestartUnlessStopped = "unless-stopped"
+)
+
+// ValidateRestartPolicy checks restart policy for updating
+func ValidateRestartPolicy(policy string) error {
+	switch policy {
+	case RestartNo, RestartAlways, RestartOnFailure, RestartUnlessStopped:
+		return nil
+	}
+	return NewDetailedErr(ErrBadRestartPolicy, policy)
+}
+
 // TriOptions .
 type TriOptions int
 
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -151,3 +152,46 @@ func TestImageOptions(t *testing.T) {
 	o.Normalize()
 	assert.Equal(o.Step, 3)
 }
+
+func TestReserveOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	o := &ReserveOptions{DeployOptions: DeployOptions{Entrypoint: &Entrypoint{}}}
+	assert.Equal(ErrEmptyAppName, o.Validate())
+	o.Name = "testname"
+	assert.Equal(ErrEmptyPodName, o.Validate())
+	o.Podname = "testpod"
+	assert.True(errors.Is(o.Validate(), ErrBadCount))
+	o.Count = 1
+	assert.Equal(ErrEmptyEntrypointName, o.Validate())
+	o.Entrypoint.Name = "entry"
+	// image is not required
+	assert.NoError(o.Validate())
+}
+
+func TestNodeResourceOptions(t *testing.T) {
+	o := &NodeResourceOptions{Force: true, Fix: true}
+	assert.True(t, errors.Is(o.Validate(), ErrForceFix))
+	o.Fix, o.DryRun = false, true
+	assert.True(t, errors.Is(o.Validate(), ErrForceFix))
+	o.DryRun, o.SkipInspect = false, true
+	assert.NoError(t, o.Validate())
+}
+
+func TestValidateRestartPolicy(t *testing.T) {
+	for _, policy := range []string{"no", "always", "on-failure", "unless-stopped"} {
+		assert.NoError(t, ValidateRestartPolicy(policy))
+	}
+	for _, policy := range []string{"", "bad", "on-failure:3"} {
+		assert.True(t, errors.Is(ValidateRestartPolicy(policy), ErrBadRestartPolicy))
+	}
+}
+
+func TestListNetworksOptions(t *testing.T) {
+	o := &ListNetworksOptions{Limit: -1}
+	assert.True(t, errors.Is(o.Validate(), ErrBadLimit))
+	o.Limit, o.Offset = 0, -1
+	assert.True(t, errors.Is(o.Validate(), ErrBadOffset))
+	o.Offset = 0
+	assert.NoError(t, o.Validate())
+}
@@ -2,12 +2,53 @@ package types
 
 // Pod define pod
 type Pod struct {
-	Name string `json:"name"`
-	Desc string `json:"desc"`
+	Name            string `json:"name"`
+	Desc            string `json:"desc"`
+	DefaultStrategy string `json:"default_strategy,omitempty"` // used by deploys without strategy
 }
 
 // PodResource define pod resource
 type PodResource struct {
-	Name          string
-	NodesResource []*NodeResource
+	Name           string
+	NodesResource  []*NodeResource
+	EngineVersions map[string][]string // nodenames by "<type> <version>", more than one key means skew
+	Skipped        []string            // nodenames failed to lock, busy with other operations
+
+	// summed over nodes in NodesResource, percents are weighted by node capacity
+	CPU     ResourceUsage
+	Memory  ResourceUsage
+	Storage ResourceUsage
+	Volume  ResourceUsage
+}
+
+// ResourceUsage is usage of one resource dimension
+type ResourceUsage struct {
+	Total   float64
+	Used    float64
+	Percent float64
+}
+
+// Add counts in a node by its capacity and percent used
+func (u *ResourceUsage) Add(total, percent float64) {
+	u.Total += total
+	u.Used += total * percent
+	if u.Total > 0 {
+		u.Percent = u.Used / u.Total
+	}
+}
+
+// PodResourceFix is the report of fixing resource on every node of a pod
+type PodResourceFix struct {
+	Name  string
+	Nodes []*NodeResourceFix
+}
+
+// NodeResourceFix is diffs found on a node and the plan fixing them
+// Plan is not applied if dry run, Residual is diffs still found after Plan applied
+type NodeResourceFix struct {
+	Nodename string
+	Diffs    []string
+	Plan     *ResourceFixPlan
+	Residual []string
+	Error    error
 }
@@ -0,0 +1,18 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceUsage(t *testing.T) {
+	u := ResourceUsage{}
+	u.Add(0, 0)
+	assert.Equal(t, ResourceUsage{}, u)
+
+	// weighted by capacity rather than averaging percents
+	u.Add(2, 0.5)
+	u.Add(8, 1)
+	assert.Equal(t, ResourceUsage{Total: 10, Used: 9, Percent: 0.9}, u)
+}
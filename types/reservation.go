@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+// Reservation is resources held on nodes for workloads not created yet
+type Reservation struct {
+	Token     string                     `json:"token"`
+	Podname   string                     `json:"podname"`
+	Resources map[string][]*ResourceMeta `json:"resources"` // nodename -> resource of each workload
+	ExpireAt  time.Time                  `json:"expire_at"`
+}
+
+// Nodenames of reserved nodes
+func (r *Reservation) Nodenames() []string {
+	nodenames := []string{}
+	for nodename := range r.Resources {
+		nodenames = append(nodenames, nodename)
+	}
+	return nodenames
+}
+
+// Expired .
+func (r *Reservation) Expired(now time.Time) bool {
+	return !now.Before(r.ExpireAt)
+}
@@ -14,12 +14,14 @@ type ResourceOptions struct {
 
 	MemoryRequest int64
 	MemoryLimit   int64
+	NUMANode      string // pin to numa node, memory and bound cpus are taken from it only
 
 	VolumeRequest VolumeBindings
 	VolumeLimit   VolumeBindings
 
 	StorageRequest int64
 	StorageLimit   int64
+	StoragePool    string // bind to storage pool, storage is taken from it as well as the total
 }
 
 // ResourceMeta for messages and workload to store
@@ -38,8 +40,9 @@ type ResourceMeta struct {
 	VolumePlanLimit   VolumePlan     `json:"volume_plan_limit"`
 	VolumeChanged     bool           `json:"volume_changed"`
 
-	StorageRequest int64 `json:"storage_request"`
-	StorageLimit   int64 `json:"storage_limit"`
+	StorageRequest int64  `json:"storage_request"`
+	StorageLimit   int64  `json:"storage_limit"`
+	StoragePool    string `json:"storage_pool,omitempty"`
 }
 
 // ResourceType .
@@ -2,6 +2,7 @@ package types
 
 import (
 	"strings"
+	"time"
 )
 
 // Hook define hooks
@@ -17,6 +18,9 @@ type HealthCheck struct {
 	HTTPPort string   `yaml:"http_port"`
 	HTTPURL  string   `yaml:"url,omitempty"`
 	HTTPCode int      `yaml:"code,omitempty"`
+
+	WatchdogInterval time.Duration `yaml:"watchdog_interval,omitempty"`
+	Notify           bool          `yaml:"notify,omitempty"`
 }
 
 // Entrypoint is a single entrypoint
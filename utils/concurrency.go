@@ -0,0 +1,22 @@
+package utils
+
+import "sync"
+
+// Parallel calls f for every index in [0, n) concurrently and waits for all of them
+// at most max calls run at the same time, 0 or less means unlimited
+func Parallel(n, max int, f func(i int)) {
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, Max(max, 0))
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if cap(sem) > 0 {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallel(t *testing.T) {
+	var running, peak int32
+	done := make([]bool, 10)
+	Parallel(len(done), 3, func(i int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		done[i] = true
+	})
+	assert.True(t, peak <= 3)
+	for _, d := range done {
+		assert.True(t, d)
+	}
+
+	// unlimited
+	count := int32(0)
+	Parallel(5, 0, func(int) { atomic.AddInt32(&count, 1) })
+	assert.Equal(t, int32(5), count)
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/projecteru2/core/types"
+)
+
+type promSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// FormatNodeResourceMetrics renders node resource as prometheus exposition text
+// samples are labeled by podname and nodename, map percents get one more label of their key
+func FormatNodeResourceMetrics(podname string, nr *types.NodeResource) string {
+	base := [][2]string{{"podname", podname}, {"nodename", nr.Name}}
+	gauge := func(value float64, extra ...[2]string) []promSample {
+		return []promSample{{labels: append(append([][2]string{}, base...), extra...), value: value}}
+	}
+	keyed := func(label string, values map[string]float64) []promSample {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		samples := []promSample{}
+		for _, key := range keys {
+			samples = append(samples, gauge(values[key], [2]string{label, key})...)
+		}
+		return samples
+	}
+
+	b := &strings.Builder{}
+	writePromGauge(b, "node_resource_cpu_percent", "cpu used over init cpu.", gauge(nr.CPUPercent))
+	writePromGauge(b, "node_resource_memory_percent", "memory used over init memory.", gauge(nr.MemoryPercent))
+	writePromGauge(b, "node_resource_memory_overcommit", "memory limits over init memory.", gauge(nr.MemoryOvercommit))
+	writePromGauge(b, "node_resource_storage_percent", "storage used over init storage.", gauge(nr.StoragePercent))
+	writePromGauge(b, "node_resource_volume_percent", "volume used over init volume.", gauge(nr.VolumePercent))
+	writePromGauge(b, "node_resource_numa_memory_percent", "memory used over init memory of numa node.", keyed("numa_node", nr.NUMAMemoryPercent))
+	writePromGauge(b, "node_resource_storage_pool_percent", "storage used over init storage of pool.", keyed("pool", nr.StoragePoolPercent))
+	writePromGauge(b, "node_resource_diffs", "count of resource diffs.", gauge(float64(len(nr.Diffs))))
+	return b.String()
+}
+
+func writePromGauge(b *strings.Builder, name, help string, samples []promSample) {
+	if len(samples) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	for _, sample := range samples {
+		labels := make([]string, 0, len(sample.labels))
+		for _, label := range sample.labels {
+			labels = append(labels, fmt.Sprintf("%s=\"%s\"", label[0], escapePromLabel(label[1])))
+		}
+		fmt.Fprintf(b, "%s{%s} %s\n", name, strings.Join(labels, ","), strconv.FormatFloat(sample.value, 'g', -1, 64))
+	}
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapePromLabel(value string) string {
+	return promLabelEscaper.Replace(value)
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatNodeResourceMetrics(t *testing.T) {
+	nr := &types.NodeResource{
+		Name:              "node\"1",
+		CPUPercent:        0.5,
+		MemoryPercent:     0.25,
+		NUMAMemoryPercent: map[string]float64{"1": 0.1, "0": 0.2},
+		Diffs:             []string{"a", "b"},
+	}
+	text := FormatNodeResourceMetrics("pod", nr)
+	assert.Contains(t, text, "# TYPE node_resource_cpu_percent gauge\nnode_resource_cpu_percent{podname=\"pod\",nodename=\"node\\\"1\"} 0.5\n")
+	assert.Contains(t, text, "node_resource_memory_percent{podname=\"pod\",nodename=\"node\\\"1\"} 0.25\n")
+	assert.Contains(t, text, "node_resource_numa_memory_percent{podname=\"pod\",nodename=\"node\\\"1\",numa_node=\"0\"} 0.2\nnode_resource_numa_memory_percent{podname=\"pod\",nodename=\"node\\\"1\",numa_node=\"1\"} 0.1\n")
+	assert.Contains(t, text, "node_resource_diffs{podname=\"pod\",nodename=\"node\\\"1\"} 2\n")
+	// nothing to report without pools
+	assert.NotContains(t, text, "node_resource_storage_pool_percent")
+}